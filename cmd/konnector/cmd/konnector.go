@@ -65,6 +65,9 @@ func New(ctx context.Context) *cobra.Command {
 				return err
 			}
 			server.OptionallyStartInformers(ctx) // hot standby
+			if err := server.OptionallyStartWebhook(ctx); err != nil {
+				return err
+			}
 
 			logger.Info("trying to acquire the lock")
 			lock := NewLock(config.KubeClient, options.LeaseLockNamespace, options.LeaseLockName, options.LeaseLockIdentity)
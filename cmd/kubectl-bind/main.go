@@ -25,6 +25,13 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	apiservicecmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-apiservice/cmd"
+	diffcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-diff/cmd"
+	doctorcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-doctor/cmd"
+	konnectorcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-konnector/cmd"
+	listcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-list/cmd"
+	refreshcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-refresh/cmd"
+	statuscmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-status/cmd"
+	unbindcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind-unbind/cmd"
 	bindcmd "github.com/kube-bind/kube-bind/pkg/kubectl/bind/cmd"
 )
 
@@ -45,6 +52,55 @@ func main() {
 	}
 	bindCmd.AddCommand(apiserviceCmd)
 
+	listCmd, err := listcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(listCmd)
+
+	statusCmd, err := statuscmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(statusCmd)
+
+	unbindCmd, err := unbindcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(unbindCmd)
+
+	konnectorCmd, err := konnectorcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(konnectorCmd)
+
+	doctorCmd, err := doctorcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(doctorCmd)
+
+	diffCmd, err := diffcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(diffCmd)
+
+	refreshCmd, err := refreshcmd.New(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v", err)
+		os.Exit(1)
+	}
+	bindCmd.AddCommand(refreshCmd)
+
 	if err := bindCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -94,6 +94,10 @@ func (r *reconciler) reconcile(ctx context.Context, export *kubebindv1alpha1.API
 			continue
 		}
 		resource.Namespace = export.Namespace
+		if export.Spec.ClusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+			// namespaced on the provider side, but cluster-scoped for the consumer
+			resource.Spec.Scope = apiextensionsv1.ClusterScoped
+		}
 
 		if ser == nil {
 			// APIServiceExportResource missing
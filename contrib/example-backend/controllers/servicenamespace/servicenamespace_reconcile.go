@@ -108,6 +108,10 @@ func (c *reconciler) ensureRBACRole(ctx context.Context, ns string, sns *kubebin
 				APIGroups: []string{resource.Group},
 				Resources: []string{resource.Resource},
 				Verbs:     []string{"get", "list", "watch", "update", "patch", "delete", "create"},
+			}, rbacv1.PolicyRule{
+				APIGroups: []string{resource.Group},
+				Resources: []string{resource.Resource + "/scale"},
+				Verbs:     []string{"get", "update", "patch"},
 			})
 		}
 	}
@@ -35,8 +35,9 @@ type SessionState struct {
 	IDToken      string `msgpack:"it,omitempty"`
 	RefreshToken string `msgpack:"rt,omitempty"`
 
-	RedirectURL string `msgpack:"ru,omitempty"`
-	SessionID   string `msgpack:"si,omitempty"`
+	RedirectURL  string `msgpack:"ru,omitempty"`
+	SessionID    string `msgpack:"si,omitempty"`
+	CacheSession bool   `msgpack:"cs,omitempty"`
 }
 
 func (s *SessionState) Encode() ([]byte, error) {
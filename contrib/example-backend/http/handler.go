@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
@@ -45,6 +46,7 @@ import (
 
 var (
 	resourcesTemplate = htmltemplate.Must(htmltemplate.New("resource").Parse(mustRead(template.Files.ReadFile, "resources.gohtml")))
+	confirmTemplate   = htmltemplate.Must(htmltemplate.New("confirm").Parse(mustRead(template.Files.ReadFile, "confirm.gohtml")))
 )
 
 // See https://developers.google.com/web/fundamentals/performance/optimizing-content-efficiency/http-caching?hl=en
@@ -65,6 +67,9 @@ type handler struct {
 	apiextensionsLister apiextensionslisters.CustomResourceDefinitionLister
 
 	kubeManager *kubernetes.Manager
+
+	headless *headlessSessions
+	sessions *refreshSessions
 }
 
 func NewHandler(
@@ -81,6 +86,8 @@ func NewHandler(
 		client:              http.DefaultClient,
 		kubeManager:         mgr,
 		apiextensionsLister: apiextensionsLister,
+		headless:            newHeadlessSessions(),
+		sessions:            newRefreshSessions(),
 	}, nil
 }
 
@@ -90,6 +97,10 @@ func (h *handler) AddRoutes(mux *mux.Router) {
 	mux.HandleFunc("/bind", h.handleBind).Methods("GET")
 	mux.HandleFunc("/authorize", h.handleAuthorize).Methods("GET")
 	mux.HandleFunc("/callback", h.handleCallback).Methods("GET")
+	mux.HandleFunc("/confirm", h.handleConfirm).Methods("POST")
+	mux.HandleFunc("/collect", h.handleCollect).Methods("POST")
+	mux.HandleFunc("/refresh", h.handleRefresh).Methods("POST")
+	mux.HandleFunc("/refresh", h.handleRevoke).Methods("DELETE")
 }
 
 func (h *handler) handleServiceExport(w http.ResponseWriter, r *http.Request) {
@@ -97,8 +108,10 @@ func (h *handler) handleServiceExport(w http.ResponseWriter, r *http.Request) {
 
 	serviceProvider := &v1alpha1.APIServiceProvider{
 		Spec: v1alpha1.APIServiceProviderSpec{
-			AuthenticatedClientURL: fmt.Sprintf("http://%s/authorize", r.Host), // TODO: support https
-			ProviderPrettyName:     h.providerPrettyName,
+			AuthenticatedClientURL:   fmt.Sprintf("http://%s/authorize", r.Host), // TODO: support https
+			AuthenticationCollectURL: fmt.Sprintf("http://%s/collect", r.Host),
+			AuthenticationRefreshURL: fmt.Sprintf("http://%s/refresh", r.Host),
+			ProviderPrettyName:       h.providerPrettyName,
 		},
 	}
 
@@ -126,14 +139,30 @@ func (h *handler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
 
 	scopes := []string{"openid", "profile", "email", "offline_access"}
 	code := &resources.AuthCode{
-		RedirectURL: r.URL.Query().Get("u"),
-		SessionID:   r.URL.Query().Get("s"),
+		RedirectURL:  r.URL.Query().Get("u"),
+		SessionID:    r.URL.Query().Get("s"),
+		CacheSession: r.URL.Query().Get("cache_session") == "true",
 	}
-	if code.RedirectURL == "" || code.SessionID == "" {
-		logger.Error(errors.New("missing redirect url or session id"), "failed to authorize")
-		http.Error(w, "missing redirect_url or session_id", http.StatusBadRequest)
+	if code.SessionID == "" {
+		logger.Error(errors.New("missing session id"), "failed to authorize")
+		http.Error(w, "missing session_id", http.StatusBadRequest)
 		return
 	}
+	if code.RedirectURL == "" {
+		// headless client, collecting the response at /collect
+		if len(code.SessionID) < minHeadlessSessionIDLength {
+			logger.Error(errors.New("session id too short"), "failed to authorize")
+			http.Error(w, "session_id too short without redirect_url", http.StatusBadRequest)
+			return
+		}
+		// the client has to register the session at /collect before, with the
+		// confirmation code it shows to the user.
+		if !h.headless.pending(code.SessionID) {
+			logger.Error(errHeadlessSessionNotFound, "failed to authorize")
+			http.Error(w, "session not found or expired", http.StatusNotFound)
+			return
+		}
+	}
 
 	dataCode, err := json.Marshal(code)
 	if err != nil {
@@ -159,6 +188,24 @@ func parseJWT(p string) ([]byte, error) {
 	return payload, nil
 }
 
+// newSessionState returns the session cookie of a login. The refresh token is only kept
+// if the client asked to cache the session.
+func newSessionState(token *oauth2.Token, idToken []byte, authCode *resources.AuthCode) *cookie.SessionState {
+	state := &cookie.SessionState{
+		CreatedAt:    time.Now(),
+		ExpiresOn:    token.Expiry,
+		AccessToken:  token.AccessToken,
+		IDToken:      string(idToken),
+		RedirectURL:  authCode.RedirectURL,
+		SessionID:    authCode.SessionID,
+		CacheSession: authCode.CacheSession,
+	}
+	if authCode.CacheSession {
+		state.RefreshToken = token.RefreshToken
+	}
+	return state
+}
+
 // handleCallback handle the authorization redirect callback from OAuth2 auth flow.
 func (h *handler) handleCallback(w http.ResponseWriter, r *http.Request) {
 	logger := klog.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
@@ -223,16 +270,7 @@ func (h *handler) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionCookie := cookie.SessionState{
-		CreatedAt:    time.Now(),
-		ExpiresOn:    token.Expiry,
-		AccessToken:  token.AccessToken,
-		IDToken:      string(jwt),
-		RefreshToken: token.RefreshToken,
-		RedirectURL:  authCode.RedirectURL,
-		SessionID:    authCode.SessionID,
-	}
-
+	sessionCookie := newSessionState(token, jwt, authCode)
 	b, err := sessionCookie.Encode()
 	if err != nil {
 		logger.Info("failed to encode session cookie", "error", err)
@@ -288,6 +326,15 @@ func (h *handler) handleResources(w http.ResponseWriter, r *http.Request) {
 	w.Write(bs.Bytes()) // nolint:errcheck
 }
 
+// sessionToken returns an opaque session token for the refresh token of the login if the
+// client asked to cache the session. The refresh token stays in the backend.
+func (h *handler) sessionToken(subject string, state *cookie.SessionState) (string, error) {
+	if !state.CacheSession {
+		return "", nil
+	}
+	return h.sessions.issue(subject, state.RefreshToken)
+}
+
 func (h *handler) handleBind(w http.ResponseWriter, r *http.Request) {
 	logger := klog.FromContext(r.Context()).WithValues("method", r.Method, "url", r.URL.String())
 
@@ -317,25 +364,29 @@ func (h *handler) handleBind(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group := r.URL.Query().Get("group")
-	resource := r.URL.Query().Get("resource")
-	kfg, err := h.kubeManager.HandleResources(r.Context(), idToken.Subject, resource, group)
+	grs, err := h.selectedResources(r.URL.Query())
+	if err != nil {
+		logger.Info("failed to get selected resources", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	kfg, err := h.kubeManager.HandleResources(r.Context(), idToken.Subject, grs)
 	if err != nil {
 		logger.Info("failed to handle resources", "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
-	// callback client with access token and kubeconfig
-	authResponse := resources.AuthResponse{
-		SessionID:  state.SessionID,
-		ID:         idToken.Issuer + "/" + idToken.Subject,
-		Kubeconfig: kfg,
-		Group:      group,
-		Resource:   resource,
-		Export:     resource + "." + group,
+	sessionToken, err := h.sessionToken(idToken.Subject, state)
+	if err != nil {
+		logger.Info("failed to issue session token", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
 	}
 
+	// callback client with access token and kubeconfig
+	authResponse := newAuthResponse(state.SessionID, idToken.Issuer, idToken.Subject, kfg, grs, sessionToken)
 	payload, err := json.Marshal(authResponse)
 	if err != nil {
 		logger.Info("failed to marshal auth response", "error", err)
@@ -343,6 +394,16 @@ func (h *handler) handleBind(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if state.RedirectURL == "" {
+		if !h.headless.complete(state.SessionID, payload) {
+			logger.Info("headless session not found", "error", "session expired")
+			http.Error(w, "session expired", http.StatusGone)
+			return
+		}
+		h.renderConfirm(w, r, state.SessionID, false, "")
+		return
+	}
+
 	encoded := base64.StdEncoding.EncodeToString(payload)
 
 	parsedAuthURL, err := url.Parse(state.RedirectURL)
@@ -360,6 +421,240 @@ func (h *handler) handleBind(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, parsedAuthURL.String(), http.StatusFound)
 }
 
+// handleConfirm checks the confirmation code of a headless session entered by the user.
+// Only confirmed sessions can be collected.
+func (h *handler) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithValues("method", r.Method)
+
+	prepareNoCache(w)
+
+	sessionID := r.FormValue("s")
+	if sessionID == "" {
+		http.Error(w, "missing session_id", http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.headless.confirm(sessionID, r.FormValue("c")); {
+	case errors.Is(err, errWrongConfirmationCode):
+		logger.Info("wrong confirmation code")
+		h.renderConfirm(w, r, sessionID, false, "The confirmation code is wrong. Please try again.")
+	case err != nil:
+		logger.Info("failed to confirm headless session", "error", err)
+		http.Error(w, "session not found or expired", http.StatusNotFound)
+	default:
+		h.renderConfirm(w, r, sessionID, true, "")
+	}
+}
+
+func (h *handler) renderConfirm(w http.ResponseWriter, r *http.Request, sessionID string, confirmed bool, message string) {
+	logger := klog.FromContext(r.Context())
+
+	var bs bytes.Buffer
+	if err := confirmTemplate.Execute(&bs, struct {
+		SessionID string
+		Confirmed bool
+		Error     string
+	}{
+		SessionID: sessionID,
+		Confirmed: confirmed,
+		Error:     message,
+	}); err != nil {
+		logger.Info("failed to execute template", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(bs.Bytes()) // nolint:errcheck
+}
+
+// handleCollect returns the AuthResponse of a headless session when the user has authenticated
+// and confirmed the session, 202 as long as the session is pending, and 404 if the session is
+// unknown or has expired. A request with a confirmation code starts the session if it does not
+// exist yet.
+func (h *handler) handleCollect(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithValues("method", r.Method)
+
+	prepareNoCache(w)
+
+	sessionID := r.FormValue("s")
+	if sessionID == "" {
+		http.Error(w, "missing session_id", http.StatusBadRequest)
+		return
+	}
+
+	if code := r.FormValue("c"); code != "" {
+		if len(sessionID) < minHeadlessSessionIDLength {
+			http.Error(w, "session_id too short", http.StatusBadRequest)
+			return
+		}
+		if err := h.headless.start(sessionID, code); err != nil {
+			logger.Info("failed to start headless session", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	response, found := h.headless.collect(sessionID)
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	logger.Info("headless session collected")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response) // nolint:errcheck
+}
+
+// handleRefresh returns a new AuthResponse for the identity of the session token of a
+// previous authentication, without a browser login. The session token is replaced by a new
+// one. It returns 401 if the session token is unknown, has been revoked, or the OIDC provider
+// does not accept the refresh token of the session anymore.
+func (h *handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithValues("method", r.Method)
+
+	prepareNoCache(w)
+
+	sessionToken, ok := bearerToken(r)
+	if !ok {
+		http.Error(w, "missing bearer session token", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, found := h.sessions.lookup(sessionToken)
+	if !found {
+		logger.Info("unknown session token")
+		http.Error(w, "session expired", http.StatusUnauthorized)
+		return
+	}
+	token, idToken, err := h.oidc.Refresh(r.Context(), session.refreshToken)
+	if err == nil && idToken.Subject != session.subject {
+		err = fmt.Errorf("subject %q of refreshed token does not match session subject %q", idToken.Subject, session.subject)
+	}
+	if err != nil {
+		logger.Info("failed to refresh session", "error", err)
+		h.sessions.revoke(sessionToken)
+		http.Error(w, "session expired", http.StatusUnauthorized)
+		return
+	}
+	// the OIDC provider might rotate the refresh token
+	if token.RefreshToken != "" {
+		h.sessions.update(sessionToken, token.RefreshToken)
+	}
+
+	grs, err := h.selectedResources(r.PostForm)
+	if err != nil {
+		logger.Info("failed to get selected resources", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	kfg, err := h.kubeManager.HandleResources(r.Context(), idToken.Subject, grs)
+	if err != nil {
+		logger.Info("failed to handle resources", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	newSessionToken, err := h.sessions.rotate(sessionToken)
+	if err != nil {
+		logger.Info("failed to issue session token", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	payload, err := json.Marshal(newAuthResponse("", idToken.Issuer, idToken.Subject, kfg, grs, newSessionToken))
+	if err != nil {
+		logger.Info("failed to marshal auth response", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("session refreshed")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(payload) // nolint:errcheck
+}
+
+// handleRevoke revokes the session token, such that it cannot be used to refresh anymore.
+// Revoking an unknown session token succeeds too.
+func (h *handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithValues("method", r.Method)
+
+	prepareNoCache(w)
+
+	sessionToken, ok := bearerToken(r)
+	if !ok {
+		http.Error(w, "missing bearer session token", http.StatusUnauthorized)
+		return
+	}
+	if h.sessions.revoke(sessionToken) {
+		logger.Info("session revoked")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bearerToken returns the bearer token of the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == "" || token == header {
+		return "", false
+	}
+	return token, true
+}
+
+// selectedResources returns the resources of a single resource and group parameter, or multiple
+// export=<resource>.<group> parameters, and checks that they are served.
+func (h *handler) selectedResources(values url.Values) ([]v1alpha1.GroupResource, error) {
+	var grs []v1alpha1.GroupResource
+	if resource := values.Get("resource"); resource != "" {
+		grs = append(grs, v1alpha1.GroupResource{Group: values.Get("group"), Resource: resource})
+	}
+	for _, export := range values["export"] {
+		parts := strings.SplitN(export, ".", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid export %q", export)
+		}
+		grs = append(grs, v1alpha1.GroupResource{Group: parts[1], Resource: parts[0]})
+	}
+	if len(grs) == 0 {
+		return nil, errors.New("no resource selected")
+	}
+	for _, gr := range grs {
+		if _, err := h.apiextensionsLister.Get(gr.Resource + "." + gr.Group); err != nil {
+			return nil, fmt.Errorf("unknown resource %s.%s", gr.Resource, gr.Group)
+		}
+	}
+	return grs, nil
+}
+
+func newAuthResponse(sessionID, issuer, subject string, kubeconfig []byte, grs []v1alpha1.GroupResource, sessionToken string) *resources.AuthResponse {
+	response := &resources.AuthResponse{
+		SessionID:    sessionID,
+		ID:           issuer + "/" + subject,
+		Kubeconfig:   kubeconfig,
+		Group:        grs[0].Group,
+		Resource:     grs[0].Resource,
+		Export:       grs[0].Resource + "." + grs[0].Group,
+		SessionToken: sessionToken,
+	}
+	for _, gr := range grs {
+		response.Exports = append(response.Exports, resources.AuthResponseExport{
+			Resource: gr.Resource,
+			Group:    gr.Group,
+			Export:   gr.Resource + "." + gr.Group,
+		})
+	}
+	return response
+}
+
 func mustRead(f func(name string) ([]byte, error), name string) string {
 	bs, err := f(name)
 	if err != nil {
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/cookie"
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	"github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestSelectedResources(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"mangodbs.mangodb.com", "backups.mangodb.com"} {
+		require.NoError(t, indexer.Add(&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: name}}))
+	}
+	h := &handler{apiextensionsLister: apiextensionslisters.NewCustomResourceDefinitionLister(indexer)}
+
+	tests := []struct {
+		name    string
+		values  url.Values
+		want    []v1alpha1.GroupResource
+		wantErr string
+	}{
+		{
+			name:    "nothing selected",
+			values:  url.Values{},
+			wantErr: "no resource selected",
+		},
+		{
+			name:   "resource and group",
+			values: url.Values{"resource": {"mangodbs"}, "group": {"mangodb.com"}},
+			want:   []v1alpha1.GroupResource{{Group: "mangodb.com", Resource: "mangodbs"}},
+		},
+		{
+			name:   "multiple exports",
+			values: url.Values{"export": {"mangodbs.mangodb.com", "backups.mangodb.com"}},
+			want: []v1alpha1.GroupResource{
+				{Group: "mangodb.com", Resource: "mangodbs"},
+				{Group: "mangodb.com", Resource: "backups"},
+			},
+		},
+		{
+			name:   "resource and exports",
+			values: url.Values{"resource": {"mangodbs"}, "group": {"mangodb.com"}, "export": {"backups.mangodb.com"}},
+			want: []v1alpha1.GroupResource{
+				{Group: "mangodb.com", Resource: "mangodbs"},
+				{Group: "mangodb.com", Resource: "backups"},
+			},
+		},
+		{
+			name:    "export without group",
+			values:  url.Values{"export": {"mangodbs"}},
+			wantErr: `invalid export "mangodbs"`,
+		},
+		{
+			name:    "unknown resource",
+			values:  url.Values{"export": {"mangodbs.mangodb.com", "foos.example.com"}},
+			wantErr: "unknown resource foos.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.selectedResources(tt.values)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewAuthResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		grs         []v1alpha1.GroupResource
+		wantExport  string
+		wantExports []resources.AuthResponseExport
+	}{
+		{
+			name:       "single resource",
+			grs:        []v1alpha1.GroupResource{{Group: "mangodb.com", Resource: "mangodbs"}},
+			wantExport: "mangodbs.mangodb.com",
+			wantExports: []resources.AuthResponseExport{
+				{Resource: "mangodbs", Group: "mangodb.com", Export: "mangodbs.mangodb.com"},
+			},
+		},
+		{
+			name: "multiple resources",
+			grs: []v1alpha1.GroupResource{
+				{Group: "mangodb.com", Resource: "mangodbs"},
+				{Group: "mangodb.com", Resource: "backups"},
+			},
+			wantExport: "mangodbs.mangodb.com",
+			wantExports: []resources.AuthResponseExport{
+				{Resource: "mangodbs", Group: "mangodb.com", Export: "mangodbs.mangodb.com"},
+				{Resource: "backups", Group: "mangodb.com", Export: "backups.mangodb.com"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := newAuthResponse("session", "https://issuer", "subject", []byte("kubeconfig"), tt.grs, "token")
+			require.Equal(t, "session", response.SessionID)
+			require.Equal(t, "https://issuer/subject", response.ID)
+			require.Equal(t, []byte("kubeconfig"), response.Kubeconfig)
+			require.Equal(t, "token", response.SessionToken)
+
+			// older clients only know the first export
+			require.Equal(t, tt.grs[0].Resource, response.Resource)
+			require.Equal(t, tt.grs[0].Group, response.Group)
+			require.Equal(t, tt.wantExport, response.Export)
+			require.Equal(t, tt.wantExports, response.Exports)
+			require.Equal(t, tt.wantExports, response.AllExports())
+
+			// and they still decode it
+			bs, err := json.Marshal(response)
+			require.NoError(t, err)
+			var old struct {
+				Resource string `json:"resource"`
+				Group    string `json:"group"`
+				Export   string `json:"export"`
+			}
+			require.NoError(t, json.Unmarshal(bs, &old))
+			require.Equal(t, tt.wantExport, old.Export)
+		})
+	}
+}
+
+func TestHandleAuthorizeCacheSession(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{name: "without cache_session", query: "u=http://127.0.0.1:1234/callback&s=session"},
+		{name: "with cache_session", query: "u=http://127.0.0.1:1234/callback&s=session&cache_session=true", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{oidc: &OIDCServiceProvider{provider: &oidc.Provider{}}}
+			w := httptest.NewRecorder()
+			h.handleAuthorize(w, httptest.NewRequest(http.MethodGet, "/authorize?"+tt.query, nil))
+			require.Equal(t, http.StatusFound, w.Code)
+
+			location, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			state, err := base64.StdEncoding.DecodeString(location.Query().Get("state"))
+			require.NoError(t, err)
+			var code resources.AuthCode
+			require.NoError(t, json.Unmarshal(state, &code))
+			require.Equal(t, "session", code.SessionID)
+			require.Equal(t, tt.want, code.CacheSession)
+		})
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}
+
+	tests := []struct {
+		name             string
+		cacheSession     bool
+		wantRefreshToken string
+		wantSessions     int
+	}{
+		{name: "not cached", cacheSession: false},
+		{name: "cached", cacheSession: true, wantRefreshToken: "refresh", wantSessions: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newSessionState(token, []byte("{}"), &resources.AuthCode{SessionID: "session", CacheSession: tt.cacheSession})
+			require.Equal(t, tt.wantRefreshToken, state.RefreshToken)
+			require.Equal(t, "access", state.AccessToken)
+
+			// the cookie carries the flag to /bind
+			b, err := state.Encode()
+			require.NoError(t, err)
+			decoded, err := cookie.Decode(base64.RawURLEncoding.EncodeToString(b))
+			require.NoError(t, err)
+			require.Equal(t, tt.cacheSession, decoded.CacheSession)
+
+			h := &handler{sessions: newRefreshSessions()}
+			sessionToken, err := h.sessionToken("subject", decoded)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSessions != 0, sessionToken != "")
+			require.Len(t, h.sessions.sessions, tt.wantSessions)
+		})
+	}
+}
@@ -18,6 +18,7 @@ package http
 
 import (
 	"context"
+	"errors"
 
 	oidc "github.com/coreos/go-oidc"
 	"golang.org/x/oauth2"
@@ -58,3 +59,21 @@ func (o *OIDCServiceProvider) OIDCProviderConfig(scopes []string) *oauth2.Config
 		Scopes:       scopes,
 	}
 }
+
+// Refresh exchanges the refresh token of a previous login for new tokens, and verifies
+// the returned id token. It fails if the OIDC provider has revoked the session.
+func (o *OIDCServiceProvider) Refresh(ctx context.Context, refreshToken string) (*oauth2.Token, *oidc.IDToken, error) {
+	token, err := o.OIDCProviderConfig(nil).TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errors.New("no id_token in refreshed token")
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, idToken, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshSessionTTL is how long a session token can be used to refresh credentials
+	// without a browser login, unless the OIDC provider expires the refresh token earlier.
+	refreshSessionTTL = 30 * 24 * time.Hour
+
+	// maxRefreshSessions caps the number of session tokens. When reached, no new session
+	// tokens are issued and clients fall back to a browser login.
+	maxRefreshSessions = 10000
+)
+
+// refreshSessions maps opaque session tokens handed out to clients to the refresh tokens of
+// the OIDC provider, which never leave the backend. Only hashes of the session tokens are kept,
+// and a session token is replaced on every use and can be revoked.
+//
+// The sessions are kept in memory, i.e. they do not survive a restart of the backend, and
+// refreshing needs a single backend replica or sticky sessions.
+type refreshSessions struct {
+	lock     sync.Mutex
+	sessions map[string]*refreshSession
+}
+
+type refreshSession struct {
+	expires      time.Time
+	subject      string
+	refreshToken string
+}
+
+func newRefreshSessions() *refreshSessions {
+	return &refreshSessions{
+		sessions: map[string]*refreshSession{},
+	}
+}
+
+// issue returns a new session token for the refresh token of the given subject, or an empty
+// string if there is no refresh token or too many sessions exist.
+func (s *refreshSessions) issue(subject, refreshToken string) (string, error) {
+	if refreshToken == "" {
+		return "", nil
+	}
+
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(bs)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	if len(s.sessions) >= maxRefreshSessions {
+		return "", nil
+	}
+	s.sessions[hashSessionToken(token)] = &refreshSession{
+		expires:      time.Now().Add(refreshSessionTTL),
+		subject:      subject,
+		refreshToken: refreshToken,
+	}
+	return token, nil
+}
+
+// lookup returns the session of the given token, or false if it is unknown, expired or revoked.
+func (s *refreshSessions) lookup(token string) (refreshSession, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	session, found := s.sessions[hashSessionToken(token)]
+	if !found {
+		return refreshSession{}, false
+	}
+	return *session, true
+}
+
+// update replaces the refresh token of the session of the given token, e.g. when the OIDC
+// provider rotates refresh tokens.
+func (s *refreshSessions) update(token, refreshToken string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if session, found := s.sessions[hashSessionToken(token)]; found {
+		session.refreshToken = refreshToken
+	}
+}
+
+// rotate replaces the given token by a new one for the same session. It returns an empty
+// string if the session has been revoked in the meantime.
+func (s *refreshSessions) rotate(token string) (string, error) {
+	s.lock.Lock()
+	session, found := s.sessions[hashSessionToken(token)]
+	s.lock.Unlock()
+	if !found || !s.revoke(token) {
+		return "", nil
+	}
+	return s.issue(session.subject, session.refreshToken)
+}
+
+// revoke forgets the session of the given token. It returns false if there is no such session.
+func (s *refreshSessions) revoke(token string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hash := hashSessionToken(token)
+	_, found := s.sessions[hash]
+	delete(s.sessions, hash)
+	return found
+}
+
+func (s *refreshSessions) expire() {
+	now := time.Now()
+	for hash, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, hash)
+		}
+	}
+}
+
+func hashSessionToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshSessions(t *testing.T) {
+	tests := []struct {
+		name         string
+		refreshToken string
+		revoke       bool
+		rotate       bool
+		wantToken    bool
+		wantFound    bool
+	}{
+		{
+			name:         "no refresh token",
+			refreshToken: "",
+		},
+		{
+			name:         "issued",
+			refreshToken: "refresh",
+			wantToken:    true,
+			wantFound:    true,
+		},
+		{
+			name:         "revoked",
+			refreshToken: "refresh",
+			revoke:       true,
+			wantToken:    true,
+		},
+		{
+			name:         "rotated",
+			refreshToken: "refresh",
+			rotate:       true,
+			wantToken:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRefreshSessions()
+			token, err := s.issue("subject", tt.refreshToken)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantToken, token != "")
+
+			if tt.revoke {
+				require.True(t, s.revoke(token))
+			}
+			if tt.rotate {
+				rotated, err := s.rotate(token)
+				require.NoError(t, err)
+				require.NotEmpty(t, rotated)
+				require.NotEqual(t, token, rotated)
+
+				session, found := s.lookup(rotated)
+				require.True(t, found)
+				require.Equal(t, "subject", session.subject)
+				require.Equal(t, tt.refreshToken, session.refreshToken)
+			}
+
+			session, found := s.lookup(token)
+			require.Equal(t, tt.wantFound, found)
+			if found {
+				require.Equal(t, "subject", session.subject)
+				require.Equal(t, tt.refreshToken, session.refreshToken)
+			}
+			for hash := range s.sessions {
+				require.NotEqual(t, token, hash, "only hashes of tokens must be kept")
+			}
+		})
+	}
+}
+
+func TestRefreshSessionsUpdate(t *testing.T) {
+	s := newRefreshSessions()
+	token, err := s.issue("subject", "refresh")
+	require.NoError(t, err)
+
+	s.update(token, "rotated")
+	session, found := s.lookup(token)
+	require.True(t, found)
+	require.Equal(t, "rotated", session.refreshToken)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// headlessSessionTTL is how long a headless session waits for the user to authenticate,
+	// and then for the client to collect the response.
+	headlessSessionTTL = 10 * time.Minute
+
+	// minHeadlessSessionIDLength makes sure headless session IDs cannot be guessed. Whoever
+	// knows the session ID can collect the kubeconfig.
+	minHeadlessSessionIDLength = 32
+
+	// maxHeadlessSessions caps the number of headless sessions, which can be started
+	// without authentication. When reached, the oldest session the user has not
+	// authenticated yet is dropped, such that nobody can block headless logins by
+	// starting sessions.
+	maxHeadlessSessions = 1000
+
+	// maxConfirmationAttempts is how often the user can enter a wrong confirmation code
+	// before the session is dropped.
+	maxConfirmationAttempts = 5
+)
+
+var (
+	errTooManyHeadlessSessions = errors.New("too many pending headless sessions")
+	errHeadlessSessionNotFound = errors.New("headless session not found or expired")
+	errWrongConfirmationCode   = errors.New("wrong confirmation code")
+)
+
+// headlessSessions holds the authentication responses of headless clients, which have no
+// callback url, until they are collected. The client starts a session with a short
+// confirmation code that it shows to the user. The response is only handed out after the
+// user has entered that code in the browser, such that nobody can make a victim authenticate
+// a session started by somebody else without the victim noticing.
+//
+// The sessions are kept in memory, i.e. headless authentication needs a single backend
+// replica or sticky sessions.
+type headlessSessions struct {
+	lock     sync.Mutex
+	sessions map[string]*headlessSession
+}
+
+type headlessSession struct {
+	started time.Time
+	expires time.Time
+	// code is the confirmation code shown by the client.
+	code string
+	// attempts is the number of wrong confirmation codes entered.
+	attempts int
+	// response is the marshalled AuthResponse, or nil as long as the user has not authenticated.
+	response []byte
+	// confirmed is true when the user has entered the confirmation code.
+	confirmed bool
+}
+
+func newHeadlessSessions() *headlessSessions {
+	return &headlessSessions{
+		sessions: map[string]*headlessSession{},
+	}
+}
+
+// start registers a pending session with the given confirmation code, if it does not
+// exist yet.
+func (s *headlessSessions) start(id, code string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	if _, found := s.sessions[id]; found {
+		return nil
+	}
+	if len(s.sessions) >= maxHeadlessSessions {
+		oldest := ""
+		for id, session := range s.sessions {
+			if session.response == nil && (oldest == "" || session.started.Before(s.sessions[oldest].started)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			return errTooManyHeadlessSessions // all authenticated, waiting to be collected
+		}
+		delete(s.sessions, oldest)
+	}
+
+	now := time.Now()
+	s.sessions[id] = &headlessSession{
+		started: now,
+		expires: now.Add(headlessSessionTTL),
+		code:    normalizeConfirmationCode(code),
+	}
+	return nil
+}
+
+// pending returns true if the session exists and the user has not authenticated yet.
+func (s *headlessSessions) pending(id string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	session, found := s.sessions[id]
+	return found && session.response == nil
+}
+
+// complete stores the response of a pending session, to be handed out when the user has
+// confirmed the session. It returns false if there is no such session.
+func (s *headlessSessions) complete(id string, response []byte) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	session, found := s.sessions[id]
+	if !found {
+		return false
+	}
+	session.response = response
+	session.expires = time.Now().Add(headlessSessionTTL)
+	return true
+}
+
+// confirm checks the confirmation code entered by the user. The session is dropped after
+// too many wrong codes.
+func (s *headlessSessions) confirm(id, code string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	session, found := s.sessions[id]
+	if !found || session.response == nil {
+		return errHeadlessSessionNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(session.code), []byte(normalizeConfirmationCode(code))) != 1 {
+		session.attempts++
+		if session.attempts >= maxConfirmationAttempts {
+			delete(s.sessions, id)
+			return errHeadlessSessionNotFound
+		}
+		return errWrongConfirmationCode
+	}
+	session.confirmed = true
+	return nil
+}
+
+// collect returns the response of a session, and forgets the session when it is confirmed.
+// The response is nil as long as the session is not confirmed. It returns false if there is
+// no such session.
+func (s *headlessSessions) collect(id string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire()
+	session, found := s.sessions[id]
+	if !found {
+		return nil, false
+	}
+	if !session.confirmed {
+		return nil, true
+	}
+	delete(s.sessions, id)
+	return session.response, true
+}
+
+func (s *headlessSessions) expire() {
+	now := time.Now()
+	for id, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// normalizeConfirmationCode makes the comparison of confirmation codes ignore case
+// and separators.
+func normalizeConfirmationCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadlessSessions(t *testing.T) {
+	const id = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name          string
+		start         bool
+		complete      bool
+		codes         []string
+		wantConfirm   error
+		wantFound     bool
+		wantCollected bool
+	}{
+		{
+			name:      "pending session",
+			start:     true,
+			wantFound: true,
+		},
+		{
+			name:        "unknown session",
+			complete:    true,
+			codes:       []string{"ABCD-EFGH"},
+			wantConfirm: errHeadlessSessionNotFound,
+		},
+		{
+			name:        "not confirmed",
+			start:       true,
+			complete:    true,
+			codes:       []string{"ABCD-EFGJ"},
+			wantConfirm: errWrongConfirmationCode,
+			wantFound:   true,
+		},
+		{
+			name:          "confirmed",
+			start:         true,
+			complete:      true,
+			codes:         []string{"ABCD-EFGH"},
+			wantFound:     true,
+			wantCollected: true,
+		},
+		{
+			name:          "confirmed ignoring case and separators",
+			start:         true,
+			complete:      true,
+			codes:         []string{"abcd efgh"},
+			wantFound:     true,
+			wantCollected: true,
+		},
+		{
+			name:          "confirmed after wrong code",
+			start:         true,
+			complete:      true,
+			codes:         []string{"XXXX-XXXX", "ABCD-EFGH"},
+			wantFound:     true,
+			wantCollected: true,
+		},
+		{
+			name:        "too many wrong codes",
+			start:       true,
+			complete:    true,
+			codes:       []string{"XXXX-XXXX", "XXXX-XXXX", "XXXX-XXXX", "XXXX-XXXX", "XXXX-XXXX"},
+			wantConfirm: errHeadlessSessionNotFound,
+		},
+		{
+			name:        "confirm before authentication",
+			start:       true,
+			codes:       []string{"ABCD-EFGH"},
+			wantConfirm: errHeadlessSessionNotFound,
+			wantFound:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHeadlessSessions()
+			if tt.start {
+				require.NoError(t, s.start(id, "ABCD-EFGH"))
+			}
+			if tt.complete {
+				require.Equal(t, tt.start, s.complete(id, []byte("response")))
+			}
+			var err error
+			for _, code := range tt.codes {
+				err = s.confirm(id, code)
+			}
+			require.Equal(t, tt.wantConfirm, err)
+
+			response, found := s.collect(id)
+			require.Equal(t, tt.wantFound, found)
+			if tt.wantCollected {
+				require.Equal(t, []byte("response"), response)
+				_, found := s.collect(id)
+				require.False(t, found, "session should be forgotten after collection")
+			} else {
+				require.Nil(t, response)
+			}
+		})
+	}
+}
+
+func TestHeadlessSessionsLimit(t *testing.T) {
+	s := newHeadlessSessions()
+	now := time.Now()
+	for i := 0; i < maxHeadlessSessions; i++ {
+		require.NoError(t, s.start(fmt.Sprintf("session-%d", i), "ABCD-EFGH"))
+		s.sessions[fmt.Sprintf("session-%d", i)].started = now.Add(time.Duration(i) * time.Millisecond)
+	}
+	require.NoError(t, s.start("session-0", "ABCD-EFGH"), "starting an existing session should succeed")
+	require.Len(t, s.sessions, maxHeadlessSessions)
+
+	// authenticated sessions are kept, the oldest pending one is dropped
+	require.True(t, s.complete("session-0", []byte("response")))
+	require.NoError(t, s.start("one-too-many", "ABCD-EFGH"))
+	require.Len(t, s.sessions, maxHeadlessSessions)
+	require.True(t, s.pending("one-too-many"))
+	require.False(t, s.pending("session-1"), "the oldest pending session should be dropped")
+	_, found := s.collect("session-1")
+	require.False(t, found)
+	_, found = s.collect("session-0")
+	require.True(t, found, "authenticated sessions should be kept")
+	require.True(t, s.pending("session-2"))
+
+	// nothing to drop if all sessions are authenticated
+	for id := range s.sessions {
+		require.True(t, s.complete(id, []byte("response")))
+	}
+	require.Equal(t, errTooManyHeadlessSessions, s.start("two-too-many", "ABCD-EFGH"))
+}
@@ -29,6 +29,7 @@ import (
 	"k8s.io/klog/v2"
 
 	kuberesources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
 	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
 	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions/kubebind/v1alpha1"
 	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
@@ -36,8 +37,10 @@ import (
 )
 
 type Manager struct {
-	namespacePrefix    string
-	providerPrettyName string
+	namespacePrefix        string
+	providerPrettyName     string
+	clusterScopedIsolation kubebindv1alpha1.Isolation
+	namespaceIsolation     kubebindv1alpha1.NamespaceIsolation
 
 	clusterConfig *rest.Config
 
@@ -53,6 +56,8 @@ type Manager struct {
 
 func NewKubernetesManager(
 	namespacePrefix, providerPrettyName string,
+	clusterScopedIsolation kubebindv1alpha1.Isolation,
+	namespaceIsolation kubebindv1alpha1.NamespaceIsolation,
 	config *rest.Config,
 	namespaceInformer corev1informers.NamespaceInformer,
 	exportInformer bindinformers.APIServiceExportInformer,
@@ -70,8 +75,10 @@ func NewKubernetesManager(
 	}
 
 	m := &Manager{
-		namespacePrefix:    namespacePrefix,
-		providerPrettyName: providerPrettyName,
+		namespacePrefix:        namespacePrefix,
+		providerPrettyName:     providerPrettyName,
+		clusterScopedIsolation: clusterScopedIsolation,
+		namespaceIsolation:     namespaceIsolation,
 
 		clusterConfig: config,
 
@@ -96,8 +103,8 @@ func NewKubernetesManager(
 	return m, nil
 }
 
-func (m *Manager) HandleResources(ctx context.Context, identity, resource, group string) ([]byte, error) {
-	logger := klog.FromContext(ctx).WithValues("identity", identity, "resource", resource, "group", group)
+func (m *Manager) HandleResources(ctx context.Context, identity string, grs []kubebindv1alpha1.GroupResource) ([]byte, error) {
+	logger := klog.FromContext(ctx).WithValues("identity", identity, "resources", grs)
 	ctx = klog.NewContext(ctx, logger)
 
 	// try to find an existing namespace by annotation, or create a new one.
@@ -146,8 +153,10 @@ func (m *Manager) HandleResources(ctx context.Context, identity, resource, group
 		return nil, err
 	}
 
-	if err := kuberesources.CreateAPIServiceExport(ctx, m.bindClient, m.exportIndexer, ns, resource, group); err != nil {
-		return nil, err
+	for _, gr := range grs {
+		if err := kuberesources.CreateAPIServiceExport(ctx, m.bindClient, m.exportIndexer, ns, gr.Resource, gr.Group, m.clusterScopedIsolation, m.namespaceIsolation); err != nil {
+			return nil, err
+		}
 	}
 
 	return kfgSecret.Data["kubeconfig"], nil
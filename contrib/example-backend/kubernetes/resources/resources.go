@@ -28,6 +28,9 @@ const (
 type AuthCode struct {
 	RedirectURL string `json:"redirectURL"`
 	SessionID   string `json:"sid"`
+	// CacheSession is set if the client wants to cache a session token, to refresh the
+	// credentials later without a login.
+	CacheSession bool `json:"cacheSession,omitempty"`
 }
 
 // AuthResponse contains the authentication data which is needed to connect to the service provider
@@ -36,7 +39,34 @@ type AuthResponse struct {
 	SessionID  string `json:"sid"`
 	ID         string `json:"id"`
 	Kubeconfig []byte `json:"kubeconfig"`
-	Resource   string `json:"resource"`
-	Group      string `json:"group"`
-	Export     string `json:"export"`
+
+	// Resource, Group and Export are the first of Exports, for clients that only know one export.
+	Resource string `json:"resource"`
+	Group    string `json:"group"`
+	Export   string `json:"export"`
+
+	// Exports are all exports selected by the user.
+	Exports []AuthResponseExport `json:"exports,omitempty"`
+
+	// SessionToken is an opaque token to request a new kubeconfig for the same identity at the
+	// authenticationRefreshURL of the service provider, without a new browser login. It is
+	// replaced on every refresh and can be revoked. It is empty if the service provider does
+	// not support refreshing.
+	SessionToken string `json:"sessionToken,omitempty"`
+}
+
+// AuthResponseExport is one export of an AuthResponse.
+type AuthResponseExport struct {
+	Resource string `json:"resource"`
+	Group    string `json:"group"`
+	Export   string `json:"export"`
+}
+
+// AllExports returns the exports of the response, also of responses of older service providers
+// without Exports.
+func (r *AuthResponse) AllExports() []AuthResponseExport {
+	if len(r.Exports) > 0 {
+		return r.Exports
+	}
+	return []AuthResponseExport{{Resource: r.Resource, Group: r.Group, Export: r.Export}}
 }
@@ -29,7 +29,7 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/indexers"
 )
 
-func CreateAPIServiceExport(ctx context.Context, client bindclient.Interface, serviceExport cache.Indexer, ns, resource, group string) error {
+func CreateAPIServiceExport(ctx context.Context, client bindclient.Interface, serviceExport cache.Indexer, ns, resource, group string, clusterScopedIsolation kubebindv1alpha1.Isolation, namespaceIsolation kubebindv1alpha1.NamespaceIsolation) error {
 	logging := klog.FromContext(ctx)
 
 	exports, err := serviceExport.ByIndex(indexers.ServiceExportByServiceExportResource, indexers.ServiceExportByServiceExportResourceKey(ns, resource, group))
@@ -49,7 +49,9 @@ func CreateAPIServiceExport(ctx context.Context, client bindclient.Interface, se
 			Namespace: ns,
 		},
 		Spec: kubebindv1alpha1.APIServiceExportSpec{
-			Scope: kubebindv1alpha1.ClusterScope,
+			Scope:                  kubebindv1alpha1.ClusterScope,
+			ClusterScopedIsolation: clusterScopedIsolation,
+			NamespaceIsolation:     namespaceIsolation,
 			Resources: []kubebindv1alpha1.APIServiceExportGroupResource{
 				{
 					GroupResource: kubebindv1alpha1.GroupResource{
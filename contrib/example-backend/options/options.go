@@ -23,6 +23,8 @@ import (
 
 	"k8s.io/component-base/logs"
 	logsv1 "k8s.io/component-base/logs/api/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
 )
 
 type Options struct {
@@ -35,8 +37,10 @@ type Options struct {
 type ExtraOptions struct {
 	KubeConfig string
 
-	NamespacePrefix string
-	PrettyName      string
+	NamespacePrefix        string
+	PrettyName             string
+	ClusterScopedIsolation string
+	NamespaceIsolation     string
 
 	TestingAutoSelect string
 }
@@ -64,8 +68,10 @@ func NewOptions() *Options {
 		Serve: NewServe(),
 
 		ExtraOptions: ExtraOptions{
-			NamespacePrefix: "cluster",
-			PrettyName:      "Example Backend",
+			NamespacePrefix:        "cluster",
+			PrettyName:             "Example Backend",
+			ClusterScopedIsolation: string(kubebindv1alpha1.IsolationNone),
+			NamespaceIsolation:     string(kubebindv1alpha1.NamespaceIsolationServiceNamespace),
 		},
 	}
 }
@@ -78,6 +84,8 @@ func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.KubeConfig, "kubeconfig", options.KubeConfig, "path to a kubeconfig. Only required if out-of-cluster")
 	fs.StringVar(&options.NamespacePrefix, "namespace-prefix", options.NamespacePrefix, "The prefix to use for cluster namespaces")
 	fs.StringVar(&options.PrettyName, "pretty-name", options.PrettyName, "Pretty name for the backend")
+	fs.StringVar(&options.ClusterScopedIsolation, "cluster-scoped-isolation", options.ClusterScopedIsolation, "How cluster-scoped consumer objects are isolated on the provider side. One of None or Namespaced. Namespaced requires the exported CRDs to be namespaced.")
+	fs.StringVar(&options.NamespaceIsolation, "namespace-isolation", options.NamespaceIsolation, "How consumer namespaces are mapped on the provider side. One of ServiceNamespace (one namespace per consumer namespace) or ClusterNamespace (all objects with mangled names in the cluster namespace).")
 
 	fs.StringVar(&options.TestingAutoSelect, "testing-auto-select", options.TestingAutoSelect, "<resource>.<group> that is automatically selected on th bind screen for testing")
 	fs.MarkHidden("testing-auto-select") // nolint: errcheck
@@ -108,6 +116,16 @@ func (options *CompletedOptions) Validate() error {
 	if options.PrettyName == "" {
 		return fmt.Errorf("pretty name cannot be empty")
 	}
+	switch kubebindv1alpha1.Isolation(options.ClusterScopedIsolation) {
+	case kubebindv1alpha1.IsolationNone, kubebindv1alpha1.IsolationNamespaced:
+	default:
+		return fmt.Errorf("cluster-scoped isolation must be one of %s or %s", kubebindv1alpha1.IsolationNone, kubebindv1alpha1.IsolationNamespaced)
+	}
+	switch kubebindv1alpha1.NamespaceIsolation(options.NamespaceIsolation) {
+	case kubebindv1alpha1.NamespaceIsolationServiceNamespace, kubebindv1alpha1.NamespaceIsolationClusterNamespace:
+	default:
+		return fmt.Errorf("namespace isolation must be one of %s or %s", kubebindv1alpha1.NamespaceIsolationServiceNamespace, kubebindv1alpha1.NamespaceIsolationClusterNamespace)
+	}
 
 	if err := options.OIDC.Validate(); err != nil {
 		return err
@@ -29,6 +29,7 @@ import (
 	"github.com/kube-bind/kube-bind/contrib/example-backend/controllers/servicenamespace"
 	examplehttp "github.com/kube-bind/kube-bind/contrib/example-backend/http"
 	examplekube "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
 )
 
 type Server struct {
@@ -76,6 +77,8 @@ func NewServer(config *Config) (*Server, error) {
 	s.Kubernetes, err = examplekube.NewKubernetesManager(
 		config.Options.NamespacePrefix,
 		config.Options.PrettyName,
+		kubebindv1alpha1.Isolation(config.Options.ClusterScopedIsolation),
+		kubebindv1alpha1.NamespaceIsolation(config.Options.NamespaceIsolation),
 		config.ClientConfig,
 		config.KubeInformers.Core().V1().Namespaces(),
 		config.BindInformers.KubeBind().V1alpha1().APIServiceExports(),
@@ -19,7 +19,11 @@ package konnector
 import (
 	"context"
 	"embed"
+	"fmt"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -27,9 +31,87 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/bootstrap"
 )
 
+const (
+	// Namespace is the namespace of the konnector.
+	Namespace = "kube-bind"
+	// DeploymentName is the name of the konnector Deployment.
+	DeploymentName = "konnector"
+
+	// VersionAnnotationKey is the annotation on the konnector Deployment holding the installed version.
+	VersionAnnotationKey = "kube-bind.io/konnector-version"
+
+	// DefaultImage is the konnector image of the embedded manifests.
+	DefaultImage = "ghcr.io/kube-bind/kube-bind:latest"
+	// DefaultVersion is the konnector version of the embedded manifests.
+	DefaultVersion = "latest"
+
+	// WebhookBattery enables the validating admission webhook of the konnector, with
+	// its Service and a certificate issued by cert-manager.
+	WebhookBattery = "webhook"
+
+	// BootstrapTimeout bounds the creation of the konnector resources.
+	BootstrapTimeout = 2 * time.Minute
+
+	certManagerGroupVersion = "cert-manager.io/v1"
+)
+
 //go:embed *.yaml
 var raw embed.FS
 
-func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, batteriesIncluded sets.String) error {
-	return bootstrap.Bootstrap(ctx, discoveryClient, dynamicClient, batteriesIncluded, raw)
+// Bootstrap creates or updates the konnector resources, giving up after BootstrapTimeout.
+// The webhook battery requires cert-manager to be installed.
+func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, batteriesIncluded sets.String, opts ...bootstrap.Option) error {
+	if batteriesIncluded.Has(WebhookBattery) {
+		if err := requireCertManager(discoveryClient); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, BootstrapTimeout)
+	defer cancel()
+	return bootstrap.Bootstrap(ctx, discoveryClient, dynamicClient, batteriesIncluded, raw, opts...)
+}
+
+// requireCertManager fails if cert-manager, issuing the webhook certificate, is not installed.
+func requireCertManager(discoveryClient discovery.DiscoveryInterface) error {
+	if _, err := discoveryClient.ServerResourcesForGroupVersion(certManagerGroupVersion); apierrors.IsNotFound(err) {
+		return fmt.Errorf("the konnector webhook requires cert-manager, but %s is not served. Install cert-manager first", certManagerGroupVersion)
+	} else if err != nil {
+		return fmt.Errorf("failed to discover %s: %w", certManagerGroupVersion, err)
+	}
+	return nil
+}
+
+// Manifests returns the konnector resources, to be applied by other means than Bootstrap.
+func Manifests(batteriesIncluded sets.String, opts ...bootstrap.Option) ([]*unstructured.Unstructured, error) {
+	var transformers []bootstrap.TransformFileFunc
+	for _, opt := range opts {
+		transformers = append(transformers, opt.TransformFile)
+	}
+	return bootstrap.RenderResourcesFromFS(raw, batteriesIncluded, transformers...)
+}
+
+// Uninstall deletes the konnector resources, except the namespace which might hold
+// other objects, e.g. kubeconfig secrets.
+func Uninstall(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) error {
+	objs, err := Manifests(sets.NewString(WebhookBattery))
+	if err != nil {
+		return err
+	}
+	var toDelete []*unstructured.Unstructured
+	for _, obj := range objs {
+		if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" {
+			continue
+		}
+		toDelete = append(toDelete, obj)
+	}
+	return bootstrap.DeleteResources(ctx, discoveryClient, dynamicClient, toDelete)
+}
+
+// ImageOption sets the konnector image, and the version recorded on the Deployment.
+func ImageOption(image, version string) bootstrap.Option {
+	return bootstrap.ReplaceOption(
+		"image: "+DefaultImage, "image: "+image,
+		VersionAnnotationKey+": "+DefaultVersion, VersionAnnotationKey+": \""+version+"\"",
+	)
 }
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package konnector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBootstrapRequiresCertManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		wantErr   string
+	}{
+		{
+			name:    "without cert-manager",
+			wantErr: "the konnector webhook requires cert-manager",
+		},
+		{
+			name:      "with cert-manager",
+			resources: []*metav1.APIResourceList{{GroupVersion: certManagerGroupVersion}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discoveryClient := kubefake.NewSimpleClientset().Discovery().(*fake.FakeDiscovery)
+			discoveryClient.Resources = tt.resources
+
+			err := requireCertManager(discoveryClient)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+
+			// fails without waiting for the resources to be created
+			require.ErrorContains(t, Bootstrap(context.Background(), discoveryClient, nil, sets.NewString(WebhookBattery)), tt.wantErr)
+		})
+	}
+}
@@ -44,9 +44,113 @@ const (
 	// schema is applied to the consumer cluster.
 	APIServiceBindingConditionSchemaInSync conditionsapi.ConditionType = "SchemaInSync"
 
+	// APIServiceBindingConditionMigrated is set to false when the APIServiceBinding has been
+	// pointed to another service provider, until all consumer objects have been recreated
+	// there. It is not set for bindings that have never been migrated.
+	APIServiceBindingConditionMigrated conditionsapi.ConditionType = "Migrated"
+
 	// DownstreamFinalizer is put on downstream objects to block their deletion until
 	// the upstream object has been deleted.
 	DownstreamFinalizer = "kubebind.io/syncer"
+
+	// ServiceBindingFinalizer is put on APIServiceBindings to block their deletion until the
+	// CustomResourceDefinitions and objects of the bound resources have been handled according
+	// to the CRD retention policy.
+	ServiceBindingFinalizer = "kubebind.io/crd-retention"
+
+	// RetainedFromAnnotationKey is set by the konnector on CustomResourceDefinitions that
+	// were retained when the given APIServiceBinding was deleted. A new APIServiceBinding
+	// for the same resource adopts such a CustomResourceDefinition.
+	RetainedFromAnnotationKey = "kube-bind.io/retained-from"
+
+	// UpstreamNamespaceAnnotationKey is set by the konnector on downstream objects to the
+	// namespace of the upstream object in the service provider cluster.
+	UpstreamNamespaceAnnotationKey = "kube-bind.io/upstream-namespace"
+
+	// UpstreamNameAnnotationKey is set by the konnector on downstream objects to the
+	// name of the upstream object in the service provider cluster.
+	UpstreamNameAnnotationKey = "kube-bind.io/upstream-name"
+
+	// SyncedGenerationAnnotationKey is set by the konnector on downstream objects to the
+	// last generation of the downstream object that was successfully synced upstream.
+	SyncedGenerationAnnotationKey = "kube-bind.io/synced-generation"
+
+	// UpstreamResourceVersionAnnotationKey is set by the konnector on downstream objects to
+	// the resourceVersion of the upstream object whose status was synced last. Upstream
+	// changes that leave the status untouched do not update it.
+	UpstreamResourceVersionAnnotationKey = "kube-bind.io/upstream-resource-version"
+
+	// UpstreamUIDAnnotationKey is set by the konnector on downstream objects to the UID of
+	// the upstream object they have been synced to. An observed deletion of an upstream
+	// object with this UID is what marks a deletion by the service provider.
+	UpstreamUIDAnnotationKey = "kube-bind.io/upstream-uid"
+
+	// UpstreamGenerationsAnnotationKey is set by the konnector on downstream objects to a
+	// comma separated list of "<upstream generation>=<downstream generation>" pairs, mapping
+	// the latest upstream generations to the downstream generations that produced them.
+	// It is used to translate observedGeneration fields in the status.
+	UpstreamGenerationsAnnotationKey = "kube-bind.io/upstream-generations"
+
+	// SyncErrorAnnotationKey is set by the konnector on downstream objects to the last
+	// error when syncing the object upstream. It is removed on successful sync.
+	SyncErrorAnnotationKey = "kube-bind.io/sync-error"
+
+	// ReplacingUpstreamAnnotationKey is set by the konnector on downstream objects to the UID
+	// of the upstream object that is being deleted in order to be recreated, because the
+	// service provider rejected a change of immutable fields.
+	ReplacingUpstreamAnnotationKey = "kube-bind.io/replacing-upstream"
+
+	// OriginLabelKey is set by the konnector on objects it creates while syncing. Upstream
+	// objects created from downstream objects are labeled with OriginConsumer. Downstream
+	// objects created from provider-created upstream objects are labeled with OriginProvider,
+	// are owned by the service provider and read-only in the consumer cluster.
+	OriginLabelKey = "kube-bind.io/origin"
+
+	// OriginConsumer is the value of OriginLabelKey for upstream objects created from downstream objects.
+	OriginConsumer = "consumer"
+
+	// OriginProvider is the value of OriginLabelKey for downstream objects created from
+	// provider-created upstream objects.
+	OriginProvider = "provider"
+
+	// ProviderAnnotationKey is set by the konnector on downstream objects to the ID of the
+	// service provider they have last been synced to, i.e. the UID of its ClusterBinding.
+	// Objects with another ID are recreated upstream after migrating the APIServiceBinding.
+	ProviderAnnotationKey = "kube-bind.io/provider"
+
+	// ConsumerClusterLabelKey is set by the konnector on upstream objects to the ID of the
+	// consumer cluster, i.e. the UID of its kube-system namespace.
+	ConsumerClusterLabelKey = "kube-bind.io/consumer-cluster"
+
+	// ConsumerNamespaceLabelKey is set by the konnector on upstream objects to the namespace
+	// of the downstream object. It is not set for cluster-scoped downstream objects.
+	ConsumerNamespaceLabelKey = "kube-bind.io/consumer-namespace"
+
+	// ConsumerUIDLabelKey is set by the konnector on upstream objects to the UID of the
+	// downstream object.
+	ConsumerUIDLabelKey = "kube-bind.io/consumer-uid"
+
+	// ConsumerNameAnnotationKey is set by the konnector on upstream objects to the name of
+	// the downstream object. It is an annotation because names can exceed the length of label values.
+	ConsumerNameAnnotationKey = "kube-bind.io/consumer-name"
+
+	// DownstreamConditionSynced is set by the konnector in the status of downstream objects.
+	// It is false if the service provider rejected the upstream object permanently,
+	// e.g. due to validation or admission. The konnector does not retry syncing until
+	// the downstream spec changes.
+	DownstreamConditionSynced = "Synced"
+
+	// DownstreamConditionUpstreamDeleted is set by the konnector to false in the status of
+	// deleting downstream objects while their upstream objects are still being deleted,
+	// with the pending upstream finalizers in the message. The downstream object is only
+	// released when the upstream object is gone.
+	DownstreamConditionUpstreamDeleted = "UpstreamDeleted"
+
+	// DownstreamConditionDeletedByProvider is set by the konnector in the status of downstream
+	// objects whose upstream objects have been deleted by the service provider, if the
+	// APIServiceExport declares such deletions authoritative. The upstream object is not
+	// recreated. Recreate the downstream object to bind it again.
+	DownstreamConditionDeletedByProvider = "DeletedByProvider"
 )
 
 // APIServiceBinding binds an API service represented by a APIServiceExport
@@ -92,17 +196,74 @@ type APIServiceBindingSpec struct {
 
 	// kubeconfigSecretName is the secret ref that contains the kubeconfig of the service cluster.
 	//
+	// Pointing it to the kubeconfig of another service provider, or of the same service
+	// provider in another cluster, migrates the binding: the CustomResourceDefinitions are
+	// kept, and the upstream objects are recreated in the new service provider from the
+	// consumer objects. The progress is reported in the Migrated condition. Upstream objects
+	// in the old service provider are left untouched.
+	//
 	// +required
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="kubeconfigSecretRef is immutable"
 	KubeconfigSecretRef ClusterSecretKeyRef `json:"kubeconfigSecretRef"`
+
+	// providerDeletion specifies what happens to a downstream object when the service provider
+	// deletes its upstream object, and the APIServiceExport declares such deletions authoritative.
+	//
+	// Condition: the downstream object is kept and marked with a DeletedByProvider condition.
+	// Delete:    the downstream object is deleted.
+	//
+	// +optional
+	// +kubebuilder:default=Condition
+	ProviderDeletion ProviderDeletionPolicy `json:"providerDeletion,omitempty"`
+
+	// crdRetention specifies what happens to the CustomResourceDefinitions of the bound
+	// resources and to their objects in the consumer cluster when this binding is deleted.
+	//
+	// Retain: the CustomResourceDefinitions and all objects are kept, but are not synced
+	//         anymore. The upstream objects are left untouched.
+	// Delete: the CustomResourceDefinitions are deleted. The objects are deleted, together
+	//         with their upstream objects, before the binding is gone.
+	//
+	// +optional
+	// +kubebuilder:default=Retain
+	CRDRetention CRDRetentionPolicy `json:"crdRetention,omitempty"`
 }
 
+// CRDRetentionPolicy specifies how the CustomResourceDefinitions of bound resources are
+// handled when the APIServiceBinding is deleted.
+//
+// +kubebuilder:validation:Enum=Retain;Delete
+type CRDRetentionPolicy string
+
+const (
+	// CRDRetentionPolicyRetain means that the CustomResourceDefinitions and their objects are kept.
+	CRDRetentionPolicyRetain CRDRetentionPolicy = "Retain"
+	// CRDRetentionPolicyDelete means that the CustomResourceDefinitions and their objects are deleted.
+	CRDRetentionPolicyDelete CRDRetentionPolicy = "Delete"
+)
+
+// ProviderDeletionPolicy specifies how downstream objects are handled whose upstream
+// objects have been deleted by the service provider.
+//
+// +kubebuilder:validation:Enum=Condition;Delete
+type ProviderDeletionPolicy string
+
+const (
+	// ProviderDeletionPolicyCondition means that the downstream object is marked with a DeletedByProvider condition.
+	ProviderDeletionPolicyCondition ProviderDeletionPolicy = "Condition"
+	// ProviderDeletionPolicyDelete means that the downstream object is deleted.
+	ProviderDeletionPolicyDelete ProviderDeletionPolicy = "Delete"
+)
+
 type APIServiceBindingStatus struct {
 	// providerPrettyName is the pretty name of the service provider cluster. This
 	// can be shared among different APIServiceBindings.
 	ProviderPrettyName string `json:"providerPrettyName,omitempty"`
 
+	// provider is the ID of the service provider the bound resources are synced to, i.e.
+	// the UID of its ClusterBinding. A change of it starts a migration.
+	Provider string `json:"provider,omitempty"`
+
 	// conditions is a list of conditions that apply to the APIServiceBinding.
 	Conditions conditionsapi.Conditions `json:"conditions,omitempty"`
 }
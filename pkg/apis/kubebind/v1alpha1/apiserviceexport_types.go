@@ -92,8 +92,113 @@ type APIServiceExportSpec struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:XValidation:rule="self != \"Namespaced\"",message="Namespaced scope not yet supported"
 	Scope Scope `json:"scope"`
+
+	// clusterScopedIsolation specifies how cluster-scoped consumer objects are isolated
+	// from those of other consumers on the service provider cluster.
+	//
+	// None:       cluster-scoped consumer objects are stored as cluster-scoped objects with
+	//             the same name. Objects of different consumers can collide and are visible
+	//             to each other.
+	// Namespaced: the resources are namespaced on the service provider cluster, while
+	//             cluster-scoped in the consumer cluster. Consumer objects are stored with
+	//             the same name in the namespace of the ClusterBinding.
+	//
+	// +optional
+	// +kubebuilder:default=None
+	ClusterScopedIsolation Isolation `json:"clusterScopedIsolation,omitempty"`
+
+	// namespaceIsolation specifies how namespaced consumer objects are mapped to namespaces
+	// on the service provider cluster.
+	//
+	// ServiceNamespace: every consumer namespace is mapped to its own namespace on the service
+	//                   provider cluster through an APIServiceNamespace.
+	// ClusterNamespace: all consumer objects are stored in the namespace of the ClusterBinding.
+	//                   Their names are mangled from the consumer namespace and name as
+	//                   "<namespace>.<name>", shortened with a hash suffix if too long.
+	//
+	// +optional
+	// +kubebuilder:default=ServiceNamespace
+	NamespaceIsolation NamespaceIsolation `json:"namespaceIsolation,omitempty"`
+
+	// providerObjects specifies how objects are handled that the service provider creates
+	// itself in a service namespace, i.e. that do not originate from the consumer cluster.
+	//
+	// None: provider-created objects are not synced to the consumer cluster. Like all
+	//       upstream objects without a downstream object, they are deleted by the konnector.
+	// Sync: provider-created objects are synced into the consumer namespace, labeled as
+	//       provider-owned and read-only. Consumer changes to them are reverted.
+	//
+	// +optional
+	// +kubebuilder:default=None
+	ProviderObjects ProviderObjectsPolicy `json:"providerObjects,omitempty"`
+
+	// upstreamDeletion specifies how the konnector treats upstream objects that the service
+	// provider deletes while the downstream object still exists, e.g. for abuse or expiry.
+	//
+	// Recreate:      the upstream object is recreated from the downstream object.
+	// Authoritative: the deletion is final. The downstream object is marked with a
+	//                DeletedByProvider condition or deleted, according to the providerDeletion
+	//                policy of the APIServiceBinding. Only deletions observed by the konnector
+	//                are final. Upstream objects deleted while the konnector is not running
+	//                are recreated.
+	//
+	// +optional
+	// +kubebuilder:default=Recreate
+	UpstreamDeletion UpstreamDeletionPolicy `json:"upstreamDeletion,omitempty"`
 }
 
+// UpstreamDeletionPolicy specifies how provider-side deletions of upstream objects are handled.
+//
+// +kubebuilder:validation:Enum=Recreate;Authoritative
+type UpstreamDeletionPolicy string
+
+const (
+	// UpstreamDeletionPolicyRecreate means that upstream objects deleted by the service provider are recreated.
+	UpstreamDeletionPolicyRecreate UpstreamDeletionPolicy = "Recreate"
+	// UpstreamDeletionPolicyAuthoritative means that deletions of upstream objects by the service provider are final.
+	UpstreamDeletionPolicyAuthoritative UpstreamDeletionPolicy = "Authoritative"
+)
+
+// Isolation specifies how consumer objects are isolated on the service provider cluster.
+//
+// +kubebuilder:validation:Enum=None;Namespaced
+type Isolation string
+
+const (
+	// IsolationNone means that cluster-scoped consumer objects are stored as cluster-scoped objects.
+	IsolationNone Isolation = "None"
+	// IsolationNamespaced means that cluster-scoped consumer objects are stored as namespaced objects
+	// in the namespace of the ClusterBinding.
+	IsolationNamespaced Isolation = "Namespaced"
+)
+
+// NamespaceIsolation specifies how consumer namespaces are mapped on the service provider cluster.
+//
+// +kubebuilder:validation:Enum=ServiceNamespace;ClusterNamespace
+type NamespaceIsolation string
+
+const (
+	// NamespaceIsolationServiceNamespace means that every consumer namespace is mapped to
+	// its own namespace on the service provider cluster.
+	NamespaceIsolationServiceNamespace NamespaceIsolation = "ServiceNamespace"
+	// NamespaceIsolationClusterNamespace means that all namespaced consumer objects are stored
+	// with mangled names in the namespace of the ClusterBinding.
+	NamespaceIsolationClusterNamespace NamespaceIsolation = "ClusterNamespace"
+)
+
+// ProviderObjectsPolicy specifies how provider-created objects are handled.
+//
+// +kubebuilder:validation:Enum=None;Sync
+type ProviderObjectsPolicy string
+
+const (
+	// ProviderObjectsPolicyNone means that provider-created objects are not synced to the consumer cluster.
+	ProviderObjectsPolicyNone ProviderObjectsPolicy = "None"
+	// ProviderObjectsPolicySync means that provider-created objects are synced to the consumer cluster
+	// as read-only objects.
+	ProviderObjectsPolicySync ProviderObjectsPolicy = "Sync"
+)
+
 type APIServiceExportStatus struct {
 	// conditions is a list of conditions that apply to the APIServiceExport.
 	Conditions conditionsapi.Conditions `json:"conditions,omitempty"`
@@ -101,8 +206,34 @@ type APIServiceExportStatus struct {
 
 type APIServiceExportGroupResource struct {
 	GroupResource `json:",inline"`
+
+	// onImmutableChange specifies how the konnector reacts when the service provider
+	// rejects an update of an upstream object because immutable fields were changed,
+	// e.g. by a CEL transition rule like "self == oldSelf".
+	//
+	// Reject:  the update is not retried and the rejection is reported in the Synced
+	//          condition of the downstream object.
+	// Replace: the upstream object is deleted and, after it is gone including all its
+	//          finalizers and dependents, recreated with the new spec. The replacement is
+	//          reported as an event on the downstream object.
+	//
+	// +optional
+	// +kubebuilder:default=Reject
+	OnImmutableChange ImmutableChangePolicy `json:"onImmutableChange,omitempty"`
 }
 
+// ImmutableChangePolicy specifies how rejected changes of immutable fields are handled.
+//
+// +kubebuilder:validation:Enum=Reject;Replace
+type ImmutableChangePolicy string
+
+const (
+	// ImmutableChangePolicyReject means that rejected changes of immutable fields are reported, but not retried.
+	ImmutableChangePolicyReject ImmutableChangePolicy = "Reject"
+	// ImmutableChangePolicyReplace means that the upstream object is deleted and recreated.
+	ImmutableChangePolicyReplace ImmutableChangePolicy = "Replace"
+)
+
 // GroupResource identifies a resource.
 type GroupResource struct {
 	// group is the name of an API group.
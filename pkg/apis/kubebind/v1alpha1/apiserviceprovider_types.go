@@ -49,6 +49,22 @@ type APIServiceProviderSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	AuthenticatedClientURL string `json:"authenticatedClientURL"`
 
+	// authenticationCollectURL is the service provider url where a headless service consumer collects
+	// the authentication response of its session, after the user has authenticated on any device through
+	// the authenticatedClientURL without a callback url, e.g: www.mangodb.com/kubernetes/collect. If empty,
+	// headless authentication is not supported.
+	//
+	// +optional
+	AuthenticationCollectURL string `json:"authenticationCollectURL,omitempty"`
+
+	// authenticationRefreshURL is the service provider url where a service consumer requests a new
+	// kubeconfig with the session token of a previous authentication, without a new browser login,
+	// e.g: www.mangodb.com/kubernetes/refresh. A DELETE request with the session token revokes it.
+	// If empty, sessions cannot be refreshed.
+	//
+	// +optional
+	AuthenticationRefreshURL string `json:"authenticationRefreshURL,omitempty"`
+
 	// providerPrettyName is the pretty name of the service provider where the APIServiceBinding is eventually bound. e.g:
 	// MongoDB.Inc
 	//
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authenticator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+)
+
+type headlessAuthenticator struct {
+	collectURL string
+	sessionID  string
+	interval   time.Duration
+	timeout    time.Duration
+	action     func(context.Context, *resources.AuthResponse) error
+	client     *http.Client
+}
+
+// NewHeadlessAuthenticator returns an authenticator without a callback on localhost. Instead, it
+// polls the service provider for the authentication response of the given session, while the user
+// authenticates on any device. The session ID must be secret because it grants the response.
+func NewHeadlessAuthenticator(collectURL, sessionID string, timeout time.Duration, action func(context.Context, *resources.AuthResponse) error) (Authenticator, error) {
+	if _, err := url.Parse(collectURL); err != nil {
+		return nil, fmt.Errorf("invalid collect url %q: %w", collectURL, err)
+	}
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &headlessAuthenticator{
+		collectURL: collectURL,
+		sessionID:  sessionID,
+		interval:   2 * time.Second,
+		timeout:    timeout,
+		action:     action,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// confirmationCodeAlphabet avoids characters that are easily confused, like 0 and O, or 1 and I.
+const confirmationCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewConfirmationCode returns a random code of the form XXXX-XXXX that the user has to enter in the
+// browser to confirm a headless session started on the command line.
+func NewConfirmationCode() (string, error) {
+	bs := make([]byte, 8)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	var code strings.Builder
+	for i, b := range bs {
+		if i == 4 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(confirmationCodeAlphabet[int(b)%len(confirmationCodeAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// StartHeadlessSession registers a headless session with the confirmation code at the service
+// provider. This has to happen before the user is sent to the authorize url.
+func StartHeadlessSession(ctx context.Context, collectURL, sessionID, code string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, collectURL, strings.NewReader(url.Values{"s": {sessionID}, "c": {code}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to start authentication session: %w", err)
+	}
+	defer resp.Body.Close()          // nolint: errcheck
+	body, _ := io.ReadAll(resp.Body) // nolint: errcheck
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusServiceUnavailable:
+		return fmt.Errorf("service provider cannot start more authentication sessions, try again later: %s", strings.TrimSpace(string(body)))
+	default:
+		return fmt.Errorf("failed to start authentication session: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// Endpoint returns an empty string because there is no callback.
+func (h *headlessAuthenticator) Endpoint(context.Context) string {
+	return ""
+}
+
+func (h *headlessAuthenticator) Execute(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+
+	// the session might not be visible yet, e.g. behind a load balancer. Hence, a missing session
+	// is only fatal after it has been seen pending once.
+	started := false
+	err := wait.PollImmediateWithContext(ctx, h.interval, h.timeout, func(ctx context.Context) (bool, error) {
+		resp, err := h.client.PostForm(h.collectURL, url.Values{"s": {h.sessionID}})
+		if err != nil {
+			logger.V(2).Info("failed to collect authentication response, retrying", "error", err)
+			return false, nil
+		}
+		defer resp.Body.Close() // nolint: errcheck
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			logger.V(2).Info("failed to read authentication response, retrying", "error", err)
+			return false, nil
+		}
+
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			started = true
+			return false, nil
+		case http.StatusOK:
+		case http.StatusNotFound:
+			if !started {
+				logger.V(2).Info("authentication session not started yet, retrying")
+				return false, nil
+			}
+			return false, errors.New("authentication session not found or expired")
+		default:
+			return false, fmt.Errorf("failed to collect authentication response: %s: %s", resp.Status, body)
+		}
+
+		authResponse := &resources.AuthResponse{}
+		if err := json.Unmarshal(body, authResponse); err != nil {
+			return false, fmt.Errorf("failed to decode authentication response: %w", err)
+		}
+		return true, h.action(ctx, authResponse)
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return errors.New("authentication timeout")
+	}
+	return err
+}
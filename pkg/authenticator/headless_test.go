@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authenticator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+)
+
+// fakeCollector simulates the /collect endpoint of a backend, with the session only known
+// after the user has called authorize, which happens after authorizeAt polls.
+type fakeCollector struct {
+	lock        sync.Mutex
+	polls       int
+	authorizeAt int
+	pending     int
+	response    *resources.AuthResponse
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.polls++
+	switch {
+	case c.polls <= c.authorizeAt:
+		http.Error(w, "session not found", http.StatusNotFound)
+	case c.pending > 0:
+		c.pending--
+		w.WriteHeader(http.StatusAccepted)
+	case c.response != nil:
+		json.NewEncoder(w).Encode(c.response) // nolint:errcheck
+		c.response = nil
+	default:
+		http.Error(w, "session not found", http.StatusNotFound)
+	}
+}
+
+func TestHeadlessAuthenticator(t *testing.T) {
+	tests := []struct {
+		name        string
+		pending     int
+		response    *resources.AuthResponse
+		authorizeAt int
+		wantErr     string
+	}{
+		{
+			name:        "polls before authorize",
+			pending:     2,
+			response:    &resources.AuthResponse{SessionID: "session"},
+			authorizeAt: 3,
+		},
+		{
+			name:        "authorized before the first poll",
+			pending:     1,
+			response:    &resources.AuthResponse{SessionID: "session"},
+			authorizeAt: 0,
+		},
+		{
+			name:        "session disappears after it was pending",
+			pending:     1,
+			authorizeAt: 0,
+			wantErr:     "authentication session not found or expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &fakeCollector{authorizeAt: tt.authorizeAt, pending: tt.pending, response: tt.response}
+			server := httptest.NewServer(collector)
+			defer server.Close()
+
+			var got *resources.AuthResponse
+			auth, err := NewHeadlessAuthenticator(server.URL, strings.Repeat("a", 64), 10*time.Second, func(ctx context.Context, resp *resources.AuthResponse) error {
+				got = resp
+				return nil
+			})
+			require.NoError(t, err)
+			auth.(*headlessAuthenticator).interval = 10 * time.Millisecond
+
+			err = auth.Execute(context.Background())
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.response.SessionID, got.SessionID)
+		})
+	}
+}
+
+func TestNewConfirmationCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := NewConfirmationCode()
+		require.NoError(t, err)
+		require.Len(t, code, 9)
+		require.Equal(t, byte('-'), code[4])
+		for _, c := range strings.ReplaceAll(code, "-", "") {
+			require.Contains(t, confirmationCodeAlphabet, string(c))
+		}
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	apimachineryerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -71,6 +72,7 @@ func ReplaceOption(pairs ...string) Option {
 // Bootstrap creates resources in a package's fs by
 // continuously retrying the list. This is blocking, i.e. it only returns (with error)
 // when the context is closed or with nil when the bootstrapping is successfully completed.
+// The error of a closed context includes the last failure.
 func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, batteriesIncluded sets.String, fs embed.FS, opts ...Option) error {
 	cache := memory.NewMemCacheClient(discoveryClient)
 	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cache)
@@ -80,9 +82,11 @@ func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface
 	for _, opt := range opts {
 		transformers = append(transformers, opt.TransformFile)
 	}
-	return wait.PollImmediateInfiniteWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+	var lastErr error
+	err := wait.PollImmediateInfiniteWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
 		if err := CreateResourcesFromFS(ctx, dynamicClient, mapper, batteriesIncluded, fs, transformers...); err != nil {
 			klog.Infof("Failed to bootstrap resources, retrying: %v", err)
+			lastErr = err
 			// invalidate cache if resources not found
 			// xref: https://github.com/kcp-dev/kcp/issues/655
 			cache.Invalidate()
@@ -90,6 +94,10 @@ func Bootstrap(ctx context.Context, discoveryClient discovery.DiscoveryInterface
 		}
 		return true, nil
 	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("%w: %v", err, lastErr)
+	}
+	return err
 }
 
 // CreateResourcesFromFS creates all resources from a filesystem.
@@ -149,51 +157,92 @@ func CreateResourceFromFS(ctx context.Context, client dynamic.Interface, mapper
 	return apimachineryerrors.NewAggregate(errs)
 }
 
-const annotationCreateOnlyKey = "bootstrap.kube-bind.io/create-only"
-const annotationBattery = "bootstrap.kube-bind.io/battery"
-
-func createResourceFromFS(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, raw []byte, batteriesIncluded sets.String) error {
-	type Input struct {
-		Batteries map[string]bool
-	}
-	input := Input{
-		Batteries: map[string]bool{},
-	}
-	for _, b := range batteriesIncluded.List() {
-		input.Batteries[b] = true
-	}
-	tmpl, err := template.New("manifest").Parse(string(raw))
+// RenderResourcesFromFS returns all resources from a filesystem in file order, without
+// creating them. Resources of batteries not included are skipped.
+func RenderResourcesFromFS(fs embed.FS, batteriesIncluded sets.String, transformers ...TransformFileFunc) ([]*unstructured.Unstructured, error) {
+	files, err := fs.ReadDir(".")
 	if err != nil {
-		return fmt.Errorf("failed to parse manifest: %w", err)
-	}
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, input); err != nil {
-		return fmt.Errorf("failed to execute manifest: %w", err)
+		return nil, err
 	}
 
-	obj, gvk, err := extensionsapiserver.Codecs.UniversalDeserializer().Decode(buf.Bytes(), nil, &unstructured.Unstructured{})
-	if err != nil {
-		return fmt.Errorf("could not decode raw: %w", err)
-	}
-	u, ok := obj.(*unstructured.Unstructured)
-	if !ok {
-		return fmt.Errorf("decoded into incorrect type, got %T, wanted %T", obj, &unstructured.Unstructured{})
-	}
+	var objs []*unstructured.Unstructured
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		raw, err := fs.ReadFile(f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", f.Name(), err)
+		}
 
-	if v, found := u.GetAnnotations()[annotationBattery]; found {
-		partOf := strings.Split(v, ",")
-		included := false
-		for _, p := range partOf {
-			if batteriesIncluded.Has(strings.TrimSpace(p)) {
-				included = true
+		d := kubeyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(raw)))
+		for i := 1; ; i++ {
+			doc, err := d.Read()
+			if errors.Is(err, io.EOF) {
 				break
+			} else if err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+
+			for _, transformer := range transformers {
+				doc, err = transformer(doc)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			u, _, err := renderResource(doc, batteriesIncluded)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render resource %s doc %d: %w", f.Name(), i, err)
+			} else if u != nil {
+				objs = append(objs, u)
 			}
 		}
-		if !included {
-			klog.V(4).Infof("Skipping %s because %s is/are not among included batteries %s", u.GetName(), v, batteriesIncluded)
-			return nil
+	}
+	return objs, nil
+}
+
+// DeleteResources deletes the given resources in reverse order. Resources that do not exist
+// are ignored.
+func DeleteResources(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, objs []*unstructured.Unstructured) error {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	var errs []error
+	for i := len(objs) - 1; i >= 0; i-- {
+		u := objs[i]
+		gvk := u.GroupVersionKind()
+		m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			continue // the API is gone, and so is the object
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("could not get REST mapping for %s: %w", gvk, err))
+			continue
+		}
+
+		err = dynamicClient.Resource(m.Resource).Namespace(u.GetNamespace()).Delete(ctx, u.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("could not delete %s %s: %w", gvk.Kind, qualifiedObjectName(u), err))
+			continue
+		} else if err == nil {
+			klog.Infof("Deleted %s %s", gvk.Kind, qualifiedObjectName(u))
 		}
 	}
+	return apimachineryerrors.NewAggregate(errs)
+}
+
+const annotationCreateOnlyKey = "bootstrap.kube-bind.io/create-only"
+const annotationBattery = "bootstrap.kube-bind.io/battery"
+
+func createResourceFromFS(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, raw []byte, batteriesIncluded sets.String) error {
+	u, gvk, err := renderResource(raw, batteriesIncluded)
+	if err != nil {
+		return err
+	} else if u == nil {
+		return nil
+	}
 
 	m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
@@ -234,6 +283,54 @@ func createResourceFromFS(ctx context.Context, client dynamic.Interface, mapper
 	return nil
 }
 
+// renderResource executes the manifest template and decodes it. It returns nil if the
+// resource belongs to batteries not included.
+func renderResource(raw []byte, batteriesIncluded sets.String) (*unstructured.Unstructured, *schema.GroupVersionKind, error) {
+	type Input struct {
+		Batteries map[string]bool
+	}
+	input := Input{
+		Batteries: map[string]bool{},
+	}
+	for _, b := range batteriesIncluded.List() {
+		input.Batteries[b] = true
+	}
+	tmpl, err := template.New("manifest").Parse(string(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, input); err != nil {
+		return nil, nil, fmt.Errorf("failed to execute manifest: %w", err)
+	}
+
+	obj, gvk, err := extensionsapiserver.Codecs.UniversalDeserializer().Decode(buf.Bytes(), nil, &unstructured.Unstructured{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode raw: %w", err)
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil, fmt.Errorf("decoded into incorrect type, got %T, wanted %T", obj, &unstructured.Unstructured{})
+	}
+
+	if v, found := u.GetAnnotations()[annotationBattery]; found {
+		partOf := strings.Split(v, ",")
+		included := false
+		for _, p := range partOf {
+			if batteriesIncluded.Has(strings.TrimSpace(p)) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			klog.V(4).Infof("Skipping %s because %s is/are not among included batteries %s", u.GetName(), v, batteriesIncluded)
+			return nil, nil, nil
+		}
+	}
+
+	return u, gvk, nil
+}
+
 func qualifiedObjectName(obj metav1.Object) string {
 	if len(obj.GetNamespace()) > 0 {
 		return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package indexers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+const (
+	UpstreamByConsumerObject = "upstreamByConsumerObject"
+)
+
+// IndexUpstreamByConsumerObject indexes upstream objects by the consumer cluster,
+// namespace and name in their provenance labels and annotations.
+func IndexUpstreamByConsumerObject(obj interface{}) ([]string, error) {
+	upstream, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, nil
+	}
+	clusterID := upstream.GetLabels()[kubebindv1alpha1.ConsumerClusterLabelKey]
+	name := upstream.GetAnnotations()[kubebindv1alpha1.ConsumerNameAnnotationKey]
+	if clusterID == "" || name == "" {
+		return nil, nil
+	}
+	return []string{UpstreamByConsumerObjectKey(clusterID, upstream.GetLabels()[kubebindv1alpha1.ConsumerNamespaceLabelKey], name)}, nil
+}
+
+func UpstreamByConsumerObjectKey(clusterID, ns, name string) string {
+	if ns == "" {
+		return clusterID + "/" + name
+	}
+	return clusterID + "/" + ns + "/" + name
+}
@@ -17,18 +17,25 @@ limitations under the License.
 package konnector
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/pointer"
 
 	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
 	bindinformers "github.com/kube-bind/kube-bind/pkg/client/informers/externalversions"
 	"github.com/kube-bind/kube-bind/pkg/konnector/options"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 type Config struct {
@@ -40,6 +47,9 @@ type Config struct {
 	KubeInformers          kubeinformers.SharedInformerFactory
 	BindInformers          bindinformers.SharedInformerFactory
 	ApiextensionsInformers apiextensionsinformers.SharedInformerFactory
+
+	// Webhook is the validating admission webhook for bound resources, or nil if disabled.
+	Webhook *webhook.Server
 }
 
 func NewConfig(options *options.CompletedOptions) (*Config, error) {
@@ -71,5 +81,42 @@ func NewConfig(options *options.CompletedOptions) (*Config, error) {
 	config.BindInformers = bindinformers.NewSharedInformerFactory(config.BindClient, time.Minute*30)
 	config.ApiextensionsInformers = apiextensionsinformers.NewSharedInformerFactory(config.ApiextensionsClient, time.Minute*30)
 
+	if options.WebhookBindAddress != "" {
+		webhookOptions, err := newWebhookOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		config.Webhook = webhook.NewServer(*webhookOptions, config.KubeClient)
+	}
+
 	return config, nil
 }
+
+func newWebhookOptions(options *options.CompletedOptions) (*webhook.Options, error) {
+	caBundle, err := os.ReadFile(options.WebhookCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook CA file: %w", err)
+	}
+
+	ns, name, _ := strings.Cut(options.WebhookService, "/")
+	service := admissionregistrationv1.ServiceReference{Namespace: ns, Name: name}
+	if name, port, found := strings.Cut(name, ":"); found {
+		p, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook service port %q: %w", port, err)
+		}
+		service.Name = name
+		service.Port = pointer.Int32(int32(p))
+	}
+
+	return &webhook.Options{
+		BindAddress:   options.WebhookBindAddress,
+		CertFile:      options.WebhookCertFile,
+		KeyFile:       options.WebhookKeyFile,
+		CABundle:      caBundle,
+		Service:       service,
+		FailurePolicy: admissionregistrationv1.FailurePolicyType(options.WebhookFailurePolicy),
+		PodNamespace:  options.PodNamespace,
+		PodName:       options.PodName,
+	}, nil
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexport"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 const (
@@ -62,6 +63,7 @@ func NewController(
 	namespaceInformer dynamic.Informer[corelisters.NamespaceLister],
 	serviceBindingInformer dynamic.Informer[bindlisters.APIServiceBindingLister],
 	crdInformer dynamic.Informer[crdlisters.CustomResourceDefinitionLister],
+	validators webhook.Registry,
 ) (*controller, error) {
 	consumerConfig = rest.CopyConfig(consumerConfig)
 	consumerConfig = rest.AddUserAgent(consumerConfig, controllerName)
@@ -99,6 +101,12 @@ func NewController(
 		}),
 	)
 
+	consumerClusterRoleInformers := kubernetesinformers.NewSharedInformerFactoryWithOptions(consumerKubeClient, time.Minute*30,
+		kubernetesinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = servicebinding.ClusterRoleLabelKey
+		}),
+	)
+
 	// create controllers
 	clusterbindingCtrl, err := clusterbinding.NewController(
 		consumerSecretRefKey,
@@ -141,9 +149,11 @@ func NewController(
 		consumerConfig,
 		providerConfig,
 		serviceBindingInformer,
+		providerBindInformers.KubeBind().V1alpha1().ClusterBindings(),
 		providerBindInformers.KubeBind().V1alpha1().APIServiceExports(),
 		providerBindInformers.KubeBind().V1alpha1().APIServiceExportResources(),
 		crdInformer,
+		consumerClusterRoleInformers.Rbac().V1().ClusterRoles(),
 	)
 	if err != nil {
 		return nil, err
@@ -154,9 +164,13 @@ func NewController(
 		consumerConfig,
 		providerConfig,
 		providerBindInformers.KubeBind().V1alpha1().APIServiceExportResources(),
+		providerBindInformers.KubeBind().V1alpha1().APIServiceExports(),
 		providerBindInformers.KubeBind().V1alpha1().APIServiceNamespaces(),
+		providerBindInformers.KubeBind().V1alpha1().ClusterBindings(),
+		namespaceInformer,
 		serviceBindingInformer,
 		crdInformer,
+		validators,
 	)
 	if err != nil {
 		return nil, err
@@ -171,6 +185,7 @@ func NewController(
 			providerBindInformers,
 			providerKubeInformers,
 			consumerSecretInformers,
+			consumerClusterRoleInformers,
 		},
 
 		serviceBindingLister:  serviceBindingInformer.Lister(),
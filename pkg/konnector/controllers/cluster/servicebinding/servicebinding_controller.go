@@ -21,14 +21,22 @@ import (
 	"fmt"
 	"time"
 
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicclient "k8s.io/client-go/dynamic"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -45,6 +53,10 @@ import (
 
 const (
 	controllerName = "kube-bind-konnector-cluster-servicebinding"
+
+	// ClusterRoleLabelKey is set on the aggregated ClusterRoles of bound resources. The
+	// ClusterRole informer of the controller is restricted to it.
+	ClusterRoleLabelKey = "kube-bind.io/bound-resource"
 )
 
 // NewController returns a new controller for ServiceBindings.
@@ -52,9 +64,11 @@ func NewController(
 	consumerSecretRefKey, providerNamespace string,
 	consumerConfig, providerConfig *rest.Config,
 	serviceBindingInformer dynamic.Informer[bindlisters.APIServiceBindingLister],
+	clusterBindingInformer bindinformers.ClusterBindingInformer,
 	serviceExportInformer bindinformers.APIServiceExportInformer,
 	serviceExportResourceInformer bindinformers.APIServiceExportResourceInformer,
 	crdInformer dynamic.Informer[apiextensionslisters.CustomResourceDefinitionLister],
+	clusterRoleInformer rbacinformers.ClusterRoleInformer,
 ) (*controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -74,6 +88,18 @@ func NewController(
 	if err != nil {
 		return nil, err
 	}
+	consumerKubeClient, err := kubernetesclient.NewForConfig(consumerConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumerDynamicClient, err := dynamicclient.NewForConfig(consumerConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumerMetadataClient, err := metadata.NewForConfig(consumerConfig)
+	if err != nil {
+		return nil, err
+	}
 	providerBindClient, err := bindclient.NewForConfig(providerConfig)
 	if err != nil {
 		return nil, err
@@ -96,12 +122,25 @@ func NewController(
 			consumerSecretRefKey: consumerSecretRefKey,
 			providerNamespace:    providerNamespace,
 
+			getProviderID: func() (string, error) {
+				cb, err := clusterBindingInformer.Lister().ClusterBindings(providerNamespace).Get("cluster")
+				if err != nil {
+					return "", err
+				}
+				return string(cb.UID), nil
+			},
 			getServiceExport: func(name string) (*kubebindv1alpha1.APIServiceExport, error) {
 				return serviceExportInformer.Lister().APIServiceExports(providerNamespace).Get(name)
 			},
 			getServiceBinding: func(name string) (*kubebindv1alpha1.APIServiceBinding, error) {
 				return serviceBindingInformer.Lister().Get(name)
 			},
+			updateServiceBinding: func(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding) (*kubebindv1alpha1.APIServiceBinding, error) {
+				return consumerBindClient.KubeBindV1alpha1().APIServiceBindings().Update(ctx, binding, metav1.UpdateOptions{})
+			},
+			requeue: func(binding *kubebindv1alpha1.APIServiceBinding, after time.Duration) {
+				queue.AddAfter(binding.Name, after)
+			},
 			getServiceExportResource: func(name string) (*kubebindv1alpha1.APIServiceExportResource, error) {
 				return serviceExportResourceInformer.Lister().APIServiceExportResources(providerNamespace).Get(name)
 			},
@@ -111,12 +150,75 @@ func NewController(
 			getCRD: func(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
 				return crdInformer.Lister().Get(name)
 			},
+			listServiceBindingCRDs: func(name string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+				objs, err := crdInformer.Informer().GetIndexer().ByIndex(indexers.CRDByServiceBinding, name)
+				if err != nil {
+					return nil, err
+				}
+				crds := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(objs))
+				for _, obj := range objs {
+					crds = append(crds, obj.(*apiextensionsv1.CustomResourceDefinition))
+				}
+				// released, but objects might still carry finalizers
+				all, err := crdInformer.Lister().List(labels.Everything())
+				if err != nil {
+					return nil, err
+				}
+				for _, crd := range all {
+					if crd.Annotations[kubebindv1alpha1.RetainedFromAnnotationKey] == name {
+						crds = append(crds, crd)
+					}
+				}
+				return crds, nil
+			},
 			updateCRD: func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
 				return apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Update(ctx, crd, metav1.UpdateOptions{})
 			},
 			createCRD: func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
 				return apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, crd, metav1.CreateOptions{})
 			},
+			deleteCRD: func(ctx context.Context, name string) error {
+				return apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, name, metav1.DeleteOptions{})
+			},
+			listConsumerObjects: func(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+				list, err := consumerDynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				return list.Items, nil
+			},
+			listConsumerObjectMetadata: func(ctx context.Context, gvr schema.GroupVersionResource) ([]metav1.PartialObjectMetadata, error) {
+				var objs []metav1.PartialObjectMetadata
+				opts := metav1.ListOptions{Limit: 500}
+				for {
+					list, err := consumerMetadataClient.Resource(gvr).List(ctx, opts)
+					if err != nil {
+						return nil, err
+					}
+					objs = append(objs, list.Items...)
+					if list.Continue == "" {
+						return objs, nil
+					}
+					opts.Continue = list.Continue
+				}
+			},
+			updateConsumerObject: func(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				return consumerDynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+			},
+			getClusterRole: func(ctx context.Context, name string) (*rbacv1.ClusterRole, error) {
+				role, err := clusterRoleInformer.Lister().Get(name)
+				if errors.IsNotFound(err) {
+					// not created yet, or by an older konnector without label
+					return consumerKubeClient.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+				}
+				return role, err
+			},
+			createClusterRole: func(ctx context.Context, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+				return consumerKubeClient.RbacV1().ClusterRoles().Create(ctx, role, metav1.CreateOptions{})
+			},
+			updateClusterRole: func(ctx context.Context, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+				return consumerKubeClient.RbacV1().ClusterRoles().Update(ctx, role, metav1.UpdateOptions{})
+			},
 		},
 
 		commit: committer.NewCommitter[*kubebindv1alpha1.APIServiceBinding, *kubebindv1alpha1.APIServiceBindingSpec, *kubebindv1alpha1.APIServiceBindingStatus](
@@ -146,6 +248,15 @@ func NewController(
 		},
 	})
 
+	clusterRoleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) {
+			c.enqueueClusterRole(logger, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueueClusterRole(logger, obj)
+		},
+	})
+
 	return c, nil
 }
 
@@ -221,6 +332,27 @@ func (c *controller) enqueueCRD(logger klog.Logger, obj interface{}) {
 	}
 }
 
+// enqueueClusterRole queues the bindings owning a changed or deleted ClusterRole, in order
+// to revert the change.
+func (c *controller) enqueueClusterRole(logger klog.Logger, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	role, ok := obj.(*rbacv1.ClusterRole)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected type %T", obj))
+		return
+	}
+
+	for _, ref := range role.OwnerReferences {
+		if ref.APIVersion != kubebindv1alpha1.SchemeGroupVersion.String() || ref.Kind != "APIServiceBinding" {
+			continue
+		}
+		logger.V(2).Info("queueing APIServiceBinding", "key", ref.Name, "reason", "ClusterRole", "ClusterRoleKey", role.Name)
+		c.queue.Add(ref.Name)
+	}
+}
+
 // Start starts the controller, which stops when ctx.Done() is closed.
 func (c *controller) Start(ctx context.Context, numThreads int) {
 	defer runtime.HandleCrash()
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebinding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+func TestEnqueueClusterRole(t *testing.T) {
+	role := func(owners ...metav1.OwnerReference) *rbacv1.ClusterRole {
+		return &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "kube-bind:mangodbs.mangodb.com:view", OwnerReferences: owners}}
+	}
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want []string
+	}{
+		{
+			name: "owned by a binding",
+			obj:  role(bindingReference("mangodbs.mangodb.com")),
+			want: []string{"mangodbs.mangodb.com"},
+		},
+		{
+			name: "deleted",
+			obj:  cache.DeletedFinalStateUnknown{Key: "kube-bind:mangodbs.mangodb.com:view", Obj: role(bindingReference("mangodbs.mangodb.com"))},
+			want: []string{"mangodbs.mangodb.com"},
+		},
+		{
+			name: "not owned by a binding",
+			obj:  role(metav1.OwnerReference{APIVersion: "v1", Kind: "Namespace", Name: "default"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &controller{queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())}
+			defer c.queue.ShutDown()
+
+			c.enqueueClusterRole(klog.Background(), tt.obj)
+
+			var keys []string
+			for c.queue.Len() > 0 {
+				key, _ := c.queue.Get()
+				keys = append(keys, key.(string))
+				c.queue.Done(key)
+			}
+			require.Equal(t, tt.want, keys)
+		})
+	}
+}
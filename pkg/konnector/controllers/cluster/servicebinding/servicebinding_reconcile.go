@@ -19,36 +19,71 @@ package servicebinding
 import (
 	"context"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 
 	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
 	kubebindhelpers "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1/helpers"
 	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kube-bind/kube-bind/pkg/indexers"
 )
 
 type reconciler struct {
 	consumerSecretRefKey, providerNamespace string
 
+	getProviderID     func() (string, error)
 	getServiceExport  func(ns string) (*kubebindv1alpha1.APIServiceExport, error)
 	getServiceBinding func(name string) (*kubebindv1alpha1.APIServiceBinding, error)
 
 	getServiceExportResource          func(name string) (*kubebindv1alpha1.APIServiceExportResource, error)
 	updateServiceExportResourceStatus func(ctx context.Context, resource *kubebindv1alpha1.APIServiceExportResource) (*kubebindv1alpha1.APIServiceExportResource, error)
 
-	getCRD    func(name string) (*apiextensionsv1.CustomResourceDefinition, error)
-	updateCRD func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error)
-	createCRD func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error)
+	updateServiceBinding func(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding) (*kubebindv1alpha1.APIServiceBinding, error)
+	requeue              func(binding *kubebindv1alpha1.APIServiceBinding, after time.Duration)
+
+	getCRD                 func(name string) (*apiextensionsv1.CustomResourceDefinition, error)
+	listServiceBindingCRDs func(name string) ([]*apiextensionsv1.CustomResourceDefinition, error)
+	updateCRD              func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error)
+	createCRD              func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error)
+	deleteCRD              func(ctx context.Context, name string) error
+
+	listConsumerObjects func(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error)
+	// listConsumerObjectMetadata lists the metadata of consumer objects page by page. It
+	// is only used while migrating, hence there is no informer to keep running.
+	listConsumerObjectMetadata func(ctx context.Context, gvr schema.GroupVersionResource) ([]metav1.PartialObjectMetadata, error)
+	updateConsumerObject       func(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
+	getClusterRole    func(ctx context.Context, name string) (*rbacv1.ClusterRole, error)
+	createClusterRole func(ctx context.Context, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error)
+	updateClusterRole func(ctx context.Context, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error)
 }
 
 func (r *reconciler) reconcile(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding) error {
+	if indexers.ByServiceBindingKubeconfigSecretKey(binding) == r.consumerSecretRefKey {
+		if binding.DeletionTimestamp != nil && !binding.DeletionTimestamp.IsZero() {
+			return r.reconcileDeletion(ctx, binding)
+		}
+		if !hasFinalizer(binding) {
+			klog.FromContext(ctx).V(2).Info("adding finalizer to APIServiceBinding")
+			binding = binding.DeepCopy()
+			binding.Finalizers = append(binding.Finalizers, kubebindv1alpha1.ServiceBindingFinalizer)
+			_, err := r.updateServiceBinding(ctx, binding)
+			return err // we get an event for the update
+		}
+	}
+
 	var errs []error
 
 	if err := r.ensureValidServiceExport(ctx, binding); err != nil {
@@ -59,6 +94,12 @@ func (r *reconciler) reconcile(ctx context.Context, binding *kubebindv1alpha1.AP
 		errs = append(errs, err)
 	}
 
+	if indexers.ByServiceBindingKubeconfigSecretKey(binding) == r.consumerSecretRefKey {
+		if err := r.ensureMigration(ctx, binding); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	conditions.SetSummary(binding)
 
 	return utilerrors.NewAggregate(errs)
@@ -223,7 +264,7 @@ nextResource:
 				schemaInSync = false
 				continue nextResource
 			}
-			if !foundThis && !foundOther {
+			if !foundThis && !foundOther && existing.Annotations[kubebindv1alpha1.RetainedFromAnnotationKey] == "" {
 				// this is not our CRD, we should not touch it
 				conditions.MarkFalse(
 					binding,
@@ -238,11 +279,12 @@ nextResource:
 			}
 
 			// add ourselves as owner if we are not there
-			crd.ObjectMeta = existing.ObjectMeta
+			crd.ObjectMeta = *existing.ObjectMeta.DeepCopy()
 			if !foundThis {
 				newOwners = append(newOwners, newReference)
 			}
 			crd.ObjectMeta.OwnerReferences = newOwners
+			delete(crd.ObjectMeta.Annotations, kubebindv1alpha1.RetainedFromAnnotationKey)
 			result, err = r.updateCRD(ctx, crd)
 			if err != nil {
 				errs = append(errs, err)
@@ -261,6 +303,12 @@ nextResource:
 			}
 		}
 
+		if result != nil {
+			if err := r.ensureClusterRoles(ctx, result, newReference); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
 		// copy the CRD status onto the APIServiceExportResource
 		if result != nil {
 			orig := resource
@@ -306,3 +354,385 @@ nextResource:
 
 	return utilerrors.NewAggregate(errs)
 }
+
+// ensureClusterRoles creates or updates view, edit and admin ClusterRoles for the
+// resource of the given CRD. They are aggregated into the standard roles of the same
+// name, and owned by the binding to be garbage collected on unbind.
+func (r *reconciler) ensureClusterRoles(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, owner metav1.OwnerReference) error {
+	logger := klog.FromContext(ctx)
+
+	var errs []error
+	for _, role := range clusterRolesForCRD(crd, owner) {
+		existing, err := r.getClusterRole(ctx, role.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		} else if errors.IsNotFound(err) {
+			logger.V(1).Info("creating ClusterRole", "name", role.Name)
+			if _, err := r.createClusterRole(ctx, role); err != nil && !errors.IsAlreadyExists(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		labelsMatch := true
+		for k, v := range role.Labels {
+			if existing.Labels[k] != v {
+				labelsMatch = false
+			}
+		}
+		if labelsMatch &&
+			equality.Semantic.DeepEqual(existing.Rules, role.Rules) &&
+			equality.Semantic.DeepEqual(existing.OwnerReferences, role.OwnerReferences) {
+			continue
+		}
+
+		updated := existing.DeepCopy()
+		updated.Rules = role.Rules
+		updated.OwnerReferences = role.OwnerReferences
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		for k, v := range role.Labels {
+			updated.Labels[k] = v
+		}
+		logger.V(1).Info("updating ClusterRole", "name", role.Name)
+		if _, err := r.updateClusterRole(ctx, updated); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+const aggregateToLabelPrefix = "rbac.authorization.k8s.io/aggregate-to-"
+
+// clusterRolesForCRD returns the view, edit and admin ClusterRoles of the resource of crd.
+func clusterRolesForCRD(crd *apiextensionsv1.CustomResourceDefinition, owner metav1.OwnerReference) []*rbacv1.ClusterRole {
+	hasScale := false
+	for _, v := range crd.Spec.Versions {
+		if v.Served && v.Subresources != nil && v.Subresources.Scale != nil {
+			hasScale = true
+		}
+	}
+
+	readVerbs := []string{"get", "list", "watch"}
+	writeVerbs := []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+	plural := crd.Spec.Names.Plural
+
+	read := []rbacv1.PolicyRule{{
+		APIGroups: []string{crd.Spec.Group},
+		Resources: []string{plural, plural + "/status"},
+		Verbs:     readVerbs,
+	}}
+	write := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{crd.Spec.Group},
+			Resources: []string{plural},
+			Verbs:     writeVerbs,
+		},
+		{
+			APIGroups: []string{crd.Spec.Group},
+			Resources: []string{plural + "/status"},
+			Verbs:     readVerbs,
+		},
+	}
+	if hasScale {
+		read[0].Resources = append(read[0].Resources, plural+"/scale")
+		write = append(write, rbacv1.PolicyRule{
+			APIGroups: []string{crd.Spec.Group},
+			Resources: []string{plural + "/scale"},
+			Verbs:     []string{"get", "update", "patch"},
+		})
+	}
+
+	roles := make([]*rbacv1.ClusterRole, 0, 3)
+	for _, r := range []struct {
+		aggregateTo string
+		rules       []rbacv1.PolicyRule
+	}{
+		{"view", read},
+		{"edit", write},
+		{"admin", write},
+	} {
+		roles = append(roles, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "kube-bind:" + crd.Name + ":" + r.aggregateTo,
+				Labels: map[string]string{
+					aggregateToLabelPrefix + r.aggregateTo: "true",
+					ClusterRoleLabelKey:                    "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Rules: r.rules,
+		})
+	}
+	return roles
+}
+
+// reconcileDeletion handles the CustomResourceDefinitions of a deleting binding according
+// to its CRD retention policy, and removes the finalizer when done.
+func (r *reconciler) reconcileDeletion(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding) error {
+	logger := klog.FromContext(ctx)
+
+	if !hasFinalizer(binding) {
+		return nil
+	}
+
+	crds, err := r.listServiceBindingCRDs(binding.Name)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	pending := false
+	for _, crd := range crds {
+		logger := logger.WithValues("crd", crd.Name)
+		ctx := klog.NewContext(ctx, logger)
+
+		done, err := r.releaseCRD(ctx, binding, crd)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !done {
+			pending = true
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	if pending {
+		r.requeue(binding, 2*time.Second)
+		return nil
+	}
+
+	logger.Info("Removing finalizer from APIServiceBinding", "crdRetention", binding.Spec.CRDRetention)
+	binding = binding.DeepCopy()
+	var finalizers []string
+	for _, f := range binding.Finalizers {
+		if f != kubebindv1alpha1.ServiceBindingFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	binding.Finalizers = finalizers
+	_, err = r.updateServiceBinding(ctx, binding)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// releaseCRD handles one CustomResourceDefinition of a deleting binding. It returns true
+// when the CustomResourceDefinition does not need the binding anymore.
+func (r *reconciler) releaseCRD(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding, crd *apiextensionsv1.CustomResourceDefinition) (bool, error) {
+	logger := klog.FromContext(ctx)
+
+	// other bindings of the same resource keep it alive, independent of the policy
+	var owners []metav1.OwnerReference
+	foundThis, foundOther := false, false
+	for _, ref := range crd.OwnerReferences {
+		parts := strings.SplitN(ref.APIVersion, "/", 2)
+		if parts[0] == kubebindv1alpha1.SchemeGroupVersion.Group && ref.Kind == "APIServiceBinding" {
+			if ref.Name == binding.Name {
+				foundThis = true
+				continue
+			}
+			if _, err := r.getServiceBinding(ref.Name); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			} else if err == nil {
+				foundOther = true
+			}
+		}
+		owners = append(owners, ref)
+	}
+
+	if foundOther || binding.Spec.CRDRetention != kubebindv1alpha1.CRDRetentionPolicyDelete {
+		if foundThis {
+			crd = crd.DeepCopy()
+			crd.OwnerReferences = owners
+			if !foundOther {
+				if crd.Annotations == nil {
+					crd.Annotations = map[string]string{}
+				}
+				crd.Annotations[kubebindv1alpha1.RetainedFromAnnotationKey] = binding.Name
+			}
+			logger.Info("Releasing CustomResourceDefinition", "retained", !foundOther)
+			if _, err := r.updateCRD(ctx, crd); err != nil {
+				return false, err
+			}
+		}
+		if foundOther {
+			return true, nil // syncing continues with the other binding
+		}
+
+		// the syncers stop as the binding does not own the CRD anymore. Release the objects.
+		return r.removeDownstreamFinalizers(ctx, crd)
+	}
+
+	// delete policy: the objects are deleted through the syncers, including their upstream objects
+	if crd.DeletionTimestamp == nil || crd.DeletionTimestamp.IsZero() {
+		logger.Info("Deleting CustomResourceDefinition")
+		if err := r.deleteCRD(ctx, crd.Name); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	logger.V(2).Info("waiting for CustomResourceDefinition to be deleted")
+	return false, nil
+}
+
+// removeDownstreamFinalizers removes the syncer finalizer from all objects of the
+// resource of crd. It returns true if there were none left.
+func (r *reconciler) removeDownstreamFinalizers(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (bool, error) {
+	logger := klog.FromContext(ctx)
+
+	gvr, ok := servedResource(crd)
+	if !ok {
+		return true, nil // nothing to list
+	}
+
+	objs, err := r.listConsumerObjects(ctx, gvr)
+	if err != nil {
+		return false, err
+	}
+
+	var errs []error
+	done := true
+	for i := range objs {
+		obj := &objs[i]
+		var finalizers []string
+		found := false
+		for _, f := range obj.GetFinalizers() {
+			if f == kubebindv1alpha1.DownstreamFinalizer {
+				found = true
+				continue
+			}
+			finalizers = append(finalizers, f)
+		}
+		if !found {
+			continue
+		}
+
+		done = false // check again to catch syncers that were still running
+		logger.V(2).Info("removing finalizer from retained object", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		obj.SetFinalizers(finalizers)
+		if _, err := r.updateConsumerObject(ctx, gvr, obj); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	return done, utilerrors.NewAggregate(errs)
+}
+
+func hasFinalizer(binding *kubebindv1alpha1.APIServiceBinding) bool {
+	for _, f := range binding.Finalizers {
+		if f == kubebindv1alpha1.ServiceBindingFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureMigration detects a change of the service provider, and reports the progress of
+// recreating the consumer objects in the new service provider in the Migrated condition.
+func (r *reconciler) ensureMigration(ctx context.Context, binding *kubebindv1alpha1.APIServiceBinding) error {
+	logger := klog.FromContext(ctx)
+
+	providerID, err := r.getProviderID()
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	} else if errors.IsNotFound(err) {
+		return nil // the ClusterBinding controller will set a condition
+	}
+
+	if binding.Status.Provider != providerID {
+		if binding.Status.Provider != "" {
+			logger.Info("APIServiceBinding has been pointed to another service provider, migrating", "previousProvider", binding.Status.Provider, "provider", providerID)
+			conditions.MarkFalse(
+				binding,
+				kubebindv1alpha1.APIServiceBindingConditionMigrated,
+				"Migrating",
+				conditionsapi.ConditionSeverityInfo,
+				"Migrating from service provider %s to %s.",
+				binding.Status.Provider, providerID,
+			)
+		}
+		binding.Status.Provider = providerID
+	}
+
+	if !conditions.IsFalse(binding, kubebindv1alpha1.APIServiceBindingConditionMigrated) {
+		return nil // never migrated, or done
+	}
+
+	crds, err := r.listServiceBindingCRDs(binding.Name)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, crd := range crds {
+		n, err := r.countUnmigratedObjects(ctx, crd, providerID)
+		if err != nil {
+			return err
+		}
+		pending += n
+	}
+
+	if pending > 0 {
+		conditions.MarkFalse(
+			binding,
+			kubebindv1alpha1.APIServiceBindingConditionMigrated,
+			"Migrating",
+			conditionsapi.ConditionSeverityInfo,
+			"%d objects are pending to be recreated at the new service provider.",
+			pending,
+		)
+		r.requeue(binding, 5*time.Second)
+		return nil
+	}
+
+	logger.Info("APIServiceBinding migration finished")
+	conditions.MarkTrue(
+		binding,
+		kubebindv1alpha1.APIServiceBindingConditionMigrated,
+	)
+
+	return nil
+}
+
+// countUnmigratedObjects returns the number of consumer objects of the resource of crd
+// that have not been synced to providerID yet. Objects without provider annotation have
+// been synced before it was recorded, or not at all, and are pending as well.
+func (r *reconciler) countUnmigratedObjects(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, providerID string) (int, error) {
+	gvr, ok := servedResource(crd)
+	if !ok {
+		return 0, nil
+	}
+	objs, err := r.listConsumerObjectMetadata(ctx, gvr)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, obj := range objs {
+		if obj.GetLabels()[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginProvider {
+			continue // owned by the old service provider
+		}
+		if obj.GetDeletionTimestamp() != nil && !obj.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if obj.GetAnnotations()[kubebindv1alpha1.ProviderAnnotationKey] != providerID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// servedResource returns the resource of crd in its first served version.
+func servedResource(crd *apiextensionsv1.CustomResourceDefinition) (schema.GroupVersionResource, bool) {
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			return schema.GroupVersionResource{Group: crd.Spec.Group, Version: v.Name, Resource: crd.Spec.Names.Plural}, true
+		}
+	}
+	return schema.GroupVersionResource{}, false
+}
@@ -0,0 +1,596 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebinding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+)
+
+func newTestBinding(name, secret string, retention kubebindv1alpha1.CRDRetentionPolicy) *kubebindv1alpha1.APIServiceBinding {
+	return &kubebindv1alpha1.APIServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name)},
+		Spec: kubebindv1alpha1.APIServiceBindingSpec{
+			Export:       "mangodbs.mangodb.com",
+			CRDRetention: retention,
+			KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+				LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: secret, Key: "kubeconfig"},
+				Namespace:         "kube-bind",
+			},
+		},
+	}
+}
+
+func bindingReference(name string) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+		Kind:       "APIServiceBinding",
+		Name:       name,
+		UID:        types.UID("uid-" + name),
+		Controller: pointer.Bool(true),
+	}
+}
+
+func newTestCRD(annotations map[string]string, owners ...metav1.OwnerReference) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "mangodbs.mangodb.com",
+			Annotations:     annotations,
+			OwnerReferences: owners,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "mangodb.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "mangodbs", Singular: "mangodb", Kind: "MangoDB", ListKind: "MangoDBList"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true, Storage: true},
+			},
+		},
+	}
+}
+
+func newTestConsumerObject(name string, finalizers ...string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("mangodb.com/v1alpha1")
+	obj.SetKind("MangoDB")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetFinalizers(finalizers)
+	return obj
+}
+
+func TestReleaseCRD(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention kubebindv1alpha1.CRDRetentionPolicy
+		crd       *apiextensionsv1.CustomResourceDefinition
+		bindings  []string
+		objects   []unstructured.Unstructured
+
+		wantDone        bool
+		wantUpdated     *apiextensionsv1.CustomResourceDefinition
+		wantDeleted     bool
+		wantObjectNames []string
+	}{
+		{
+			name:      "retain releases the CRD and the objects",
+			retention: kubebindv1alpha1.CRDRetentionPolicyRetain,
+			crd:       newTestCRD(nil, bindingReference("binding")),
+			objects: []unstructured.Unstructured{
+				newTestConsumerObject("a", kubebindv1alpha1.DownstreamFinalizer, "other"),
+				newTestConsumerObject("b"),
+			},
+			wantDone:        false,
+			wantUpdated:     newTestCRD(map[string]string{kubebindv1alpha1.RetainedFromAnnotationKey: "binding"}),
+			wantObjectNames: []string{"a"},
+		},
+		{
+			name:      "retain is done without finalizers left",
+			retention: kubebindv1alpha1.CRDRetentionPolicyRetain,
+			crd:       newTestCRD(map[string]string{kubebindv1alpha1.RetainedFromAnnotationKey: "binding"}),
+			objects:   []unstructured.Unstructured{newTestConsumerObject("b")},
+			wantDone:  true,
+		},
+		{
+			name:        "empty policy defaults to retain",
+			crd:         newTestCRD(nil, bindingReference("binding")),
+			wantDone:    true,
+			wantUpdated: newTestCRD(map[string]string{kubebindv1alpha1.RetainedFromAnnotationKey: "binding"}),
+		},
+		{
+			name:        "delete deletes the CRD",
+			retention:   kubebindv1alpha1.CRDRetentionPolicyDelete,
+			crd:         newTestCRD(nil, bindingReference("binding")),
+			wantDone:    false,
+			wantDeleted: true,
+		},
+		{
+			name:      "delete waits for a deleting CRD",
+			retention: kubebindv1alpha1.CRDRetentionPolicyDelete,
+			crd: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := newTestCRD(nil, bindingReference("binding"))
+				now := metav1.Now()
+				crd.DeletionTimestamp = &now
+				return crd
+			}(),
+			wantDone: false,
+		},
+		{
+			name:        "other binding keeps the CRD on delete",
+			retention:   kubebindv1alpha1.CRDRetentionPolicyDelete,
+			crd:         newTestCRD(nil, bindingReference("binding"), bindingReference("other")),
+			bindings:    []string{"other"},
+			wantDone:    true,
+			wantUpdated: newTestCRD(nil, bindingReference("other")),
+		},
+		{
+			name:        "reference of a deleted binding is ignored",
+			retention:   kubebindv1alpha1.CRDRetentionPolicyDelete,
+			crd:         newTestCRD(nil, bindingReference("binding"), bindingReference("gone")),
+			wantDone:    false,
+			wantDeleted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *apiextensionsv1.CustomResourceDefinition
+			var deleted bool
+			var objectNames []string
+			r := &reconciler{
+				getServiceBinding: func(name string) (*kubebindv1alpha1.APIServiceBinding, error) {
+					for _, b := range tt.bindings {
+						if b == name {
+							return newTestBinding(name, "other", ""), nil
+						}
+					}
+					return nil, errors.NewNotFound(kubebindv1alpha1.SchemeGroupVersion.WithResource("apiservicebindings").GroupResource(), name)
+				},
+				updateCRD: func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
+					updated = crd
+					return crd, nil
+				},
+				deleteCRD: func(ctx context.Context, name string) error {
+					deleted = true
+					return nil
+				},
+				listConsumerObjects: func(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+					require.Equal(t, schema.GroupVersionResource{Group: "mangodb.com", Version: "v1alpha1", Resource: "mangodbs"}, gvr)
+					return tt.objects, nil
+				},
+				updateConsumerObject: func(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+					require.NotContains(t, obj.GetFinalizers(), kubebindv1alpha1.DownstreamFinalizer)
+					objectNames = append(objectNames, obj.GetName())
+					return obj, nil
+				},
+			}
+
+			done, err := r.releaseCRD(context.Background(), newTestBinding("binding", "secret", tt.retention), tt.crd)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDone, done)
+			require.Equal(t, tt.wantUpdated, updated)
+			require.Equal(t, tt.wantDeleted, deleted)
+			require.Equal(t, tt.wantObjectNames, objectNames)
+		})
+	}
+}
+
+func TestEnsureCRDsAdoption(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *apiextensionsv1.CustomResourceDefinition
+		bindings []*kubebindv1alpha1.APIServiceBinding
+
+		wantUpdated bool
+		wantInSync  bool
+		wantReason  string
+	}{
+		{
+			name:        "adopts a retained CRD",
+			existing:    newTestCRD(map[string]string{kubebindv1alpha1.RetainedFromAnnotationKey: "old"}),
+			wantUpdated: true,
+			wantInSync:  true,
+		},
+		{
+			name:        "keeps its own CRD",
+			existing:    newTestCRD(nil, bindingReference("binding")),
+			wantUpdated: true,
+			wantInSync:  true,
+		},
+		{
+			name:       "refuses a CRD not created by kube-bind",
+			existing:   newTestCRD(nil),
+			wantReason: "ForeignCustomResourceDefinition",
+		},
+		{
+			name:       "refuses a CRD of another service provider",
+			existing:   newTestCRD(nil, bindingReference("other")),
+			bindings:   []*kubebindv1alpha1.APIServiceBinding{newTestBinding("other", "other", "")},
+			wantReason: "ForeignCustomResourceDefinition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *apiextensionsv1.CustomResourceDefinition
+			r := newTestReconciler(tt.existing, tt.bindings)
+			r.updateCRD = func(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
+				updated = crd
+				return crd, nil
+			}
+
+			binding := newTestBinding("binding", "secret", "")
+			require.NoError(t, r.ensureCRDs(context.Background(), binding))
+
+			if tt.wantUpdated {
+				require.NotNil(t, updated)
+				require.Equal(t, []metav1.OwnerReference{bindingReference("binding")}, updated.OwnerReferences)
+				require.NotContains(t, updated.Annotations, kubebindv1alpha1.RetainedFromAnnotationKey)
+			} else {
+				require.Nil(t, updated)
+			}
+			require.Equal(t, tt.wantInSync, conditions.IsTrue(binding, kubebindv1alpha1.APIServiceBindingConditionSchemaInSync))
+			if tt.wantReason != "" {
+				require.Equal(t, tt.wantReason, conditions.GetReason(binding, kubebindv1alpha1.APIServiceBindingConditionSchemaInSync))
+			}
+		})
+	}
+}
+
+func TestEnsureCRDsSameResourceTwice(t *testing.T) {
+	first := newTestBinding("mangodbs.mangodb.com", "secret", "")
+	second := newTestBinding("mangodbs.mangodb.com-other", "other", "")
+
+	var crd *apiextensionsv1.CustomResourceDefinition
+	updates := 0
+	r := newTestReconciler(newTestCRD(nil), []*kubebindv1alpha1.APIServiceBinding{first, second})
+	r.getCRD = func(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+		if crd == nil {
+			return nil, errors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
+		}
+		return crd.DeepCopy(), nil
+	}
+	r.createCRD = func(ctx context.Context, obj *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
+		crd = obj
+		return obj, nil
+	}
+	r.updateCRD = func(ctx context.Context, obj *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
+		updates++
+		crd = obj
+		return obj, nil
+	}
+
+	require.NoError(t, r.ensureCRDs(context.Background(), first))
+	require.True(t, conditions.IsTrue(first, kubebindv1alpha1.APIServiceBindingConditionSchemaInSync))
+	require.NotNil(t, crd)
+	require.Equal(t, "mangodbs.mangodb.com", crd.Name)
+
+	// the name of the second binding is different, but the CRD is the same.
+	require.NoError(t, r.ensureCRDs(context.Background(), second))
+	require.Equal(t, "ForeignCustomResourceDefinition", conditions.GetReason(second, kubebindv1alpha1.APIServiceBindingConditionSchemaInSync))
+	require.Zero(t, updates)
+	require.Equal(t, []metav1.OwnerReference{bindingReference(first.Name)}, crd.OwnerReferences)
+}
+
+// newTestReconciler returns a reconciler for the export of newTestBinding, with the
+// given existing CRD and bindings.
+func newTestReconciler(existing *apiextensionsv1.CustomResourceDefinition, bindings []*kubebindv1alpha1.APIServiceBinding) *reconciler {
+	return &reconciler{
+		getServiceExport: func(name string) (*kubebindv1alpha1.APIServiceExport, error) {
+			return &kubebindv1alpha1.APIServiceExport{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: kubebindv1alpha1.APIServiceExportSpec{
+					Resources: []kubebindv1alpha1.APIServiceExportGroupResource{{GroupResource: kubebindv1alpha1.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}}},
+				},
+			}, nil
+		},
+		getServiceExportResource: func(name string) (*kubebindv1alpha1.APIServiceExportResource, error) {
+			return &kubebindv1alpha1.APIServiceExportResource{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: kubebindv1alpha1.APIServiceExportResourceSpec{
+					Group: "mangodb.com",
+					Names: existing.Spec.Names,
+					Scope: apiextensionsv1.NamespaceScoped,
+					Versions: []kubebindv1alpha1.APIServiceExportResourceVersion{
+						{Name: "v1alpha1", Served: true, Storage: true},
+					},
+				},
+			}, nil
+		},
+		updateServiceExportResourceStatus: func(ctx context.Context, resource *kubebindv1alpha1.APIServiceExportResource) (*kubebindv1alpha1.APIServiceExportResource, error) {
+			return resource, nil
+		},
+		getServiceBinding: func(name string) (*kubebindv1alpha1.APIServiceBinding, error) {
+			for _, b := range bindings {
+				if b.Name == name {
+					return b, nil
+				}
+			}
+			return nil, errors.NewNotFound(kubebindv1alpha1.SchemeGroupVersion.WithResource("apiservicebindings").GroupResource(), name)
+		},
+		getCRD: func(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+			return existing, nil
+		},
+		getClusterRole: func(ctx context.Context, name string) (*rbacv1.ClusterRole, error) {
+			return nil, errors.NewNotFound(rbacv1.Resource("clusterroles"), name)
+		},
+		createClusterRole: func(ctx context.Context, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+			return role, nil
+		},
+	}
+}
+
+func newTestObjectMetadata(name string, labels, annotations map[string]string) metav1.PartialObjectMetadata {
+	return metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        name,
+		Labels:      labels,
+		Annotations: annotations,
+	}}
+}
+
+func TestCountUnmigratedObjects(t *testing.T) {
+	now := metav1.Now()
+	deleting := newTestObjectMetadata("deleting", nil, nil)
+	deleting.DeletionTimestamp = &now
+	notServed := newTestCRD(nil)
+	notServed.Spec.Versions[0].Served = false
+
+	tests := []struct {
+		name    string
+		crd     *apiextensionsv1.CustomResourceDefinition
+		objs    []metav1.PartialObjectMetadata
+		listErr error
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "no objects",
+			crd:  newTestCRD(nil),
+		},
+		{
+			name: "counts objects of other or no provider",
+			crd:  newTestCRD(nil),
+			objs: []metav1.PartialObjectMetadata{
+				newTestObjectMetadata("migrated", nil, map[string]string{kubebindv1alpha1.ProviderAnnotationKey: "new"}),
+				newTestObjectMetadata("old", nil, map[string]string{kubebindv1alpha1.ProviderAnnotationKey: "old"}),
+				newTestObjectMetadata("unannotated", nil, nil),
+			},
+			want: 2,
+		},
+		{
+			name: "skips objects of the old service provider and deleting objects",
+			crd:  newTestCRD(nil),
+			objs: []metav1.PartialObjectMetadata{
+				newTestObjectMetadata("provider", map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginProvider}, map[string]string{kubebindv1alpha1.ProviderAnnotationKey: "old"}),
+				deleting,
+			},
+		},
+		{
+			name: "no served version",
+			crd:  notServed,
+			objs: []metav1.PartialObjectMetadata{newTestObjectMetadata("old", nil, nil)},
+		},
+		{
+			name:    "list error",
+			crd:     newTestCRD(nil),
+			listErr: errors.NewServiceUnavailable("etcd is down"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listed []schema.GroupVersionResource
+			r := &reconciler{
+				listConsumerObjectMetadata: func(ctx context.Context, gvr schema.GroupVersionResource) ([]metav1.PartialObjectMetadata, error) {
+					listed = append(listed, gvr)
+					return tt.objs, tt.listErr
+				},
+			}
+
+			n, err := r.countUnmigratedObjects(context.Background(), tt.crd, "new")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, n)
+			if tt.crd.Spec.Versions[0].Served {
+				require.Equal(t, []schema.GroupVersionResource{{Group: "mangodb.com", Version: "v1alpha1", Resource: "mangodbs"}}, listed)
+			} else {
+				require.Empty(t, listed)
+			}
+		})
+	}
+}
+
+func TestEnsureMigration(t *testing.T) {
+	migrating := func(binding *kubebindv1alpha1.APIServiceBinding) *kubebindv1alpha1.APIServiceBinding {
+		conditions.MarkFalse(binding, kubebindv1alpha1.APIServiceBindingConditionMigrated, "Migrating", conditionsapi.ConditionSeverityInfo, "Migrating.")
+		return binding
+	}
+	withProvider := func(binding *kubebindv1alpha1.APIServiceBinding, provider string) *kubebindv1alpha1.APIServiceBinding {
+		binding.Status.Provider = provider
+		return binding
+	}
+	unmigrated := []metav1.PartialObjectMetadata{newTestObjectMetadata("old", nil, map[string]string{kubebindv1alpha1.ProviderAnnotationKey: "old"})}
+	migrated := []metav1.PartialObjectMetadata{newTestObjectMetadata("new", nil, map[string]string{kubebindv1alpha1.ProviderAnnotationKey: "new"})}
+
+	tests := []struct {
+		name       string
+		binding    *kubebindv1alpha1.APIServiceBinding
+		providerID string
+		objs       []metav1.PartialObjectMetadata
+
+		wantProvider string
+		wantMigrated *bool
+		wantMessage  string
+		wantRequeue  bool
+	}{
+		{
+			name:         "records the first service provider",
+			binding:      newTestBinding("binding", "secret", ""),
+			providerID:   "new",
+			objs:         unmigrated,
+			wantProvider: "new",
+		},
+		{
+			name:       "unknown service provider",
+			binding:    withProvider(newTestBinding("binding", "secret", ""), "old"),
+			providerID: "",
+			// the ClusterBinding does not exist yet
+			wantProvider: "old",
+		},
+		{
+			name:         "starts migrating to another service provider",
+			binding:      withProvider(newTestBinding("binding", "secret", ""), "old"),
+			providerID:   "new",
+			objs:         unmigrated,
+			wantProvider: "new",
+			wantMigrated: pointer.Bool(false),
+			wantMessage:  "1 objects are pending to be recreated at the new service provider.",
+			wantRequeue:  true,
+		},
+		{
+			name:         "finishes migrating",
+			binding:      migrating(withProvider(newTestBinding("binding", "secret", ""), "new")),
+			providerID:   "new",
+			objs:         migrated,
+			wantProvider: "new",
+			wantMigrated: pointer.Bool(true),
+		},
+		{
+			name:         "migrated before",
+			binding:      withProvider(newTestBinding("binding", "secret", ""), "new"),
+			providerID:   "new",
+			objs:         unmigrated,
+			wantProvider: "new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requeued := false
+			r := &reconciler{
+				getProviderID: func() (string, error) {
+					if tt.providerID == "" {
+						return "", errors.NewNotFound(kubebindv1alpha1.SchemeGroupVersion.WithResource("clusterbindings").GroupResource(), "cluster")
+					}
+					return tt.providerID, nil
+				},
+				listServiceBindingCRDs: func(name string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+					return []*apiextensionsv1.CustomResourceDefinition{newTestCRD(nil, bindingReference(name))}, nil
+				},
+				listConsumerObjectMetadata: func(ctx context.Context, gvr schema.GroupVersionResource) ([]metav1.PartialObjectMetadata, error) {
+					return tt.objs, nil
+				},
+				requeue: func(binding *kubebindv1alpha1.APIServiceBinding, after time.Duration) {
+					requeued = true
+				},
+			}
+
+			require.NoError(t, r.ensureMigration(context.Background(), tt.binding))
+			require.Equal(t, tt.wantProvider, tt.binding.Status.Provider)
+			require.Equal(t, tt.wantRequeue, requeued)
+
+			cond := conditions.Get(tt.binding, kubebindv1alpha1.APIServiceBindingConditionMigrated)
+			if tt.wantMigrated == nil {
+				require.Nil(t, cond)
+				return
+			}
+			require.NotNil(t, cond)
+			require.Equal(t, *tt.wantMigrated, cond.Status == corev1.ConditionTrue)
+			if tt.wantMessage != "" {
+				require.Equal(t, tt.wantMessage, cond.Message)
+			}
+		})
+	}
+}
+
+func TestClusterRolesForCRD(t *testing.T) {
+	scaled := newTestCRD(nil)
+	scaled.Spec.Versions[0].Subresources = &apiextensionsv1.CustomResourceSubresources{
+		Scale: &apiextensionsv1.CustomResourceSubresourceScale{SpecReplicasPath: ".spec.replicas", StatusReplicasPath: ".status.replicas"},
+	}
+
+	read := rbacv1.PolicyRule{APIGroups: []string{"mangodb.com"}, Resources: []string{"mangodbs", "mangodbs/status"}, Verbs: []string{"get", "list", "watch"}}
+	write := []rbacv1.PolicyRule{
+		{APIGroups: []string{"mangodb.com"}, Resources: []string{"mangodbs"}, Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}},
+		{APIGroups: []string{"mangodb.com"}, Resources: []string{"mangodbs/status"}, Verbs: []string{"get", "list", "watch"}},
+	}
+	scaledRead := read
+	scaledRead.Resources = []string{"mangodbs", "mangodbs/status", "mangodbs/scale"}
+	scaledWrite := append(append([]rbacv1.PolicyRule{}, write...), rbacv1.PolicyRule{APIGroups: []string{"mangodb.com"}, Resources: []string{"mangodbs/scale"}, Verbs: []string{"get", "update", "patch"}})
+
+	tests := []struct {
+		name      string
+		crd       *apiextensionsv1.CustomResourceDefinition
+		wantRules map[string][]rbacv1.PolicyRule
+	}{
+		{
+			name: "without scale subresource",
+			crd:  newTestCRD(nil),
+			wantRules: map[string][]rbacv1.PolicyRule{
+				"view":  {read},
+				"edit":  write,
+				"admin": write,
+			},
+		},
+		{
+			name: "with scale subresource",
+			crd:  scaled,
+			wantRules: map[string][]rbacv1.PolicyRule{
+				"view":  {scaledRead},
+				"edit":  scaledWrite,
+				"admin": scaledWrite,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := bindingReference("binding")
+			roles := map[string]*rbacv1.ClusterRole{}
+			for _, role := range clusterRolesForCRD(tt.crd, owner) {
+				roles[role.Name] = role
+			}
+			require.Len(t, roles, len(tt.wantRules))
+			for aggregateTo := range tt.wantRules {
+				role := roles["kube-bind:mangodbs.mangodb.com:"+aggregateTo]
+				require.NotNil(t, role, aggregateTo)
+				require.Equal(t, map[string]string{
+					"rbac.authorization.k8s.io/aggregate-to-" + aggregateTo: "true",
+					ClusterRoleLabelKey: "true",
+				}, role.Labels)
+				require.Equal(t, []metav1.OwnerReference{owner}, role.OwnerReferences)
+				require.Equal(t, tt.wantRules[aggregateTo], role.Rules, aggregateTo)
+			}
+		})
+	}
+}
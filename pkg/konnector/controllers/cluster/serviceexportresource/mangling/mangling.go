@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mangling
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic/dynamiclister"
+)
+
+// hashLength is the number of hex characters of the hash suffix of shortened names.
+const hashLength = 10
+
+// Name returns the name of the upstream object for a downstream object with the
+// given namespace and name, when all consumer namespaces are flattened into one
+// provider namespace. The name is "<namespace>.<name>". Namespaces cannot contain
+// dots, hence the namespace can always be recovered. If the result is too long,
+// the name is shortened and suffixed with a hash of namespace and name.
+func Name(namespace, name string) string {
+	mangled := namespace + "." + name
+	if len(mangled) <= validation.DNS1123SubdomainMaxLength {
+		return mangled
+	}
+
+	hash := sha256.Sum256([]byte(namespace + "/" + name))
+	prefix := strings.TrimRight(mangled[:validation.DNS1123SubdomainMaxLength-hashLength-1], ".-")
+	return prefix + "-" + hex.EncodeToString(hash[:])[:hashLength]
+}
+
+// Unmangle splits a mangled upstream name into the downstream namespace and name.
+// The name is only correct if it was not shortened by Name. Use Lookup to find the
+// downstream object in any case. It returns false if the name is not mangled.
+func Unmangle(upstreamName string) (namespace, name string, ok bool) {
+	namespace, name, ok = strings.Cut(upstreamName, ".")
+	if !ok || namespace == "" || name == "" {
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// Lookup returns the downstream object for the given mangled upstream name, or a
+// NotFound error if there is none.
+func Lookup(lister dynamiclister.Lister, gr schema.GroupResource, upstreamName string) (*unstructured.Unstructured, error) {
+	ns, name, ok := Unmangle(upstreamName)
+	if !ok {
+		return nil, errors.NewNotFound(gr, upstreamName)
+	}
+
+	obj, err := lister.Namespace(ns).Get(name)
+	if err == nil || !errors.IsNotFound(err) {
+		return obj, err
+	}
+
+	// the name might have been shortened. Find the object with the same mangled name.
+	objs, listErr := lister.Namespace(ns).List(labels.Everything())
+	if listErr != nil {
+		return nil, listErr
+	}
+	for _, obj := range objs {
+		if Name(ns, obj.GetName()) == upstreamName {
+			return obj, nil
+		}
+	}
+	return nil, err
+}
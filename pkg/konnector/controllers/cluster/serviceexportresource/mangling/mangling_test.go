@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mangling
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic/dynamiclister"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestName(t *testing.T) {
+	long := strings.Repeat("a", 250)
+
+	tests := []struct {
+		name      string
+		namespace string
+		objName   string
+		want      string
+		shortened bool
+	}{
+		{
+			name:      "short",
+			namespace: "default",
+			objName:   "foo",
+			want:      "default.foo",
+		},
+		{
+			name:      "exactly at the limit",
+			namespace: "default",
+			objName:   strings.Repeat("a", validation.DNS1123SubdomainMaxLength-len("default.")),
+			want:      "default." + strings.Repeat("a", validation.DNS1123SubdomainMaxLength-len("default.")),
+		},
+		{
+			name:      "too long",
+			namespace: "default",
+			objName:   long,
+			shortened: true,
+		},
+		{
+			name:      "too long with dash at the cut",
+			namespace: "default",
+			objName:   strings.Repeat("a", 233) + "-" + long,
+			shortened: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Name(tt.namespace, tt.objName)
+			require.LessOrEqual(t, len(got), validation.DNS1123SubdomainMaxLength)
+			require.Empty(t, validation.IsDNS1123Subdomain(got))
+			if !tt.shortened {
+				require.Equal(t, tt.want, got)
+				return
+			}
+			require.True(t, strings.HasPrefix(got, tt.namespace+"."), "namespace must be recoverable from %q", got)
+			require.Equal(t, got, Name(tt.namespace, tt.objName), "must be deterministic")
+		})
+	}
+}
+
+func TestNameHashDistinguishesShortenedNames(t *testing.T) {
+	long := strings.Repeat("a", 250)
+	require.NotEqual(t, Name("default", long+"1"), Name("default", long+"2"))
+	require.NotEqual(t, Name("default", long), Name("other", long))
+}
+
+func TestUnmangle(t *testing.T) {
+	tests := []struct {
+		name          string
+		upstreamName  string
+		wantNamespace string
+		wantName      string
+		wantOK        bool
+	}{
+		{name: "mangled", upstreamName: "default.foo", wantNamespace: "default", wantName: "foo", wantOK: true},
+		{name: "dots in name", upstreamName: "default.foo.bar", wantNamespace: "default", wantName: "foo.bar", wantOK: true},
+		{name: "not mangled", upstreamName: "foo"},
+		{name: "empty namespace", upstreamName: ".foo"},
+		{name: "empty name", upstreamName: "default."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns, name, ok := Unmangle(tt.upstreamName)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantNamespace, ns)
+			require.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "mangodb.com", Version: "v1alpha1", Resource: "mangodbs"}
+	long := strings.Repeat("a", 250)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, key := range [][2]string{{"default", "foo"}, {"default", long}, {"other", long}} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("mangodb.com/v1alpha1")
+		obj.SetKind("MangoDB")
+		obj.SetNamespace(key[0])
+		obj.SetName(key[1])
+		require.NoError(t, indexer.Add(obj))
+	}
+	lister := dynamiclister.New(indexer, gvr)
+
+	tests := []struct {
+		name          string
+		upstreamName  string
+		wantNamespace string
+		wantName      string
+		wantNotFound  bool
+	}{
+		{name: "short name", upstreamName: "default.foo", wantNamespace: "default", wantName: "foo"},
+		{name: "shortened name", upstreamName: Name("default", long), wantNamespace: "default", wantName: long},
+		{name: "shortened name in other namespace", upstreamName: Name("other", long), wantNamespace: "other", wantName: long},
+		{name: "unknown", upstreamName: "default.bar", wantNotFound: true},
+		{name: "unknown shortened name", upstreamName: Name("default", long+"b"), wantNotFound: true},
+		{name: "not mangled", upstreamName: "foo", wantNotFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := Lookup(lister, gvr.GroupResource(), tt.upstreamName)
+			if tt.wantNotFound {
+				require.True(t, errors.IsNotFound(err), "expected NotFound, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantNamespace, obj.GetNamespace())
+			require.Equal(t, tt.wantName, obj.GetName())
+		})
+	}
+}
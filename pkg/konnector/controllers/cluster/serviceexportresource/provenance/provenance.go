@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+// Set stamps the provenance labels and annotations of the given downstream object
+// onto the upstream object. It returns true if the upstream object was changed.
+func Set(upstream, downstream metav1.Object, clusterID string) bool {
+	changed := false
+
+	labels := upstream.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range map[string]string{
+		kubebindv1alpha1.ConsumerClusterLabelKey:   clusterID,
+		kubebindv1alpha1.ConsumerNamespaceLabelKey: downstream.GetNamespace(),
+		kubebindv1alpha1.ConsumerUIDLabelKey:       string(downstream.GetUID()),
+	} {
+		if existing, found := labels[k]; v == "" && found {
+			delete(labels, k)
+			changed = true
+		} else if v != "" && existing != v {
+			labels[k] = v
+			changed = true
+		}
+	}
+	upstream.SetLabels(labels)
+
+	annotations := upstream.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[kubebindv1alpha1.ConsumerNameAnnotationKey] != downstream.GetName() {
+		annotations[kubebindv1alpha1.ConsumerNameAnnotationKey] = downstream.GetName()
+		changed = true
+	}
+	upstream.SetAnnotations(annotations)
+
+	return changed
+}
+
+// Consumer returns the namespace and name of the downstream object recorded in the
+// provenance of the given upstream object. It returns false if the upstream object
+// has no provenance or originates from another consumer cluster.
+func Consumer(upstream metav1.Object, clusterID string) (ns, name string, ok bool) {
+	if upstream.GetLabels()[kubebindv1alpha1.ConsumerClusterLabelKey] != clusterID {
+		return "", "", false
+	}
+	name = upstream.GetAnnotations()[kubebindv1alpha1.ConsumerNameAnnotationKey]
+	if name == "" {
+		return "", "", false
+	}
+	return upstream.GetLabels()[kubebindv1alpha1.ConsumerNamespaceLabelKey], name, true
+}
+
+// ProviderCreated returns true if the given upstream object has been created by the
+// service provider, i.e. it has neither been labeled as originating from a consumer
+// nor carries the provenance of any consumer object.
+func ProviderCreated(upstream metav1.Object) bool {
+	labels := upstream.GetLabels()
+	if labels[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginConsumer {
+		return false
+	}
+	for _, k := range []string{kubebindv1alpha1.ConsumerClusterLabelKey, kubebindv1alpha1.ConsumerUIDLabelKey} {
+		if _, found := labels[k]; found {
+			return false
+		}
+	}
+	_, found := upstream.GetAnnotations()[kubebindv1alpha1.ConsumerNameAnnotationKey]
+	return !found
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestSet(t *testing.T) {
+	downstream := &metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid"}
+	stamped := metav1.ObjectMeta{
+		Labels: map[string]string{
+			"app":                                    "foo",
+			kubebindv1alpha1.ConsumerClusterLabelKey: "cluster",
+			kubebindv1alpha1.ConsumerNamespaceLabelKey: "default",
+			kubebindv1alpha1.ConsumerUIDLabelKey:       "uid",
+		},
+		Annotations: map[string]string{
+			kubebindv1alpha1.ConsumerNameAnnotationKey: "foo",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		upstream    metav1.ObjectMeta
+		downstream  *metav1.ObjectMeta
+		wantChanged bool
+		want        metav1.ObjectMeta
+	}{
+		{
+			name:        "new",
+			upstream:    metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+			downstream:  downstream,
+			wantChanged: true,
+			want:        stamped,
+		},
+		{
+			name:       "unchanged",
+			upstream:   *stamped.DeepCopy(),
+			downstream: downstream,
+			want:       stamped,
+		},
+		{
+			name: "replaced downstream object",
+			upstream: func() metav1.ObjectMeta {
+				m := stamped.DeepCopy()
+				m.Labels[kubebindv1alpha1.ConsumerUIDLabelKey] = "old"
+				return *m
+			}(),
+			downstream:  downstream,
+			wantChanged: true,
+			want:        stamped,
+		},
+		{
+			name:       "cluster-scoped",
+			upstream:   *stamped.DeepCopy(),
+			downstream: &metav1.ObjectMeta{Name: "foo", UID: "uid"},
+			want: func() metav1.ObjectMeta {
+				m := stamped.DeepCopy()
+				delete(m.Labels, kubebindv1alpha1.ConsumerNamespaceLabelKey)
+				return *m
+			}(),
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := tt.upstream.DeepCopy()
+			require.Equal(t, tt.wantChanged, Set(upstream, tt.downstream, "cluster"))
+			require.Equal(t, tt.want.Labels, upstream.Labels)
+			require.Equal(t, tt.want.Annotations, upstream.Annotations)
+
+			ns, name, ok := Consumer(upstream, "cluster")
+			require.True(t, ok)
+			require.Equal(t, tt.downstream.Namespace, ns)
+			require.Equal(t, tt.downstream.Name, name)
+		})
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	tests := []struct {
+		name          string
+		upstream      metav1.ObjectMeta
+		wantNamespace string
+		wantName      string
+		wantOK        bool
+	}{
+		{
+			name: "own consumer",
+			upstream: metav1.ObjectMeta{
+				Labels:      map[string]string{kubebindv1alpha1.ConsumerClusterLabelKey: "cluster", kubebindv1alpha1.ConsumerNamespaceLabelKey: "default"},
+				Annotations: map[string]string{kubebindv1alpha1.ConsumerNameAnnotationKey: "foo"},
+			},
+			wantNamespace: "default",
+			wantName:      "foo",
+			wantOK:        true,
+		},
+		{
+			name: "other consumer",
+			upstream: metav1.ObjectMeta{
+				Labels:      map[string]string{kubebindv1alpha1.ConsumerClusterLabelKey: "other", kubebindv1alpha1.ConsumerNamespaceLabelKey: "default"},
+				Annotations: map[string]string{kubebindv1alpha1.ConsumerNameAnnotationKey: "foo"},
+			},
+		},
+		{
+			name: "no name",
+			upstream: metav1.ObjectMeta{
+				Labels: map[string]string{kubebindv1alpha1.ConsumerClusterLabelKey: "cluster"},
+			},
+		},
+		{
+			name: "no provenance",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns, name, ok := Consumer(&tt.upstream, "cluster")
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantNamespace, ns)
+			require.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestProviderCreated(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "no labels", want: true},
+		{name: "unrelated labels", labels: map[string]string{"app": "foo"}, want: true},
+		{name: "provider origin", labels: map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginProvider}, want: true},
+		{name: "consumer origin", labels: map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginConsumer}},
+		{name: "consumer cluster", labels: map[string]string{kubebindv1alpha1.ConsumerClusterLabelKey: "cluster"}},
+		{name: "consumer uid", labels: map[string]string{kubebindv1alpha1.ConsumerUIDLabelKey: "uid"}},
+		{name: "consumer name", annotations: map[string]string{kubebindv1alpha1.ConsumerNameAnnotationKey: "foo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ProviderCreated(&metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}))
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -39,6 +40,7 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/committer"
 	"github.com/kube-bind/kube-bind/pkg/indexers"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 const (
@@ -51,9 +53,13 @@ func NewController(
 	consumerSecretRefKey, providerNamespace string,
 	consumerConfig, providerConfig *rest.Config,
 	serviceExportResourceInformer bindinformers.APIServiceExportResourceInformer,
+	serviceExportInformer bindinformers.APIServiceExportInformer,
 	serviceNamespaceInformer bindinformers.APIServiceNamespaceInformer,
+	clusterBindingInformer bindinformers.ClusterBindingInformer,
+	namespaceInformer dynamic.Informer[corelisters.NamespaceLister],
 	serviceBindingInformer dynamic.Informer[bindlisters.APIServiceBindingLister],
 	crdInformer dynamic.Informer[apiextensionslisters.CustomResourceDefinitionLister],
+	validators webhook.Registry,
 ) (*controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -77,6 +83,9 @@ func NewController(
 		serviceExportResourceLister:  serviceExportResourceInformer.Lister(),
 		serviceExportResourceIndexer: serviceExportResourceInformer.Informer().GetIndexer(),
 
+		serviceExportLister:  serviceExportInformer.Lister(),
+		serviceExportIndexer: serviceExportInformer.Informer().GetIndexer(),
+
 		serviceNamespaceLister:  serviceNamespaceInformer.Lister(),
 		serviceNamespaceIndexer: serviceNamespaceInformer.Informer().GetIndexer(),
 
@@ -89,15 +98,41 @@ func NewController(
 			consumerConfig:           consumerConfig,
 			providerConfig:           providerConfig,
 			serviceNamespaceInformer: dynamicServiceNamespaceInformer,
+			validators:               validators,
 
 			syncContext: map[string]syncContext{},
 
+			getConsumerClusterID: func() (string, error) {
+				ns, err := namespaceInformer.Lister().Get("kube-system")
+				if err != nil {
+					return "", err
+				}
+				return string(ns.UID), nil
+			},
+			getProviderID: func() (string, error) {
+				cb, err := clusterBindingInformer.Lister().ClusterBindings(providerNamespace).Get("cluster")
+				if err != nil {
+					return "", err
+				}
+				return string(cb.UID), nil
+			},
 			getCRD: func(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
 				return crdInformer.Lister().Get(name)
 			},
 			getServiceBinding: func(name string) (*kubebindv1alpha1.APIServiceBinding, error) {
 				return serviceBindingInformer.Lister().Get(name)
 			},
+			listServiceExports: func(resource string) ([]*kubebindv1alpha1.APIServiceExport, error) {
+				objs, err := serviceExportInformer.Informer().GetIndexer().ByIndex(indexers.ServiceExportByServiceExportResource, providerNamespace+"/"+resource)
+				if err != nil {
+					return nil, err
+				}
+				exports := make([]*kubebindv1alpha1.APIServiceExport, 0, len(objs))
+				for _, obj := range objs {
+					exports = append(exports, obj.(*kubebindv1alpha1.APIServiceExport))
+				}
+				return exports, nil
+			},
 		},
 
 		commit: committer.NewCommitter[*kubebindv1alpha1.APIServiceExportResource, *kubebindv1alpha1.APIServiceExportResourceSpec, *kubebindv1alpha1.APIServiceExportResourceStatus](
@@ -107,6 +142,10 @@ func NewController(
 		),
 	}
 
+	indexers.AddIfNotPresentOrDie(serviceExportInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.ServiceExportByServiceExportResource: indexers.IndexServiceExportByServiceExportResource,
+	})
+
 	indexers.AddIfNotPresentOrDie(serviceNamespaceInformer.Informer().GetIndexer(), cache.Indexers{
 		indexers.ServiceNamespaceByNamespace: indexers.IndexServiceNamespaceByNamespace,
 	})
@@ -123,6 +162,18 @@ func NewController(
 		},
 	})
 
+	serviceExportInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueueServiceExport(logger, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			c.enqueueServiceExport(logger, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueueServiceExport(logger, obj)
+		},
+	})
+
 	return c, nil
 }
 
@@ -136,6 +187,9 @@ type controller struct {
 	serviceExportResourceLister  bindlisters.APIServiceExportResourceLister
 	serviceExportResourceIndexer cache.Indexer
 
+	serviceExportLister  bindlisters.APIServiceExportLister
+	serviceExportIndexer cache.Indexer
+
 	serviceNamespaceLister  bindlisters.APIServiceNamespaceLister
 	serviceNamespaceIndexer cache.Indexer
 
@@ -158,6 +212,23 @@ func (c *controller) enqueueServiceExportResource(logger klog.Logger, obj interf
 	c.queue.Add(key)
 }
 
+func (c *controller) enqueueServiceExport(logger klog.Logger, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	export, ok := obj.(*kubebindv1alpha1.APIServiceExport)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected type %T", obj))
+		return
+	}
+
+	for _, gr := range export.Spec.Resources {
+		key := c.providerNamespace + "/" + gr.Resource + "." + gr.Group
+		logger.V(2).Info("queueing APIServiceExportResource", "key", key, "reason", "APIServiceExport", "ServiceExportKey", export.Namespace+"/"+export.Name)
+		c.queue.Add(key)
+	}
+}
+
 func (c *controller) enqueueServiceBinding(logger klog.Logger, obj interface{}) {
 	binding, ok := obj.(*kubebindv1alpha1.APIServiceBinding)
 	if !ok {
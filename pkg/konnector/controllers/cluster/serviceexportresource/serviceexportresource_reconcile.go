@@ -39,6 +39,7 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/spec"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/status"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 type reconciler struct {
@@ -49,18 +50,42 @@ type reconciler struct {
 
 	consumerConfig, providerConfig *rest.Config
 
+	// validators is where the spec syncers register to validate downstream objects in
+	// admission. It is nil if the admission webhook is disabled.
+	validators webhook.Registry
+
 	lock        sync.Mutex
 	syncContext map[string]syncContext // by CRD name
 
-	getCRD            func(name string) (*apiextensionsv1.CustomResourceDefinition, error)
-	getServiceBinding func(name string) (*kubebindv1alpha1.APIServiceBinding, error)
+	getConsumerClusterID func() (string, error)
+	getProviderID        func() (string, error)
+	getCRD               func(name string) (*apiextensionsv1.CustomResourceDefinition, error)
+	getServiceBinding    func(name string) (*kubebindv1alpha1.APIServiceBinding, error)
+	listServiceExports   func(resource string) ([]*kubebindv1alpha1.APIServiceExport, error)
 }
 
 type syncContext struct {
 	generation int64
+	config     syncConfig
 	cancel     func()
 }
 
+// syncConfig is the configuration of the syncers derived from the APIServiceExports
+// and the consumer CRD.
+type syncConfig struct {
+	providerObjects        kubebindv1alpha1.ProviderObjectsPolicy
+	clusterScopedIsolation kubebindv1alpha1.Isolation
+	namespaceIsolation     kubebindv1alpha1.NamespaceIsolation
+	onImmutableChange      kubebindv1alpha1.ImmutableChangePolicy
+	upstreamDeletion       kubebindv1alpha1.UpstreamDeletionPolicy
+	providerDeletion       kubebindv1alpha1.ProviderDeletionPolicy
+
+	// statusSubresource is true if the consumer CRD has a status subresource, and
+	// syncConditions is true if in addition its schema keeps status.conditions.
+	statusSubresource bool
+	syncConditions    bool
+}
+
 func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubebindv1alpha1.APIServiceExportResource) error {
 	logger := klog.FromContext(ctx)
 
@@ -103,6 +128,7 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 
 	// any binding that references this CRD?
 	foundBinding := false
+	providerDeletion := kubebindv1alpha1.ProviderDeletionPolicyCondition
 	for _, ref := range crd.OwnerReferences {
 		parts := strings.SplitN(ref.APIVersion, "/", 2)
 		if parts[0] != kubebindv1alpha1.SchemeGroupVersion.Group || ref.Kind != "APIServiceBinding" {
@@ -117,6 +143,9 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 
 		if binding.Spec.KubeconfigSecretRef.Namespace+"/"+binding.Spec.KubeconfigSecretRef.Name == r.consumerSecretRefKey {
 			foundBinding = true
+			if binding.Spec.ProviderDeletion == kubebindv1alpha1.ProviderDeletionPolicyDelete {
+				providerDeletion = kubebindv1alpha1.ProviderDeletionPolicyDelete
+			}
 			break
 		}
 	}
@@ -142,10 +171,64 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 		return nil
 	}
 
+	// the consumer cluster ID is stamped onto upstream objects
+	clusterID, err := r.getConsumerClusterID()
+	if err != nil {
+		return err
+	}
+
+	// the provider ID is recorded on downstream objects to detect migrations
+	providerID, err := r.getProviderID()
+	if err != nil {
+		return err
+	}
+
+	// any export asking for provider-created objects, isolation or replacements?
+	exports, err := r.listServiceExports(resource.Name)
+	if err != nil {
+		return err
+	}
+	config := syncConfig{
+		providerObjects:        kubebindv1alpha1.ProviderObjectsPolicyNone,
+		clusterScopedIsolation: kubebindv1alpha1.IsolationNone,
+		namespaceIsolation:     kubebindv1alpha1.NamespaceIsolationServiceNamespace,
+		onImmutableChange:      kubebindv1alpha1.ImmutableChangePolicyReject,
+		upstreamDeletion:       kubebindv1alpha1.UpstreamDeletionPolicyRecreate,
+		providerDeletion:       providerDeletion,
+	}
+	for _, export := range exports {
+		if export.Spec.UpstreamDeletion == kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative {
+			config.upstreamDeletion = kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative
+		}
+		if export.Spec.ProviderObjects == kubebindv1alpha1.ProviderObjectsPolicySync {
+			config.providerObjects = kubebindv1alpha1.ProviderObjectsPolicySync
+		}
+		if export.Spec.ClusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced && resource.Spec.Scope == apiextensionsv1.ClusterScoped {
+			config.clusterScopedIsolation = kubebindv1alpha1.IsolationNamespaced
+		}
+		if export.Spec.NamespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace && resource.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			config.namespaceIsolation = kubebindv1alpha1.NamespaceIsolationClusterNamespace
+		}
+		for _, gr := range export.Spec.Resources {
+			if gr.Group == resource.Spec.Group && gr.Resource == resource.Spec.Names.Plural && gr.OnImmutableChange == kubebindv1alpha1.ImmutableChangePolicyReplace {
+				config.onImmutableChange = kubebindv1alpha1.ImmutableChangePolicyReplace
+			}
+		}
+	}
+
+	// the Synced condition can only be written if the consumer CRD does not drop it
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			config.statusSubresource = v.Subresources != nil && v.Subresources.Status != nil
+			config.syncConditions = config.statusSubresource && keepsStatusConditions(v.Schema)
+			break
+		}
+	}
+
 	r.lock.Lock()
 	c, found := r.syncContext[resource.Name]
 	if found {
-		if c.generation == resource.Generation {
+		if c.generation == resource.Generation && c.config == config {
 			r.lock.Unlock()
 			conditions.MarkTrue(resource, kubebindv1alpha1.APIServiceExportResourrceConditionSyncing)
 			return nil // all as expected
@@ -154,7 +237,7 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 		// technically, we could be less aggressive here if nothing big changed in the resource, e.g. just schemas. But ¯\_(ツ)_/¯
 
 		if c, found := r.syncContext[resource.Name]; found {
-			logger.V(1).Info("Stopping APIServiceExportResource sync", "reason", "GenerationChanged", "generation", resource.Generation)
+			logger.V(1).Info("Stopping APIServiceExportResource sync", "reason", "GenerationChanged", "generation", resource.Generation, "config", config)
 			c.cancel()
 			delete(r.syncContext, resource.Name)
 		}
@@ -164,9 +247,11 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 	// start a new syncer
 
 	var syncVersion string
+	var scale *apiextensionsv1.CustomResourceSubresourceScale
 	for _, v := range resource.Spec.Versions {
 		if v.Served {
 			syncVersion = v.Name
+			scale = v.Subresources.Scale
 			break
 		}
 	}
@@ -179,7 +264,18 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 
 	specCtrl, err := spec.NewController(
 		gvr,
-		r.providerNamespace,
+		spec.Options{
+			ProviderNamespace:      r.providerNamespace,
+			ProviderID:             providerID,
+			ConsumerClusterID:      clusterID,
+			Scale:                  scale,
+			SyncConditions:         config.syncConditions,
+			ClusterScopedIsolation: config.clusterScopedIsolation,
+			NamespaceIsolation:     config.namespaceIsolation,
+			OnImmutableChange:      config.onImmutableChange,
+			UpstreamDeletion:       config.upstreamDeletion,
+			ProviderDeletion:       config.providerDeletion,
+		},
 		r.consumerConfig,
 		r.providerConfig,
 		consumerInf.ForResource(gvr),
@@ -193,8 +289,13 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 	statusCtrl, err := status.NewController(
 		gvr,
 		r.providerNamespace,
+		clusterID,
 		r.consumerConfig,
 		r.providerConfig,
+		config.providerObjects,
+		config.statusSubresource,
+		config.clusterScopedIsolation,
+		config.namespaceIsolation,
 		consumerInf.ForResource(gvr),
 		providerInf.ForResource(gvr),
 		r.serviceNamespaceInformer,
@@ -218,12 +319,19 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 
 		go specCtrl.Start(ctx, 1)
 		go statusCtrl.Start(ctx, 1)
+
+		if r.validators != nil && consumerSynced[gvr] && providerSynced[gvr] {
+			unregister := r.validators.Register(gvr.GroupResource(), specCtrl.Validate)
+			<-ctx.Done()
+			unregister()
+		}
 	}()
 
 	r.lock.Lock()
 	defer r.lock.Unlock()
 	r.syncContext[resource.Name] = syncContext{
 		generation: resource.Generation,
+		config:     config,
 		cancel:     cancel,
 	}
 
@@ -231,3 +339,23 @@ func (r *reconciler) reconcile(ctx context.Context, name string, resource *kubeb
 
 	return utilerrors.NewAggregate(errs)
 }
+
+// keepsStatusConditions returns true if objects validated by the given schema keep
+// status.conditions, i.e. they are not pruned by the API server.
+func keepsStatusConditions(validation *apiextensionsv1.CustomResourceValidation) bool {
+	if validation == nil || validation.OpenAPIV3Schema == nil {
+		return false
+	}
+	schema := validation.OpenAPIV3Schema
+	for _, field := range []string{"status", "conditions"} {
+		if schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+			return true
+		}
+		next, found := schema.Properties[field]
+		if !found {
+			return false
+		}
+		schema = &next
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// observedDeletionTTL is how long an observed upstream deletion is kept. Afterwards,
+// the downstream object is either marked as deleted by the service provider, or the
+// deletion was caused by the konnector itself, e.g. because the downstream object is gone.
+const observedDeletionTTL = time.Hour
+
+// observedDeletions records the UIDs of upstream objects whose deletion has been observed.
+type observedDeletions struct {
+	lock sync.Mutex
+	uids map[types.UID]time.Time
+	now  func() time.Time
+}
+
+func newObservedDeletions() *observedDeletions {
+	return &observedDeletions{
+		uids: map[types.UID]time.Time{},
+		now:  time.Now,
+	}
+}
+
+func (d *observedDeletions) add(uid types.UID) {
+	if uid == "" {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	now := d.now()
+	for k, t := range d.uids {
+		if now.Sub(t) > observedDeletionTTL {
+			delete(d.uids, k)
+		}
+	}
+	d.uids[uid] = now
+}
+
+func (d *observedDeletions) observed(uid types.UID) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	t, found := d.uids[uid]
+	return found && d.now().Sub(t) <= observedDeletionTTL
+}
@@ -20,8 +20,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -32,8 +35,12 @@ import (
 	dynamicclient "k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamiclister"
 	"k8s.io/client-go/informers"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
@@ -42,6 +49,8 @@ import (
 	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
 	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
 	"github.com/kube-bind/kube-bind/pkg/indexers"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/provenance"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
 )
 
@@ -51,10 +60,28 @@ const (
 	applyManager = "kube-bind.io"
 )
 
+// Options configure the spec syncer of one resource.
+type Options struct {
+	ProviderNamespace string
+	ProviderID        string
+	ConsumerClusterID string
+
+	// Scale is the scale subresource of the consumer CRD, if any.
+	Scale *apiextensionsv1.CustomResourceSubresourceScale
+	// SyncConditions is true if the consumer CRD keeps status.conditions.
+	SyncConditions bool
+
+	ClusterScopedIsolation kubebindv1alpha1.Isolation
+	NamespaceIsolation     kubebindv1alpha1.NamespaceIsolation
+	OnImmutableChange      kubebindv1alpha1.ImmutableChangePolicy
+	UpstreamDeletion       kubebindv1alpha1.UpstreamDeletionPolicy
+	ProviderDeletion       kubebindv1alpha1.ProviderDeletionPolicy
+}
+
 // NewController returns a new controller reconciling downstream objects to upstream.
 func NewController(
 	gvr schema.GroupVersionResource,
-	providerNamespace string,
+	options Options,
 	consumerConfig, providerConfig *rest.Config,
 	consumerDynamicInformer, providerDynamicInformer informers.GenericInformer,
 	serviceNamespaceInformer dynamic.Informer[bindlisters.APIServiceNamespaceLister],
@@ -78,14 +105,32 @@ func NewController(
 	if err != nil {
 		return nil, err
 	}
+	consumerKubeClient, err := kubernetesclient.NewForConfig(consumerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerName})
+
+	var specReplicasPath []string
+	if options.Scale != nil {
+		specReplicasPath = strings.Split(strings.TrimPrefix(options.Scale.SpecReplicasPath, "."), ".")
+	}
 
 	dynamicConsumerLister := dynamiclister.New(consumerDynamicInformer.Informer().GetIndexer(), gvr)
 	dynamicProviderLister := dynamiclister.New(providerDynamicInformer.Informer().GetIndexer(), gvr)
+	deletions := newObservedDeletions()
 	c := &controller{
 		queue: queue,
 
-		consumerClient: consumerClient,
-		providerClient: providerClient,
+		gvr: gvr,
+
+		consumerClient:     consumerClient,
+		consumerKubeClient: consumerKubeClient,
+		providerClient:     providerClient,
+
+		broadcaster: broadcaster,
 
 		consumerDynamicLister:  dynamicConsumerLister,
 		consumerDynamicIndexer: consumerDynamicInformer.Informer().GetIndexer(),
@@ -95,13 +140,24 @@ func NewController(
 
 		serviceNamespaceInformer: serviceNamespaceInformer,
 
+		deletions: deletions,
+
 		reconciler: reconciler{
-			providerNamespace: providerNamespace,
+			providerNamespace:      options.ProviderNamespace,
+			providerID:             options.ProviderID,
+			consumerClusterID:      options.ConsumerClusterID,
+			clusterScopedIsolation: options.ClusterScopedIsolation,
+			namespaceIsolation:     options.NamespaceIsolation,
+			onImmutableChange:      options.OnImmutableChange,
+			upstreamDeletion:       options.UpstreamDeletion,
+			providerDeletion:       options.ProviderDeletion,
+			specReplicasPath:       specReplicasPath,
+			syncConditions:         options.SyncConditions,
 			getServiceNamespace: func(name string) (*kubebindv1alpha1.APIServiceNamespace, error) {
-				return serviceNamespaceInformer.Lister().APIServiceNamespaces(providerNamespace).Get(name)
+				return serviceNamespaceInformer.Lister().APIServiceNamespaces(options.ProviderNamespace).Get(name)
 			},
 			createServiceNamespace: func(ctx context.Context, sn *kubebindv1alpha1.APIServiceNamespace) (*kubebindv1alpha1.APIServiceNamespace, error) {
-				return providerBindClient.KubeBindV1alpha1().APIServiceNamespaces(providerNamespace).Create(ctx, sn, metav1.CreateOptions{})
+				return providerBindClient.KubeBindV1alpha1().APIServiceNamespaces(options.ProviderNamespace).Create(ctx, sn, metav1.CreateOptions{})
 			},
 			getProviderObject: func(ns, name string) (*unstructured.Unstructured, error) {
 				return dynamicProviderLister.Namespace(ns).Get(name)
@@ -118,12 +174,51 @@ func NewController(
 					obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: applyManager, Force: pointer.Bool(true)},
 				)
 			},
-			deleteProviderObject: func(ctx context.Context, ns, name string) error {
-				return providerClient.Resource(gvr).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
+			updateProviderObjectScale: func(ctx context.Context, ns, name string, replicas int64) error {
+				data, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}})
+				if err != nil {
+					return err
+				}
+				_, err = providerClient.Resource(gvr).Namespace(ns).Patch(ctx,
+					name, types.MergePatchType, data, metav1.PatchOptions{FieldManager: applyManager}, "scale",
+				)
+				return err
+			},
+			deleteProviderObject: func(ctx context.Context, ns, name string, propagation metav1.DeletionPropagation) error {
+				return providerClient.Resource(gvr).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+			},
+			deleteProviderObjectForReplacement: func(ctx context.Context, ns, name string, uid types.UID) error {
+				// foreground deletion to recreate only after all dependents are gone
+				propagation := metav1.DeletePropagationForeground
+				return providerClient.Resource(gvr).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{
+					Preconditions:     &metav1.Preconditions{UID: &uid},
+					PropagationPolicy: &propagation,
+				})
+			},
+			dryRunCreateProviderObject: func(ctx context.Context, obj *unstructured.Unstructured) error {
+				_, err := providerClient.Resource(gvr).Namespace(obj.GetNamespace()).Create(ctx, obj, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+				return err
+			},
+			dryRunUpdateProviderObject: func(ctx context.Context, obj *unstructured.Unstructured) error {
+				data, err := json.Marshal(obj.Object)
+				if err != nil {
+					return err
+				}
+				_, err = providerClient.Resource(gvr).Namespace(obj.GetNamespace()).Patch(ctx,
+					obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: applyManager, Force: pointer.Bool(true), DryRun: []string{metav1.DryRunAll}},
+				)
+				return err
 			},
 			updateConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 				return consumerClient.Resource(gvr).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
 			},
+			updateConsumerObjectStatus: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				return consumerClient.Resource(gvr).Namespace(obj.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+			},
+			deleteConsumerObject: func(ctx context.Context, ns, name string) error {
+				return consumerClient.Resource(gvr).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
+			},
+			upstreamDeleted: deletions.observed,
 			requeue: func(obj *unstructured.Unstructured, after time.Duration) error {
 				key, err := cache.MetaNamespaceKeyFunc(obj)
 				if err != nil {
@@ -132,6 +227,7 @@ func NewController(
 				queue.AddAfter(key, after)
 				return nil
 			},
+			recorder: recorder,
 		},
 	}
 
@@ -155,6 +251,7 @@ func NewController(
 			c.enqueueProvider(logger, newObj)
 		},
 		DeleteFunc: func(obj interface{}) {
+			c.observeDeletion(obj)
 			c.enqueueProvider(logger, obj)
 		},
 	})
@@ -166,8 +263,13 @@ func NewController(
 type controller struct {
 	queue workqueue.RateLimitingInterface
 
-	consumerClient dynamicclient.Interface
-	providerClient dynamicclient.Interface
+	gvr schema.GroupVersionResource
+
+	consumerClient     dynamicclient.Interface
+	consumerKubeClient kubernetesclient.Interface
+	providerClient     dynamicclient.Interface
+
+	broadcaster record.EventBroadcaster
 
 	consumerDynamicLister  dynamiclister.Lister
 	consumerDynamicIndexer cache.Indexer
@@ -177,9 +279,22 @@ type controller struct {
 
 	serviceNamespaceInformer dynamic.Informer[bindlisters.APIServiceNamespaceLister]
 
+	deletions *observedDeletions
+
 	reconciler
 }
 
+// observeDeletion records the UID of a deleted upstream object as evidence for
+// deletions by the service provider.
+func (c *controller) observeDeletion(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if upstream, ok := obj.(metav1.Object); ok {
+		c.deletions.add(upstream.GetUID())
+	}
+}
+
 func (c *controller) enqueueConsumer(logger klog.Logger, obj interface{}) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -203,7 +318,43 @@ func (c *controller) enqueueProvider(logger klog.Logger, obj interface{}) {
 		return
 	}
 
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if upstream, ok := obj.(metav1.Object); ok {
+		if downstreamNs, downstreamName, ok := provenance.Consumer(upstream, c.consumerClusterID); ok {
+			key := downstreamName
+			if downstreamNs != "" {
+				key = downstreamNs + "/" + downstreamName
+			}
+			logger.V(2).Info("queueing Unstructured", "key", key)
+			c.queue.Add(key)
+			return
+		}
+	}
+
 	if ns != "" {
+		if ns == c.providerNamespace && c.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+			// cluster-scoped downstream object
+			logger.V(2).Info("queueing Unstructured", "key", name)
+			c.queue.Add(name)
+			return
+		}
+		if ns == c.providerNamespace && c.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+			// flattened downstream object
+			downstream, err := mangling.Lookup(c.consumerDynamicLister, c.gvr.GroupResource(), name)
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					runtime.HandleError(err)
+				}
+				return
+			}
+			key := fmt.Sprintf("%s/%s", downstream.GetNamespace(), downstream.GetName())
+			logger.V(2).Info("queueing Unstructured", "key", key)
+			c.queue.Add(key)
+			return
+		}
+
 		sns, err := c.serviceNamespaceInformer.Informer().GetIndexer().ByIndex(indexers.ServiceNamespaceByNamespace, ns)
 		if err != nil {
 			if !errors.IsNotFound(err) {
@@ -265,6 +416,9 @@ func (c *controller) Start(ctx context.Context, numThreads int) {
 
 	logger := klog.FromContext(ctx).WithValues("controller", controllerName)
 
+	c.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.consumerKubeClient.CoreV1().Events("")})
+	defer c.broadcaster.Shutdown()
+
 	logger.Info("Starting controller")
 	defer logger.Info("Shutting down controller")
 
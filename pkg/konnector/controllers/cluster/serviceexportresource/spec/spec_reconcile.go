@@ -21,38 +21,109 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
 	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/provenance"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/syncstatus"
+)
+
+const (
+	deletedByProviderMessage = "Upstream object has been deleted by the service provider and will not be recreated"
+
+	// reasonAdmissionDenied is the reason of the Synced condition if an admission webhook
+	// or policy of the service provider denied the upstream object.
+	reasonAdmissionDenied = "AdmissionDenied"
 )
 
 type reconciler struct {
 	providerNamespace string
 
+	// providerID is recorded on downstream objects. Objects synced to another provider
+	// before are recreated upstream, even if they were synced or rejected before.
+	providerID string
+
+	// consumerClusterID is stamped onto upstream objects as provenance.
+	consumerClusterID string
+
+	// clusterScopedIsolation is IsolationNamespaced if cluster-scoped downstream objects
+	// are stored as namespaced upstream objects in the provider namespace.
+	clusterScopedIsolation kubebindv1alpha1.Isolation
+
+	// namespaceIsolation is NamespaceIsolationClusterNamespace if namespaced downstream
+	// objects are stored with mangled names in the provider namespace.
+	namespaceIsolation kubebindv1alpha1.NamespaceIsolation
+
+	// onImmutableChange is ImmutableChangePolicyReplace if upstream objects are recreated
+	// when the service provider rejects changes of immutable fields.
+	onImmutableChange kubebindv1alpha1.ImmutableChangePolicy
+
+	// upstreamDeletion is UpstreamDeletionPolicyAuthoritative if upstream objects deleted by the
+	// service provider are not recreated. Then providerDeletion decides whether the downstream
+	// object is marked with a condition or deleted.
+	upstreamDeletion kubebindv1alpha1.UpstreamDeletionPolicy
+	providerDeletion kubebindv1alpha1.ProviderDeletionPolicy
+
+	// specReplicasPath is the path of the replicas field in the scale subresource, or nil
+	// if there is no scale subresource.
+	specReplicasPath []string
+
+	// syncConditions is true if the downstream objects have a status subresource and keep
+	// status.conditions. Otherwise, sync results are only recorded in annotations.
+	syncConditions bool
+
 	getServiceNamespace    func(name string) (*kubebindv1alpha1.APIServiceNamespace, error)
 	createServiceNamespace func(ctx context.Context, sn *kubebindv1alpha1.APIServiceNamespace) (*kubebindv1alpha1.APIServiceNamespace, error)
 
-	getProviderObject    func(ns, name string) (*unstructured.Unstructured, error)
-	createProviderObject func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
-	updateProviderObject func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
-	deleteProviderObject func(ctx context.Context, ns, name string) error
+	getProviderObject         func(ns, name string) (*unstructured.Unstructured, error)
+	createProviderObject      func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	updateProviderObject      func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	updateProviderObjectScale func(ctx context.Context, ns, name string, replicas int64) error
+	deleteProviderObject      func(ctx context.Context, ns, name string, propagation metav1.DeletionPropagation) error
+
+	deleteProviderObjectForReplacement func(ctx context.Context, ns, name string, uid types.UID) error
+
+	dryRunCreateProviderObject func(ctx context.Context, obj *unstructured.Unstructured) error
+	dryRunUpdateProviderObject func(ctx context.Context, obj *unstructured.Unstructured) error
+
+	updateConsumerObject       func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	updateConsumerObjectStatus func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	deleteConsumerObject       func(ctx context.Context, ns, name string) error
 
-	updateConsumerObject func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	// upstreamDeleted returns true if the deletion of the upstream object with the given
+	// UID has been observed recently.
+	upstreamDeleted func(uid types.UID) bool
 
 	requeue func(obj *unstructured.Unstructured, after time.Duration) error
+
+	recorder record.EventRecorder
 }
 
 // reconcile syncs downstream objects (metadata and spec) with upstream objects.
 func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructured) error {
 	logger := klog.FromContext(ctx)
 
-	ns := obj.GetNamespace()
-	if ns != "" {
+	ns, name := obj.GetNamespace(), obj.GetName()
+	if ns != "" && r.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+		// all consumer namespaces are flattened into the provider namespace
+		name = mangling.Name(ns, name)
+		ns = r.providerNamespace
+
+		logger = logger.WithValues("upstreamNamespace", ns, "upstreamName", name)
+		ctx = klog.NewContext(ctx, logger)
+	} else if ns != "" {
 		sn, err := r.getServiceNamespace(ns)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
@@ -79,12 +150,23 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 
 		// continue with upstream namespace
 		ns = sn.Status.Namespace
+	} else if r.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+		ns = r.providerNamespace
 	}
 
-	upstream, err := r.getProviderObject(ns, obj.GetName())
+	upstream, err := r.getProviderObject(ns, name)
 	if err != nil && !errors.IsNotFound(err) {
 		return err
-	} else if errors.IsNotFound(err) {
+	}
+
+	if errors.IsNotFound(err) {
+		upstream = nil
+	}
+	if providerOwned(obj, upstream) {
+		return r.reconcileProviderOwned(ctx, obj, upstream)
+	}
+
+	if upstream == nil {
 		if obj.GetDeletionTimestamp() != nil && !obj.GetDeletionTimestamp().IsZero() {
 			logger.V(2).Info("object is already deleting, don't sync")
 
@@ -95,47 +177,50 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 			return nil
 		}
 
+		if r.migrating(obj) {
+			logger.Info("Recreating upstream object after migration from another service provider", "previousProvider", obj.GetAnnotations()[kubebindv1alpha1.ProviderAnnotationKey])
+		} else if r.deletedByProvider(obj) {
+			return r.reconcileDeletedByProvider(ctx, obj)
+		}
+
 		if obj, err = r.ensureDownstreamFinalizer(ctx, obj); err != nil {
 			return err
 		}
 
-		// clean up object
-		upstream = obj.DeepCopy()
-		upstream.SetUID("")
-		upstream.SetResourceVersion("")
-		upstream.SetNamespace(ns)
-		upstream.SetManagedFields(nil)
-		upstream.SetDeletionTimestamp(nil)
-		upstream.SetDeletionGracePeriodSeconds(nil)
-		upstream.SetOwnerReferences(nil)
-		upstream.SetFinalizers(nil)
-		unstructured.RemoveNestedField(upstream.Object, "status")
+		upstream = r.newUpstream(obj, ns, name)
+
+		if r.rejectedPermanently(obj) && !r.migrating(obj) {
+			logger.V(2).Info("upstream object was rejected permanently, waiting for spec change")
+			return nil
+		}
 
 		logger.Info("Creating upstream object")
-		if _, err := r.createProviderObject(ctx, upstream); err != nil {
-			return err
+		created, err := r.createProviderObject(ctx, upstream)
+		if err == nil && obj.GetAnnotations()[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] != "" {
+			r.recorder.Event(obj, corev1.EventTypeNormal, "ReplacedUpstream", "Upstream object has been recreated")
 		}
-		return nil
+		return r.recordSyncResult(ctx, obj, ns, name, created, err)
 	}
 
 	// here the upstream already exists. Update everything but the status.
 
 	if obj.GetDeletionTimestamp() != nil && !obj.GetDeletionTimestamp().IsZero() {
-		if upstream.GetDeletionTimestamp() != nil && !upstream.GetDeletionTimestamp().IsZero() {
-			logger.V(2).Info("upstream is already deleting, wait for it")
-			return nil // we will get an event when the upstream is deleted
-		}
-
-		logger.V(1).Info("object is already deleting downstream, deleting upstream too")
-		if err := r.deleteProviderObject(ctx, ns, obj.GetName()); err != nil && !errors.IsNotFound(err) {
-			return err
+		if upstream.GetDeletionTimestamp() == nil || upstream.GetDeletionTimestamp().IsZero() {
+			propagation := deletionPropagation(obj)
+			logger.V(1).Info("object is already deleting downstream, deleting upstream too", "propagationPolicy", propagation)
+			if err := r.deleteProviderObject(ctx, ns, name, propagation); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
 		}
 
-		if _, err := r.removeDownstreamFinalizer(ctx, obj); err != nil {
-			return err
-		}
+		// the finalizer stays until the upstream is gone, including its finalizers
+		logger.V(2).Info("upstream is deleting, waiting for it to be gone")
+		return r.updateDeletionProgress(ctx, obj, upstream)
+	}
 
-		logger.V(2).Info("upstream deleted, finalizer removed in downstream, waiting for downstream deletion to finish")
+	if upstream.GetDeletionTimestamp() != nil && !upstream.GetDeletionTimestamp().IsZero() &&
+		obj.GetAnnotations()[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] == string(upstream.GetUID()) {
+		logger.V(2).Info("upstream is being replaced, waiting for it to be gone")
 		return nil // we will get an event when the upstream is deleted
 	}
 
@@ -144,7 +229,24 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 		return err
 	}
 
-	downstreamSpec, foundDownstreamSpec, err := unstructured.NestedFieldNoCopy(obj.Object, "spec")
+	// replicas are synced through the scale subresource below, if there is one
+	desired := obj
+	if len(r.specReplicasPath) > 0 {
+		desired = obj.DeepCopy()
+		if replicas, found, err := unstructured.NestedFieldCopy(upstream.Object, r.specReplicasPath...); err != nil {
+			logger.Error(err, "failed to get upstream replicas")
+			return nil
+		} else if found {
+			if err := unstructured.SetNestedField(desired.Object, replicas, r.specReplicasPath...); err != nil {
+				logger.Error(err, "failed to set replicas")
+				return nil
+			}
+		} else {
+			unstructured.RemoveNestedField(desired.Object, r.specReplicasPath...)
+		}
+	}
+
+	downstreamSpec, foundDownstreamSpec, err := unstructured.NestedFieldNoCopy(desired.Object, "spec")
 	if err != nil {
 		logger.Error(err, "failed to get downstream spec")
 		return nil
@@ -154,34 +256,525 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 		logger.Error(err, "failed to get downstream spec")
 		return nil
 	}
-	if reflect.DeepEqual(downstreamSpec, upstreamSpec) {
-		return nil // nothing to do
+	specChanged := !reflect.DeepEqual(downstreamSpec, upstreamSpec)
+	if specChanged && r.rejectedPermanently(obj) {
+		logger.V(2).Info("upstream object update was rejected permanently, waiting for spec change")
+		return nil
 	}
-
-	upstream = upstream.DeepCopy()
-	if foundDownstreamSpec {
-		if err := unstructured.SetNestedField(upstream.Object, downstreamSpec, "spec"); err != nil {
-			bs, err := json.Marshal(downstreamSpec)
-			if err != nil {
-				logger.Error(err, "failed to marshal downstream spec", "spec", fmt.Sprintf("%s", downstreamSpec))
+	desiredUpstream := upstream.DeepCopy()
+	if provenanceChanged := provenance.Set(desiredUpstream, obj, r.consumerClusterID); specChanged || provenanceChanged {
+		upstream = desiredUpstream
+		if foundDownstreamSpec {
+			if err := unstructured.SetNestedField(upstream.Object, downstreamSpec, "spec"); err != nil {
+				bs, err := json.Marshal(downstreamSpec)
+				if err != nil {
+					logger.Error(err, "failed to marshal downstream spec", "spec", fmt.Sprintf("%s", downstreamSpec))
+					return nil // nothing we can do
+				}
+				logger.Error(err, "failed to set spec", "spec", string(bs))
 				return nil // nothing we can do
 			}
-			logger.Error(err, "failed to set spec", "spec", string(bs))
+		} else {
+			unstructured.RemoveNestedField(upstream.Object, "spec")
+		}
+
+		logger.Info("Updating update object")
+		upstream.SetManagedFields(nil) // server side apply does not want this
+		updated, err := r.updateProviderObject(ctx, upstream)
+		if err != nil {
+			if r.onImmutableChange == kubebindv1alpha1.ImmutableChangePolicyReplace && isImmutableFieldError(err) {
+				return r.replaceProviderObject(ctx, obj, upstream, err)
+			}
+			return r.recordSyncResult(ctx, obj, ns, name, nil, err)
+		}
+		upstream = updated
+	}
+
+	if len(r.specReplicasPath) > 0 {
+		if scaled, err := r.syncReplicas(ctx, obj, upstream); err != nil {
+			return r.recordSyncResult(ctx, obj, ns, name, nil, err)
+		} else if scaled {
+			// the scale subresource does not tell the new generation. The result is recorded
+			// when the update event of the upstream object comes in.
+			logger.V(2).Info("waiting for scaled upstream object")
+			return nil
+		}
+	}
+
+	return r.recordSyncResult(ctx, obj, ns, name, upstream, nil)
+}
+
+// newUpstream returns the upstream object to be created for the downstream object obj,
+// cleaned up from downstream-specific metadata and status.
+func (r *reconciler) newUpstream(obj *unstructured.Unstructured, ns, name string) *unstructured.Unstructured {
+	upstream := obj.DeepCopy()
+	upstream.SetUID("")
+	upstream.SetResourceVersion("")
+	upstream.SetNamespace(ns)
+	upstream.SetName(name)
+	upstream.SetManagedFields(nil)
+	upstream.SetDeletionTimestamp(nil)
+	upstream.SetDeletionGracePeriodSeconds(nil)
+	upstream.SetOwnerReferences(nil)
+	upstream.SetFinalizers(nil)
+	upstream.SetAnnotations(withoutSyncAnnotations(upstream.GetAnnotations()))
+	labels := upstream.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[kubebindv1alpha1.OriginLabelKey] = kubebindv1alpha1.OriginConsumer
+	upstream.SetLabels(labels)
+	provenance.Set(upstream, obj, r.consumerClusterID)
+	unstructured.RemoveNestedField(upstream.Object, "status")
+	return upstream
+}
+
+// providerOwned returns true if obj has been created from a provider-created upstream object.
+// The origin label can be removed by the consumer. Hence, a downstream object that has never
+// been synced upstream by the konnector, but whose upstream object was created by the service
+// provider, is provider-owned as well.
+func providerOwned(obj, upstream *unstructured.Unstructured) bool {
+	if obj.GetLabels()[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginProvider {
+		return true
+	}
+	if upstream == nil || !provenance.ProviderCreated(upstream) {
+		return false
+	}
+	if _, found := obj.GetAnnotations()[kubebindv1alpha1.SyncedGenerationAnnotationKey]; found {
+		return false // synced by the konnector before, e.g. before origins were recorded
+	}
+	for _, f := range obj.GetFinalizers() {
+		if f == kubebindv1alpha1.DownstreamFinalizer {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileProviderOwned keeps a downstream object that was created from a provider-created
+// upstream object in sync with the upstream object. Consumer changes to the spec and labels
+// are reverted, and the downstream object is deleted when the upstream object is gone.
+func (r *reconciler) reconcileProviderOwned(ctx context.Context, obj, upstream *unstructured.Unstructured) error {
+	logger := klog.FromContext(ctx)
+
+	if upstream == nil {
+		if obj.GetDeletionTimestamp() != nil && !obj.GetDeletionTimestamp().IsZero() {
+			return nil // already deleting
+		}
+		logger.Info("Deleting provider-owned downstream object because upstream is gone")
+		if err := r.deleteConsumerObject(ctx, obj.GetNamespace(), obj.GetName()); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	upstreamSpec, foundUpstreamSpec, err := unstructured.NestedFieldCopy(upstream.Object, "spec")
+	if err != nil {
+		logger.Error(err, "failed to get upstream spec")
+		return nil
+	}
+	downstreamSpec, _, err := unstructured.NestedFieldNoCopy(obj.Object, "spec")
+	if err != nil {
+		logger.Error(err, "failed to get downstream spec")
+		return nil
+	}
+	labels := make(map[string]string, len(upstream.GetLabels())+1)
+	for k, v := range upstream.GetLabels() {
+		labels[k] = v
+	}
+	labels[kubebindv1alpha1.OriginLabelKey] = kubebindv1alpha1.OriginProvider
+	if reflect.DeepEqual(downstreamSpec, upstreamSpec) && reflect.DeepEqual(obj.GetLabels(), labels) {
+		return nil
+	}
+
+	logger.Info("Reverting spec and labels of provider-owned downstream object")
+	obj = obj.DeepCopy()
+	obj.SetLabels(labels)
+	if foundUpstreamSpec {
+		if err := unstructured.SetNestedField(obj.Object, upstreamSpec, "spec"); err != nil {
+			logger.Error(err, "failed to set spec")
 			return nil // nothing we can do
 		}
 	} else {
-		unstructured.RemoveNestedField(upstream.Object, "spec")
+		unstructured.RemoveNestedField(obj.Object, "spec")
+	}
+	_, err = r.updateConsumerObject(ctx, obj)
+	return err
+}
+
+// deletionPropagation returns the propagation policy the downstream object is deleted
+// with. It is derived from the finalizers the API server adds for foreground and orphan
+// deletion. Orphaning is not forwarded, but upstream objects are deleted in background.
+func deletionPropagation(obj *unstructured.Unstructured) metav1.DeletionPropagation {
+	for _, f := range obj.GetFinalizers() {
+		if f == metav1.FinalizerDeleteDependents {
+			return metav1.DeletePropagationForeground
+		}
+	}
+	return metav1.DeletePropagationBackground
+}
+
+// updateDeletionProgress surfaces the progress of the upstream deletion in the
+// UpstreamDeleted condition of the downstream object.
+func (r *reconciler) updateDeletionProgress(ctx context.Context, obj, upstream *unstructured.Unstructured) error {
+	if !r.syncConditions {
+		return nil
+	}
+
+	message := "Waiting for the upstream object to be deleted"
+	if finalizers := upstream.GetFinalizers(); len(finalizers) > 0 {
+		message = fmt.Sprintf("Waiting for the upstream object to be deleted, pending finalizers: %s", strings.Join(finalizers, ", "))
+	}
+
+	obj = obj.DeepCopy()
+	changed, err := syncstatus.SetCondition(obj, metav1.Condition{
+		Type:               kubebindv1alpha1.DownstreamConditionUpstreamDeleted,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Deleting",
+		Message:            message,
+		ObservedGeneration: obj.GetGeneration(),
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	klog.FromContext(ctx).V(2).Info("updating deletion progress on downstream object", "message", message)
+	_, err = r.updateConsumerObjectStatus(ctx, obj)
+	if errors.IsNotFound(err) {
+		return nil // gone already
+	}
+	return err
+}
+
+// migrating returns true if obj has been synced to another service provider before.
+func (r *reconciler) migrating(obj *unstructured.Unstructured) bool {
+	previous := obj.GetAnnotations()[kubebindv1alpha1.ProviderAnnotationKey]
+	return previous != "" && previous != r.providerID
+}
+
+// deletedByProvider returns true if the deletion of the upstream object of obj by the
+// service provider is authoritative, and it has been observed. A missing upstream object
+// alone is no evidence, e.g. the konnector might have been pointed to another provider
+// namespace. Hence, the deletion of an upstream object with the recorded UID must have
+// been seen by the informer, or obj must have been marked as deleted by the provider before.
+func (r *reconciler) deletedByProvider(obj *unstructured.Unstructured) bool {
+	if r.upstreamDeletion != kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative {
+		return false
+	}
+	annotations := obj.GetAnnotations()
+	if annotations[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] != "" {
+		return false // deleted by the konnector itself
+	}
+	if annotations[kubebindv1alpha1.SyncErrorAnnotationKey] == deletedByProviderMessage {
+		return true
+	}
+	if cond, err := syncstatus.GetCondition(obj, kubebindv1alpha1.DownstreamConditionDeletedByProvider); err == nil && cond != nil && cond.Status == metav1.ConditionTrue {
+		return true
+	}
+	uid := annotations[kubebindv1alpha1.UpstreamUIDAnnotationKey]
+	return uid != "" && r.upstreamDeleted(types.UID(uid))
+}
+
+// reconcileDeletedByProvider handles a downstream object whose upstream object has been
+// deleted by the service provider. The upstream object is not recreated. Instead, the
+// downstream object is deleted or marked with the DeletedByProvider condition.
+func (r *reconciler) reconcileDeletedByProvider(ctx context.Context, obj *unstructured.Unstructured) error {
+	logger := klog.FromContext(ctx)
+
+	if r.providerDeletion == kubebindv1alpha1.ProviderDeletionPolicyDelete {
+		logger.Info("Deleting downstream object because service provider deleted upstream")
+		if err := r.deleteConsumerObject(ctx, obj.GetNamespace(), obj.GetName()); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.recorder.Event(obj, corev1.EventTypeNormal, "DeletedByProvider", "Upstream object has been deleted by the service provider, deleting object")
+		return nil // the finalizer is removed when the deletion is reconciled
+	}
+
+	obj, err := r.removeDownstreamFinalizer(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	if !r.syncConditions {
+		// no condition to set, fall back to the sync-error annotation
+		if obj.GetAnnotations()[kubebindv1alpha1.SyncErrorAnnotationKey] == deletedByProviderMessage {
+			return nil
+		}
+		obj = obj.DeepCopy()
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[kubebindv1alpha1.SyncErrorAnnotationKey] = deletedByProviderMessage
+		obj.SetAnnotations(annotations)
+		logger.Info("Marking downstream object as deleted by service provider")
+		if _, err := r.updateConsumerObject(ctx, obj); err != nil {
+			return err
+		}
+		r.recorder.Event(obj, corev1.EventTypeWarning, "DeletedByProvider", deletedByProviderMessage)
+		return nil
 	}
 
-	logger.Info("Updating update object")
-	upstream.SetManagedFields(nil) // server side apply does not want this
-	if _, err := r.updateProviderObject(ctx, upstream); err != nil {
+	obj = obj.DeepCopy()
+	changed, err := syncstatus.SetCondition(obj, metav1.Condition{
+		Type:               kubebindv1alpha1.DownstreamConditionDeletedByProvider,
+		Status:             metav1.ConditionTrue,
+		Reason:             "UpstreamDeleted",
+		Message:            deletedByProviderMessage,
+		ObservedGeneration: obj.GetGeneration(),
+	})
+	if err != nil {
 		return err
+	} else if !changed {
+		return nil
 	}
 
+	logger.Info("Marking downstream object as deleted by service provider")
+	if _, err := r.updateConsumerObjectStatus(ctx, obj); err != nil {
+		return err
+	}
+	r.recorder.Event(obj, corev1.EventTypeWarning, "DeletedByProvider", deletedByProviderMessage)
+
 	return nil
 }
 
+// replaceProviderObject deletes the upstream object after the service provider rejected
+// a change of immutable fields. Once the upstream object is gone, including its finalizers
+// and dependents, it is recreated with the new spec. Meanwhile, the downstream finalizer is
+// kept and the replacement is recorded in an annotation on the downstream object.
+func (r *reconciler) replaceProviderObject(ctx context.Context, obj, upstream *unstructured.Unstructured, rejection error) error {
+	logger := klog.FromContext(ctx)
+
+	if obj.GetAnnotations()[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] != string(upstream.GetUID()) {
+		obj = obj.DeepCopy()
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] = string(upstream.GetUID())
+		obj.SetAnnotations(annotations)
+		var err error
+		if obj, err = r.updateConsumerObject(ctx, obj); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Replacing upstream object because of a change of immutable fields", "rejection", rejection.Error())
+	if err := r.deleteProviderObjectForReplacement(ctx, upstream.GetNamespace(), upstream.GetName(), upstream.GetUID()); err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+		return err
+	}
+	r.recorder.Eventf(obj, corev1.EventTypeNormal, "ReplacingUpstream", "Service provider rejected a change of immutable fields, replacing upstream object: %v", rejection)
+
+	return nil // we will get an event when the upstream is deleted
+}
+
+// syncReplicas updates the upstream replicas through the scale subresource if they
+// differ from the downstream replicas. It returns true if the upstream object was scaled.
+func (r *reconciler) syncReplicas(ctx context.Context, obj, upstream *unstructured.Unstructured) (bool, error) {
+	logger := klog.FromContext(ctx)
+
+	replicas, found, err := unstructured.NestedInt64(obj.Object, r.specReplicasPath...)
+	if err != nil || !found {
+		return false, err
+	}
+	upstreamReplicas, found, err := unstructured.NestedInt64(upstream.Object, r.specReplicasPath...)
+	if err != nil {
+		return false, err
+	}
+	if found && upstreamReplicas == replicas {
+		return false, nil
+	}
+
+	logger.Info("Scaling upstream object", "replicas", replicas)
+	if err := r.updateProviderObjectScale(ctx, upstream.GetNamespace(), upstream.GetName(), replicas); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordSyncResult records the outcome of syncing obj upstream on the downstream object,
+// as annotations and as Synced condition. Permanent errors are not returned in order
+// to stop retrying until the downstream spec changes.
+func (r *reconciler) recordSyncResult(ctx context.Context, obj *unstructured.Unstructured, upstreamNamespace, upstreamName string, upstream *unstructured.Unstructured, syncErr error) error {
+	logger := klog.FromContext(ctx)
+
+	obj, err := r.updateSyncAnnotations(ctx, obj, upstreamNamespace, upstreamName, upstream, syncErr)
+	if err != nil {
+		return utilerrors.NewAggregate([]error{syncErr, err})
+	}
+
+	permanent := isPermanentError(syncErr)
+	if syncErr != nil && !permanent {
+		return syncErr // transient, just retry
+	}
+	if !r.syncConditions {
+		if permanent {
+			logger.Info("upstream object rejected by service provider", "error", syncErr)
+		}
+		return nil // the sync-error annotation is all we can record
+	}
+
+	cond := metav1.Condition{
+		Type:               kubebindv1alpha1.DownstreamConditionSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Synced",
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	if permanent {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = string(errors.ReasonForError(syncErr))
+		if isAdmissionDenial(syncErr) {
+			cond.Reason = reasonAdmissionDenied
+		}
+		cond.Message = syncErr.Error()
+		if status, ok := syncErr.(errors.APIStatus); ok && status.Status().Message != "" {
+			cond.Message = status.Status().Message
+		}
+	}
+
+	obj = obj.DeepCopy()
+	if changed, err := syncstatus.SetCondition(obj, cond); err != nil {
+		return utilerrors.NewAggregate([]error{syncErr, err})
+	} else if changed {
+		logger.V(2).Info("updating Synced condition on downstream object", "status", cond.Status, "reason", cond.Reason)
+		if _, err := r.updateConsumerObjectStatus(ctx, obj); err != nil {
+			return utilerrors.NewAggregate([]error{syncErr, err})
+		}
+	}
+
+	if permanent {
+		logger.Info("upstream object rejected by service provider, not retrying until spec changes", "reason", cond.Reason, "message", cond.Message)
+	}
+
+	return nil
+}
+
+// rejectedPermanently returns true if the current generation of obj has been rejected
+// permanently by the service provider before.
+func (r *reconciler) rejectedPermanently(obj *unstructured.Unstructured) bool {
+	cond, err := syncstatus.GetCondition(obj, kubebindv1alpha1.DownstreamConditionSynced)
+	if err != nil || cond == nil {
+		return false
+	}
+	if cond.Status != metav1.ConditionFalse || cond.ObservedGeneration != obj.GetGeneration() {
+		return false
+	}
+	return cond.Reason == string(metav1.StatusReasonInvalid) || cond.Reason == reasonAdmissionDenied
+}
+
+// isImmutableFieldError returns true if err is a rejection by the service provider because
+// immutable fields were changed. There is no dedicated status reason for that. Hence, we look
+// for the conventional messages of immutability validation and of CEL transition rules.
+func isImmutableFieldError(err error) bool {
+	if !errors.IsInvalid(err) {
+		return false
+	}
+	status, ok := err.(errors.APIStatus)
+	if !ok {
+		return false
+	}
+	messages := []string{status.Status().Message}
+	if details := status.Status().Details; details != nil {
+		for _, cause := range details.Causes {
+			messages = append(messages, cause.Message)
+		}
+	}
+	for _, msg := range messages {
+		msg = strings.ToLower(msg)
+		if strings.Contains(msg, "immutable") || strings.Contains(msg, "oldself") {
+			return true
+		}
+	}
+	return false
+}
+
+// isPermanentError returns true if err is a rejection by the service provider that
+// will not go away by retrying with the same object. Other forbidden errors, e.g. due
+// to missing permissions or terminating namespaces, are retried with backoff.
+func isPermanentError(err error) bool {
+	return errors.IsInvalid(err) || isAdmissionDenial(err)
+}
+
+// isAdmissionDenial returns true if err is a denial by an admission webhook or a
+// validating admission policy. The API server reports them as forbidden, without a
+// dedicated reason or cause. Hence, we look for the messages of the admission plugins.
+func isAdmissionDenial(err error) bool {
+	if !errors.IsForbidden(err) {
+		return false
+	}
+	status, ok := err.(errors.APIStatus)
+	if !ok {
+		return false
+	}
+	msg := status.Status().Message
+	return (strings.Contains(msg, "admission webhook") && strings.Contains(msg, "denied the request")) ||
+		strings.Contains(msg, "ValidatingAdmissionPolicy")
+}
+
+// updateSyncAnnotations records the upstream coordinates and the outcome of the last sync
+// on the downstream object. The synced generation is only bumped if syncErr is nil, and
+// then the generation of the upstream object is mapped to that of the downstream object.
+func (r *reconciler) updateSyncAnnotations(ctx context.Context, obj *unstructured.Unstructured, upstreamNamespace, upstreamName string, upstream *unstructured.Unstructured, syncErr error) (*unstructured.Unstructured, error) {
+	orig := obj.GetAnnotations()
+	annotations := make(map[string]string, len(orig)+4)
+	for k, v := range orig {
+		annotations[k] = v
+	}
+
+	if upstreamNamespace != "" {
+		annotations[kubebindv1alpha1.UpstreamNamespaceAnnotationKey] = upstreamNamespace
+	} else {
+		delete(annotations, kubebindv1alpha1.UpstreamNamespaceAnnotationKey)
+	}
+	annotations[kubebindv1alpha1.UpstreamNameAnnotationKey] = upstreamName
+	if syncErr != nil {
+		annotations[kubebindv1alpha1.SyncErrorAnnotationKey] = syncErr.Error()
+	} else {
+		annotations[kubebindv1alpha1.SyncedGenerationAnnotationKey] = strconv.FormatInt(obj.GetGeneration(), 10)
+		delete(annotations, kubebindv1alpha1.SyncErrorAnnotationKey)
+		delete(annotations, kubebindv1alpha1.ReplacingUpstreamAnnotationKey)
+		annotations[kubebindv1alpha1.ProviderAnnotationKey] = r.providerID
+		if upstream != nil {
+			annotations[kubebindv1alpha1.UpstreamUIDAnnotationKey] = string(upstream.GetUID())
+			syncstatus.RecordGeneration(annotations, upstream.GetGeneration(), obj.GetGeneration())
+		}
+	}
+
+	if reflect.DeepEqual(orig, annotations) {
+		return obj, nil
+	}
+
+	klog.FromContext(ctx).V(2).Info("updating sync annotations on downstream object")
+	obj = obj.DeepCopy()
+	obj.SetAnnotations(annotations)
+	return r.updateConsumerObject(ctx, obj)
+}
+
+// withoutSyncAnnotations returns the given annotations without those the konnector
+// maintains on downstream objects.
+func withoutSyncAnnotations(annotations map[string]string) map[string]string {
+	if annotations == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		switch k {
+		case kubebindv1alpha1.UpstreamNamespaceAnnotationKey,
+			kubebindv1alpha1.UpstreamNameAnnotationKey,
+			kubebindv1alpha1.SyncedGenerationAnnotationKey,
+			kubebindv1alpha1.UpstreamResourceVersionAnnotationKey,
+			kubebindv1alpha1.UpstreamUIDAnnotationKey,
+			kubebindv1alpha1.UpstreamGenerationsAnnotationKey,
+			kubebindv1alpha1.SyncErrorAnnotationKey,
+			kubebindv1alpha1.ReplacingUpstreamAnnotationKey,
+			kubebindv1alpha1.ProviderAnnotationKey:
+			continue
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
 func (r *reconciler) ensureDownstreamFinalizer(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	logger := klog.FromContext(ctx)
 
@@ -0,0 +1,567 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/syncstatus"
+)
+
+func TestIsImmutableFieldError(t *testing.T) {
+	gk := schema.GroupKind{Group: "mangodb.com", Kind: "MangoDB"}
+	gr := schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "not an API error",
+			err:  errors.New("field is immutable"),
+		},
+		{
+			name: "immutable field",
+			err:  apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Invalid(field.NewPath("spec", "tier"), "Shared", "field is immutable")}),
+			want: true,
+		},
+		{
+			name: "CEL transition rule",
+			err:  apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Invalid(field.NewPath("spec", "tier"), "Shared", "failed rule: self == oldSelf")}),
+			want: true,
+		},
+		{
+			name: "immutable only in the message",
+			err: &apierrors.StatusError{ErrStatus: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    422,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: "MangoDB.mangodb.com \"foo\" is invalid: spec.tier: Immutable",
+			}},
+			want: true,
+		},
+		{
+			name: "other validation error",
+			err:  apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Required(field.NewPath("spec", "tier"), "")}),
+		},
+		{
+			name: "forbidden",
+			err:  apierrors.NewForbidden(gr, "foo", errors.New("field is immutable")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isImmutableFieldError(tt.err))
+		})
+	}
+}
+
+func TestDeletedByProvider(t *testing.T) {
+	deletedByProviderCondition := map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":               kubebindv1alpha1.DownstreamConditionDeletedByProvider,
+				"status":             "True",
+				"reason":             "DeletedByProvider",
+				"lastTransitionTime": "2022-01-01T00:00:00Z",
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		policy      kubebindv1alpha1.UpstreamDeletionPolicy
+		annotations map[string]string
+		status      map[string]interface{}
+		observed    types.UID
+		want        bool
+	}{
+		{
+			name:        "recreate policy",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyRecreate,
+			annotations: map[string]string{kubebindv1alpha1.UpstreamUIDAnnotationKey: "uid"},
+			observed:    "uid",
+		},
+		{
+			name:   "never synced",
+			policy: kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+		},
+		{
+			name:        "synced, deletion not observed",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{kubebindv1alpha1.UpstreamUIDAnnotationKey: "uid"},
+		},
+		{
+			name:        "synced, deletion of other object observed",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{kubebindv1alpha1.UpstreamUIDAnnotationKey: "uid"},
+			observed:    "other",
+		},
+		{
+			name:        "synced, deletion observed",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{kubebindv1alpha1.UpstreamUIDAnnotationKey: "uid"},
+			observed:    "uid",
+			want:        true,
+		},
+		{
+			name:   "replaced by the konnector",
+			policy: kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{
+				kubebindv1alpha1.UpstreamUIDAnnotationKey:       "uid",
+				kubebindv1alpha1.ReplacingUpstreamAnnotationKey: "uid",
+			},
+			observed: "uid",
+		},
+		{
+			name:        "marked by sync error",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{kubebindv1alpha1.SyncErrorAnnotationKey: deletedByProviderMessage},
+			want:        true,
+		},
+		{
+			name:        "other sync error",
+			policy:      kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			annotations: map[string]string{kubebindv1alpha1.SyncErrorAnnotationKey: "admission webhook denied the request"},
+		},
+		{
+			name:   "marked by condition",
+			policy: kubebindv1alpha1.UpstreamDeletionPolicyAuthoritative,
+			status: deletedByProviderCondition,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletions := newObservedDeletions()
+			deletions.add(tt.observed)
+			r := &reconciler{
+				upstreamDeletion: tt.policy,
+				upstreamDeleted:  deletions.observed,
+			}
+
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			obj.SetAnnotations(tt.annotations)
+			if tt.status != nil {
+				obj.Object["status"] = tt.status
+			}
+			require.Equal(t, tt.want, r.deletedByProvider(obj))
+		})
+	}
+}
+
+func TestObservedDeletions(t *testing.T) {
+	now := time.Now()
+	d := newObservedDeletions()
+	d.now = func() time.Time { return now }
+
+	d.add("")
+	require.False(t, d.observed(""))
+
+	d.add("uid")
+	require.True(t, d.observed("uid"))
+	require.False(t, d.observed("other"))
+
+	now = now.Add(observedDeletionTTL + time.Second)
+	require.False(t, d.observed("uid"), "observed deletions must expire")
+}
+
+// fakeProvider records the calls of the reconciler to the provider and consumer clusters.
+type fakeProvider struct {
+	upstream *unstructured.Unstructured
+
+	deleted           []metav1.DeletionPropagation
+	consumerUpdates   []*unstructured.Unstructured
+	consumerStatus    []*unstructured.Unstructured
+	providerCreates   []*unstructured.Unstructured
+	providerUpdates   []*unstructured.Unstructured
+	consumerDeletions int
+}
+
+func newTestReconciler(p *fakeProvider) *reconciler {
+	return &reconciler{
+		providerNamespace: "kube-bind-abc",
+		providerID:        "provider",
+		consumerClusterID: "cluster",
+		syncConditions:    true,
+		getProviderObject: func(ns, name string) (*unstructured.Unstructured, error) {
+			if p.upstream == nil {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}, name)
+			}
+			return p.upstream.DeepCopy(), nil
+		},
+		createProviderObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			p.providerCreates = append(p.providerCreates, obj)
+			return obj, nil
+		},
+		updateProviderObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			p.providerUpdates = append(p.providerUpdates, obj)
+			return obj, nil
+		},
+		deleteProviderObject: func(ctx context.Context, ns, name string, propagation metav1.DeletionPropagation) error {
+			p.deleted = append(p.deleted, propagation)
+			return nil
+		},
+		updateConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			p.consumerUpdates = append(p.consumerUpdates, obj)
+			return obj, nil
+		},
+		updateConsumerObjectStatus: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			p.consumerStatus = append(p.consumerStatus, obj)
+			return obj, nil
+		},
+		deleteConsumerObject: func(ctx context.Context, ns, name string) error {
+			p.consumerDeletions++
+			return nil
+		},
+		upstreamDeleted: func(uid types.UID) bool { return false },
+		requeue:         func(obj *unstructured.Unstructured, after time.Duration) error { return nil },
+		recorder:        record.NewFakeRecorder(10),
+	}
+}
+
+func newTestObject(name string, finalizers ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "mangodb.com/v1alpha1",
+		"kind":       "MangoDB",
+		"spec":       map[string]interface{}{"tier": "Dedicated"},
+	}}
+	obj.SetName(name)
+	obj.SetUID("uid")
+	obj.SetGeneration(1)
+	obj.SetFinalizers(finalizers)
+	return obj
+}
+
+func TestReconcileDeletion(t *testing.T) {
+	now := metav1.Now()
+	deleting := func(obj *unstructured.Unstructured) *unstructured.Unstructured {
+		obj.SetDeletionTimestamp(&now)
+		return obj
+	}
+
+	tests := []struct {
+		name           string
+		obj            *unstructured.Unstructured
+		upstream       *unstructured.Unstructured
+		wantDeleted    []metav1.DeletionPropagation
+		wantFinalizers []string
+		wantMessage    string
+	}{
+		{
+			name:           "deletes upstream in background and keeps finalizer",
+			obj:            deleting(newTestObject("foo", kubebindv1alpha1.DownstreamFinalizer)),
+			upstream:       newTestObject("foo"),
+			wantDeleted:    []metav1.DeletionPropagation{metav1.DeletePropagationBackground},
+			wantFinalizers: []string{kubebindv1alpha1.DownstreamFinalizer},
+			wantMessage:    "Waiting for the upstream object to be deleted",
+		},
+		{
+			name:           "forwards foreground deletion",
+			obj:            deleting(newTestObject("foo", metav1.FinalizerDeleteDependents, kubebindv1alpha1.DownstreamFinalizer)),
+			upstream:       newTestObject("foo"),
+			wantDeleted:    []metav1.DeletionPropagation{metav1.DeletePropagationForeground},
+			wantFinalizers: []string{metav1.FinalizerDeleteDependents, kubebindv1alpha1.DownstreamFinalizer},
+			wantMessage:    "Waiting for the upstream object to be deleted",
+		},
+		{
+			name:           "waits for upstream finalizers",
+			obj:            deleting(newTestObject("foo", kubebindv1alpha1.DownstreamFinalizer)),
+			upstream:       deleting(newTestObject("foo", "mangodb.com/backup")),
+			wantFinalizers: []string{kubebindv1alpha1.DownstreamFinalizer},
+			wantMessage:    "Waiting for the upstream object to be deleted, pending finalizers: mangodb.com/backup",
+		},
+		{
+			name:           "removes finalizer when upstream is gone",
+			obj:            deleting(newTestObject("foo", "example.com/other", kubebindv1alpha1.DownstreamFinalizer)),
+			wantFinalizers: []string{"example.com/other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{upstream: tt.upstream}
+			r := newTestReconciler(p)
+
+			require.NoError(t, r.reconcile(context.Background(), tt.obj))
+			require.Equal(t, tt.wantDeleted, p.deleted)
+			require.Empty(t, p.providerCreates)
+
+			finalizers := tt.obj.GetFinalizers()
+			if len(p.consumerUpdates) > 0 {
+				finalizers = p.consumerUpdates[len(p.consumerUpdates)-1].GetFinalizers()
+			}
+			require.Equal(t, tt.wantFinalizers, finalizers)
+
+			if tt.wantMessage == "" {
+				require.Empty(t, p.consumerStatus)
+				return
+			}
+			require.Len(t, p.consumerStatus, 1)
+			cond, err := syncstatus.GetCondition(p.consumerStatus[0], kubebindv1alpha1.DownstreamConditionUpstreamDeleted)
+			require.NoError(t, err)
+			require.NotNil(t, cond)
+			require.Equal(t, metav1.ConditionFalse, cond.Status)
+			require.Equal(t, tt.wantMessage, cond.Message)
+		})
+	}
+}
+
+func TestIsPermanentError(t *testing.T) {
+	gk := schema.GroupKind{Group: "mangodb.com", Kind: "MangoDB"}
+	gr := schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+	forbidden := func(msg string) error {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    403,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: msg,
+		}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "not an API error",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "invalid",
+			err:  apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Required(field.NewPath("spec", "tier"), "")}),
+			want: true,
+		},
+		{
+			name: "denied by admission webhook",
+			err:  forbidden(`admission webhook "validate.mangodb.com" denied the request: tier Dedicated is sold out`),
+			want: true,
+		},
+		{
+			name: "denied by validating admission policy",
+			err:  forbidden(`mangodbs.mangodb.com "foo" is forbidden: ValidatingAdmissionPolicy 'tiers' with binding 'tiers' denied request: tier Dedicated is sold out`),
+			want: true,
+		},
+		{
+			name: "missing permissions",
+			err:  apierrors.NewForbidden(gr, "foo", errors.New(`User "system:serviceaccount:kube-bind:konnector" cannot create resource "mangodbs"`)),
+		},
+		{
+			name: "terminating namespace",
+			err:  apierrors.NewForbidden(gr, "foo", errors.New("unable to create new content in namespace kube-bind-abc because it is being terminated")),
+		},
+		{
+			name: "conflict",
+			err:  apierrors.NewConflict(gr, "foo", errors.New("the object has been modified")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isPermanentError(tt.err))
+		})
+	}
+}
+
+func TestRecordSyncResult(t *testing.T) {
+	gk := schema.GroupKind{Group: "mangodb.com", Kind: "MangoDB"}
+	gr := schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+	invalid := apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Required(field.NewPath("spec", "tier"), "")})
+	denied := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    403,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: `admission webhook "validate.mangodb.com" denied the request: tier Dedicated is sold out`,
+	}}
+
+	synced := newTestObject("foo")
+	_, err := syncstatus.SetCondition(synced, metav1.Condition{
+		Type:               kubebindv1alpha1.DownstreamConditionSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Synced",
+		ObservedGeneration: 1,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		obj            *unstructured.Unstructured
+		syncErr        error
+		syncConditions bool
+
+		wantErr          bool
+		wantSyncError    bool
+		wantStatusUpdate bool
+		wantStatus       metav1.ConditionStatus
+		wantReason       string
+	}{
+		{
+			name:             "synced",
+			obj:              newTestObject("foo"),
+			syncConditions:   true,
+			wantStatusUpdate: true,
+			wantStatus:       metav1.ConditionTrue,
+			wantReason:       "Synced",
+		},
+		{
+			name:           "synced with unchanged condition",
+			obj:            synced,
+			syncConditions: true,
+		},
+		{
+			name:           "transient error is retried",
+			obj:            newTestObject("foo"),
+			syncErr:        apierrors.NewServiceUnavailable("etcd is down"),
+			syncConditions: true,
+			wantErr:        true,
+			wantSyncError:  true,
+		},
+		{
+			name:           "missing permissions are retried",
+			obj:            newTestObject("foo"),
+			syncErr:        apierrors.NewForbidden(gr, "foo", errors.New(`User "konnector" cannot create resource "mangodbs"`)),
+			syncConditions: true,
+			wantErr:        true,
+			wantSyncError:  true,
+		},
+		{
+			name:             "invalid object is not retried",
+			obj:              newTestObject("foo"),
+			syncErr:          invalid,
+			syncConditions:   true,
+			wantSyncError:    true,
+			wantStatusUpdate: true,
+			wantStatus:       metav1.ConditionFalse,
+			wantReason:       string(metav1.StatusReasonInvalid),
+		},
+		{
+			name:             "admission denial is not retried",
+			obj:              newTestObject("foo"),
+			syncErr:          denied,
+			syncConditions:   true,
+			wantSyncError:    true,
+			wantStatusUpdate: true,
+			wantStatus:       metav1.ConditionFalse,
+			wantReason:       reasonAdmissionDenied,
+		},
+		{
+			name:          "invalid object without conditions",
+			obj:           newTestObject("foo"),
+			syncErr:       invalid,
+			wantSyncError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{}
+			r := newTestReconciler(p)
+			r.syncConditions = tt.syncConditions
+
+			upstream := newTestObject("foo")
+			upstream.SetUID("upstream-uid")
+			err := r.recordSyncResult(context.Background(), tt.obj.DeepCopy(), "", "foo", upstream, tt.syncErr)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, p.consumerUpdates, 1)
+			annotations := p.consumerUpdates[0].GetAnnotations()
+			require.Equal(t, "foo", annotations[kubebindv1alpha1.UpstreamNameAnnotationKey])
+			if tt.wantSyncError {
+				require.Equal(t, tt.syncErr.Error(), annotations[kubebindv1alpha1.SyncErrorAnnotationKey])
+				require.NotContains(t, annotations, kubebindv1alpha1.SyncedGenerationAnnotationKey)
+			} else {
+				require.NotContains(t, annotations, kubebindv1alpha1.SyncErrorAnnotationKey)
+				require.Equal(t, "1", annotations[kubebindv1alpha1.SyncedGenerationAnnotationKey])
+				require.Equal(t, "upstream-uid", annotations[kubebindv1alpha1.UpstreamUIDAnnotationKey])
+			}
+
+			if !tt.wantStatusUpdate {
+				require.Empty(t, p.consumerStatus)
+				return
+			}
+			require.Len(t, p.consumerStatus, 1)
+			cond, err := syncstatus.GetCondition(p.consumerStatus[0], kubebindv1alpha1.DownstreamConditionSynced)
+			require.NoError(t, err)
+			require.NotNil(t, cond)
+			require.Equal(t, tt.wantStatus, cond.Status)
+			require.Equal(t, tt.wantReason, cond.Reason)
+			require.Equal(t, int64(1), cond.ObservedGeneration)
+			if tt.syncErr != nil {
+				require.Equal(t, tt.syncErr.(apierrors.APIStatus).Status().Message, cond.Message)
+			}
+		})
+	}
+}
+
+func TestRejectedPermanently(t *testing.T) {
+	withCondition := func(status metav1.ConditionStatus, reason string, observedGeneration int64) *unstructured.Unstructured {
+		obj := newTestObject("foo")
+		obj.SetGeneration(2)
+		_, err := syncstatus.SetCondition(obj, metav1.Condition{
+			Type:               kubebindv1alpha1.DownstreamConditionSynced,
+			Status:             status,
+			Reason:             reason,
+			ObservedGeneration: observedGeneration,
+		})
+		require.NoError(t, err)
+		return obj
+	}
+
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want bool
+	}{
+		{name: "no condition", obj: newTestObject("foo")},
+		{name: "synced", obj: withCondition(metav1.ConditionTrue, "Synced", 2)},
+		{name: "invalid", obj: withCondition(metav1.ConditionFalse, string(metav1.StatusReasonInvalid), 2), want: true},
+		{name: "admission denied", obj: withCondition(metav1.ConditionFalse, reasonAdmissionDenied, 2), want: true},
+		{name: "invalid older generation", obj: withCondition(metav1.ConditionFalse, string(metav1.StatusReasonInvalid), 1)},
+		{name: "admission denied older generation", obj: withCondition(metav1.ConditionFalse, reasonAdmissionDenied, 1)},
+		{name: "forbidden of an older konnector", obj: withCondition(metav1.ConditionFalse, string(metav1.StatusReasonForbidden), 2)},
+		{name: "other reason", obj: withCondition(metav1.ConditionFalse, string(metav1.StatusReasonConflict), 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(&fakeProvider{})
+			require.Equal(t, tt.want, r.rejectedPermanently(tt.obj))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"context"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
+)
+
+// Validate validates a downstream object against the service provider by a server-side
+// dry-run of the corresponding upstream create or update. It implements webhook.Validator.
+func (c *controller) Validate(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+	return c.reconciler.validate(ctx, operation, obj, old)
+}
+
+// validate returns the error of the service provider if it would reject the upstream
+// object of obj. Everything that cannot be validated upfront is admitted, and left to
+// the asynchronous sync.
+func (r *reconciler) validate(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+	logger := klog.FromContext(ctx)
+
+	switch operation {
+	case admissionv1.Create, admissionv1.Update:
+	default:
+		return nil
+	}
+	if obj == nil || obj.GetName() == "" {
+		return nil // generated names are only known after admission
+	}
+	if old != nil && old.GetLabels()[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginProvider &&
+		obj.GetLabels()[kubebindv1alpha1.OriginLabelKey] != kubebindv1alpha1.OriginProvider {
+		return errors.NewInvalid(obj.GroupVersionKind().GroupKind(), obj.GetName(), field.ErrorList{
+			field.Forbidden(field.NewPath("metadata", "labels").Key(kubebindv1alpha1.OriginLabelKey), "objects owned by the service provider are read-only"),
+		})
+	}
+	if obj.GetLabels()[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginProvider {
+		return nil // reverted anyway
+	}
+	if obj.GetDeletionTimestamp() != nil && !obj.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+	if old != nil {
+		newSpec, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec")
+		oldSpec, _, _ := unstructured.NestedFieldNoCopy(old.Object, "spec")
+		if reflect.DeepEqual(newSpec, oldSpec) {
+			return nil // metadata-only change, e.g. by the konnector itself
+		}
+	}
+
+	ns, name := obj.GetNamespace(), obj.GetName()
+	if ns != "" && r.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+		name = mangling.Name(ns, name)
+		ns = r.providerNamespace
+	} else if ns != "" {
+		sn, err := r.getServiceNamespace(ns)
+		if errors.IsNotFound(err) || (err == nil && sn.Status.Namespace == "") {
+			return nil // no upstream namespace yet to validate in
+		} else if err != nil {
+			return err
+		}
+		ns = sn.Status.Namespace
+	} else if r.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+		ns = r.providerNamespace
+	}
+	logger = logger.WithValues("upstreamNamespace", ns, "upstreamName", name)
+
+	upstream, err := r.getProviderObject(ns, name)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if errors.IsNotFound(err) {
+		logger.V(2).Info("dry-run creating upstream object")
+		return r.admissionError(ctx, r.dryRunCreateProviderObject(ctx, r.newUpstream(obj, ns, name)))
+	}
+
+	desired := upstream.DeepCopy()
+	if spec, found, err := unstructured.NestedFieldCopy(obj.Object, "spec"); err != nil {
+		return nil // nothing we can validate
+	} else if found {
+		if err := unstructured.SetNestedField(desired.Object, spec, "spec"); err != nil {
+			return nil // nothing we can validate
+		}
+	} else {
+		unstructured.RemoveNestedField(desired.Object, "spec")
+	}
+	desired.SetManagedFields(nil) // server side apply does not want this
+
+	logger.V(2).Info("dry-run updating upstream object")
+	err = r.dryRunUpdateProviderObject(ctx, desired)
+	if err != nil && r.onImmutableChange == kubebindv1alpha1.ImmutableChangePolicyReplace && isImmutableFieldError(err) {
+		return nil // the upstream object will be replaced
+	}
+	return r.admissionError(ctx, err)
+}
+
+// admissionError returns err if it is a permanent rejection by the service provider.
+// Other errors are logged, and the request is admitted.
+func (r *reconciler) admissionError(ctx context.Context, err error) error {
+	if err == nil || isPermanentError(err) {
+		return err
+	}
+	klog.FromContext(ctx).Error(err, "failed to validate against service provider, admitting")
+	return nil
+}
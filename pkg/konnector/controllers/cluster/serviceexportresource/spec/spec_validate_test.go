@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestValidate(t *testing.T) {
+	gk := schema.GroupKind{Group: "mangodb.com", Kind: "MangoDB"}
+	invalid := apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Required(field.NewPath("spec", "tier"), "")})
+	denied := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    403,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: `admission webhook "validate.mangodb.com" denied the request: tier Dedicated is sold out`,
+	}}
+	immutable := apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Invalid(field.NewPath("spec", "tier"), "Shared", "field is immutable")})
+
+	withLabels := func(obj *unstructured.Unstructured, labels map[string]string) *unstructured.Unstructured {
+		obj.SetLabels(labels)
+		return obj
+	}
+	withTier := func(obj *unstructured.Unstructured, tier string) *unstructured.Unstructured {
+		obj.Object["spec"] = map[string]interface{}{"tier": tier}
+		return obj
+	}
+	fromProvider := map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginProvider}
+
+	tests := []struct {
+		name              string
+		operation         admissionv1.Operation
+		obj, old          *unstructured.Unstructured
+		upstream          *unstructured.Unstructured
+		onImmutableChange kubebindv1alpha1.ImmutableChangePolicy
+		dryRunErr         error
+
+		wantDryRunCreates int
+		wantDryRunUpdates int
+		wantErr           bool
+	}{
+		{
+			name:      "delete is admitted",
+			operation: admissionv1.Delete,
+			old:       newTestObject("foo"),
+		},
+		{
+			name:      "generated name is admitted",
+			operation: admissionv1.Create,
+			obj:       newTestObject(""),
+		},
+		{
+			name:              "create allowed by the provider",
+			operation:         admissionv1.Create,
+			obj:               newTestObject("foo"),
+			wantDryRunCreates: 1,
+		},
+		{
+			name:              "create denied by the provider",
+			operation:         admissionv1.Create,
+			obj:               newTestObject("foo"),
+			dryRunErr:         invalid,
+			wantDryRunCreates: 1,
+			wantErr:           true,
+		},
+		{
+			name:              "create denied by an admission webhook of the provider",
+			operation:         admissionv1.Create,
+			obj:               newTestObject("foo"),
+			dryRunErr:         denied,
+			wantDryRunCreates: 1,
+			wantErr:           true,
+		},
+		{
+			name:              "create admitted if the konnector lacks permissions",
+			operation:         admissionv1.Create,
+			obj:               newTestObject("foo"),
+			dryRunErr:         apierrors.NewForbidden(schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}, "foo", errors.New(`User "konnector" cannot create resource "mangodbs"`)),
+			wantDryRunCreates: 1,
+		},
+		{
+			name:              "create admitted if the provider fails",
+			operation:         admissionv1.Create,
+			obj:               newTestObject("foo"),
+			dryRunErr:         apierrors.NewServiceUnavailable("etcd is down"),
+			wantDryRunCreates: 1,
+		},
+		{
+			name:              "update allowed by the provider",
+			operation:         admissionv1.Update,
+			obj:               withTier(newTestObject("foo"), "Shared"),
+			old:               newTestObject("foo"),
+			upstream:          newTestObject("foo"),
+			wantDryRunUpdates: 1,
+		},
+		{
+			name:              "update denied by the provider",
+			operation:         admissionv1.Update,
+			obj:               withTier(newTestObject("foo"), "Shared"),
+			old:               newTestObject("foo"),
+			upstream:          newTestObject("foo"),
+			dryRunErr:         invalid,
+			wantDryRunUpdates: 1,
+			wantErr:           true,
+		},
+		{
+			name:              "immutable change denied",
+			operation:         admissionv1.Update,
+			obj:               withTier(newTestObject("foo"), "Shared"),
+			old:               newTestObject("foo"),
+			upstream:          newTestObject("foo"),
+			dryRunErr:         immutable,
+			wantDryRunUpdates: 1,
+			wantErr:           true,
+		},
+		{
+			name:              "immutable change admitted with replace policy",
+			operation:         admissionv1.Update,
+			obj:               withTier(newTestObject("foo"), "Shared"),
+			old:               newTestObject("foo"),
+			upstream:          newTestObject("foo"),
+			onImmutableChange: kubebindv1alpha1.ImmutableChangePolicyReplace,
+			dryRunErr:         immutable,
+			wantDryRunUpdates: 1,
+		},
+		{
+			name:      "metadata-only update is admitted",
+			operation: admissionv1.Update,
+			obj:       withLabels(newTestObject("foo"), map[string]string{"app": "foo"}),
+			old:       newTestObject("foo"),
+			upstream:  newTestObject("foo"),
+		},
+		{
+			name:      "removing the provider origin is denied",
+			operation: admissionv1.Update,
+			obj:       newTestObject("foo"),
+			old:       withLabels(newTestObject("foo"), fromProvider),
+			wantErr:   true,
+		},
+		{
+			name:      "update of a provider object is admitted",
+			operation: admissionv1.Update,
+			obj:       withLabels(withTier(newTestObject("foo"), "Shared"), fromProvider),
+			old:       withLabels(newTestObject("foo"), fromProvider),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dryRunCreates, dryRunUpdates []*unstructured.Unstructured
+			r := newTestReconciler(&fakeProvider{upstream: tt.upstream})
+			r.onImmutableChange = tt.onImmutableChange
+			r.dryRunCreateProviderObject = func(ctx context.Context, obj *unstructured.Unstructured) error {
+				dryRunCreates = append(dryRunCreates, obj)
+				return tt.dryRunErr
+			}
+			r.dryRunUpdateProviderObject = func(ctx context.Context, obj *unstructured.Unstructured) error {
+				dryRunUpdates = append(dryRunUpdates, obj)
+				return tt.dryRunErr
+			}
+
+			err := r.validate(context.Background(), tt.operation, tt.obj, tt.old)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.True(t, isPermanentError(err), "expected a permanent error, got %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, dryRunCreates, tt.wantDryRunCreates)
+			require.Len(t, dryRunUpdates, tt.wantDryRunUpdates)
+
+			for _, obj := range dryRunCreates {
+				require.Equal(t, kubebindv1alpha1.OriginConsumer, obj.GetLabels()[kubebindv1alpha1.OriginLabelKey])
+				require.Empty(t, obj.GetUID())
+			}
+			for _, obj := range dryRunUpdates {
+				require.Equal(t, tt.obj.Object["spec"], obj.Object["spec"])
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	dynamicclient "k8s.io/client-go/dynamic"
@@ -38,6 +39,7 @@ import (
 	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
 	bindlisters "github.com/kube-bind/kube-bind/pkg/client/listers/kubebind/v1alpha1"
 	"github.com/kube-bind/kube-bind/pkg/indexers"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
 )
 
@@ -48,8 +50,12 @@ const (
 // NewController returns a new controller reconciling status of upstream to downstream.
 func NewController(
 	gvr schema.GroupVersionResource,
-	providerNamespace string,
+	providerNamespace, consumerClusterID string,
 	consumerConfig, providerConfig *rest.Config,
+	providerObjects kubebindv1alpha1.ProviderObjectsPolicy,
+	statusSubresource bool,
+	clusterScopedIsolation kubebindv1alpha1.Isolation,
+	namespaceIsolation kubebindv1alpha1.NamespaceIsolation,
 	consumerDynamicInformer, providerDynamicInformer informers.GenericInformer,
 	serviceNamespaceInformer dynamic.Informer[bindlisters.APIServiceNamespaceLister],
 ) (*controller, error) {
@@ -77,8 +83,10 @@ func NewController(
 	c := &controller{
 		queue: queue,
 
-		gvr:               gvr,
-		providerNamespace: providerNamespace,
+		gvr:                    gvr,
+		providerNamespace:      providerNamespace,
+		clusterScopedIsolation: clusterScopedIsolation,
+		namespaceIsolation:     namespaceIsolation,
 
 		consumerClient: consumerClient,
 		providerClient: providerClient,
@@ -92,6 +100,13 @@ func NewController(
 		serviceNamespaceInformer: serviceNamespaceInformer,
 
 		reconciler: reconciler{
+			providerNamespace:      providerNamespace,
+			consumerClusterID:      consumerClusterID,
+			providerObjects:        providerObjects,
+			statusSubresource:      statusSubresource,
+			clusterScopedIsolation: clusterScopedIsolation,
+			namespaceIsolation:     namespaceIsolation,
+
 			getServiceNamespace: func(upstreamNamespace string) (*kubebindv1alpha1.APIServiceNamespace, error) {
 				sns, err := serviceNamespaceInformer.Informer().GetIndexer().ByIndex(indexers.ServiceNamespaceByNamespace, upstreamNamespace)
 				if err != nil {
@@ -105,6 +120,18 @@ func NewController(
 			getConsumerObject: func(ns, name string) (*unstructured.Unstructured, error) {
 				return dynamicConsumerLister.Namespace(ns).Get(name)
 			},
+			lookupConsumerObject: func(upstreamName string) (*unstructured.Unstructured, error) {
+				return mangling.Lookup(dynamicConsumerLister, gvr.GroupResource(), upstreamName)
+			},
+			createConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				return consumerClient.Resource(gvr).Namespace(obj.GetNamespace()).Create(ctx, obj, metav1.CreateOptions{})
+			},
+			updateConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+				return consumerClient.Resource(gvr).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+			},
+			patchConsumerObject: func(ctx context.Context, ns, name string, patch []byte) (*unstructured.Unstructured, error) {
+				return consumerClient.Resource(gvr).Namespace(ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+			},
 			updateConsumerObjectStatus: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 				return consumerClient.Resource(gvr).Namespace(obj.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
 			},
@@ -114,6 +141,10 @@ func NewController(
 		},
 	}
 
+	indexers.AddIfNotPresentOrDie(providerDynamicInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.UpstreamByConsumerObject: indexers.IndexUpstreamByConsumerObject,
+	})
+
 	consumerDynamicInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			c.enqueueConsumer(logger, obj)
@@ -145,8 +176,10 @@ func NewController(
 type controller struct {
 	queue workqueue.RateLimitingInterface
 
-	gvr               schema.GroupVersionResource
-	providerNamespace string
+	gvr                    schema.GroupVersionResource
+	providerNamespace      string
+	clusterScopedIsolation kubebindv1alpha1.Isolation
+	namespaceIsolation     kubebindv1alpha1.NamespaceIsolation
 
 	consumerClient, providerClient dynamicclient.Interface
 
@@ -173,6 +206,12 @@ func (c *controller) enqueueProvider(logger klog.Logger, obj interface{}) {
 		return
 	}
 	if ns != "" {
+		if ns == c.providerNamespace && (c.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced || c.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace) {
+			logger.V(2).Info("queueing Unstructured", "key", key)
+			c.queue.Add(key)
+			return
+		}
+
 		sns, err := c.serviceNamespaceInformer.Informer().GetIndexer().ByIndex(indexers.ServiceNamespaceByNamespace, ns)
 		if err != nil {
 			runtime.HandleError(err)
@@ -202,6 +241,32 @@ func (c *controller) enqueueConsumer(logger klog.Logger, obj interface{}) {
 		return
 	}
 
+	upstreams, err := c.providerDynamicIndexer.ByIndex(indexers.UpstreamByConsumerObject, indexers.UpstreamByConsumerObjectKey(c.consumerClusterID, ns, name))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if len(upstreams) > 0 {
+		for _, upstream := range upstreams {
+			key, err := cache.MetaNamespaceKeyFunc(upstream)
+			if err != nil {
+				runtime.HandleError(err)
+				continue
+			}
+			logger.V(2).Info("queueing Unstructured", "key", key)
+			c.queue.Add(key)
+		}
+		return
+	}
+
+	// no upstream object with provenance, fall back to the namespace mapping
+	if ns != "" && c.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+		key := fmt.Sprintf("%s/%s", c.providerNamespace, mangling.Name(ns, name))
+		logger.V(2).Info("queueing Unstructured", "key", key)
+		c.queue.Add(key)
+		return
+	}
+
 	if ns != "" {
 		sn, err := c.serviceNamespaceInformer.Lister().APIServiceNamespaces(ns).Get(name)
 		if err != nil {
@@ -219,6 +284,10 @@ func (c *controller) enqueueConsumer(logger klog.Logger, obj interface{}) {
 		return
 	}
 
+	if c.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+		upstreamKey = c.providerNamespace + "/" + name
+	}
+
 	logger.V(2).Info("queueing Unstructured", "key", upstreamKey)
 	c.queue.Add(upstreamKey)
 }
@@ -338,10 +407,22 @@ func (c *controller) process(ctx context.Context, key string) error {
 	} else if errors.IsNotFound(err) {
 		logger.V(2).Info("Upstream object disappeared")
 
-		downstream, err := c.consumerDynamicLister.Namespace(ns).Get(name)
+		var downstream *unstructured.Unstructured
+		if ns == c.providerNamespace && c.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+			downstream, err = c.lookupConsumerObject(name) // flattened downstream object
+		} else {
+			if ns == c.providerNamespace && c.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+				ns = "" // cluster-scoped downstream object
+			}
+			downstream, err = c.consumerDynamicLister.Namespace(ns).Get(name)
+		}
 		if err != nil && !errors.IsNotFound(err) {
 			return err
 		} else if err == nil {
+			if downstream.GetAnnotations()[kubebindv1alpha1.ReplacingUpstreamAnnotationKey] != "" && downstream.GetDeletionTimestamp() == nil {
+				logger.V(2).Info("Upstream object is being replaced, keeping downstream finalizer")
+				return nil
+			}
 			if _, err := c.removeDownstreamFinalizer(ctx, downstream); err != nil {
 				return err
 			}
@@ -18,6 +18,7 @@ package status
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -26,12 +27,30 @@ import (
 	"k8s.io/klog/v2"
 
 	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/provenance"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/syncstatus"
 )
 
 type reconciler struct {
+	providerNamespace string
+	consumerClusterID string
+	providerObjects   kubebindv1alpha1.ProviderObjectsPolicy
+
+	// statusSubresource is true if the downstream objects have a status subresource. Otherwise,
+	// status is written together with the rest of the object.
+	statusSubresource bool
+
+	clusterScopedIsolation kubebindv1alpha1.Isolation
+	namespaceIsolation     kubebindv1alpha1.NamespaceIsolation
+
 	getServiceNamespace func(upstreamNamespace string) (*kubebindv1alpha1.APIServiceNamespace, error)
 
 	getConsumerObject          func(ns, name string) (*unstructured.Unstructured, error)
+	lookupConsumerObject       func(upstreamName string) (*unstructured.Unstructured, error)
+	createConsumerObject       func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	updateConsumerObject       func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	patchConsumerObject        func(ctx context.Context, ns, name string, patch []byte) (*unstructured.Unstructured, error)
 	updateConsumerObjectStatus func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
 
 	deleteProviderObject func(ctx context.Context, ns, name string) error
@@ -41,8 +60,26 @@ type reconciler struct {
 func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructured) error {
 	logger := klog.FromContext(ctx)
 
-	ns := obj.GetNamespace()
-	if ns != "" {
+	ns, name := obj.GetNamespace(), obj.GetName()
+	if downstreamNs, downstreamName, ok := provenance.Consumer(obj, r.consumerClusterID); ok {
+		// continue with the downstream object the upstream object was created from
+		ns, name = downstreamNs, downstreamName
+	} else if ns == r.providerNamespace && r.clusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced {
+		// continue with cluster-scoped downstream object
+		ns = ""
+	} else if ns == r.providerNamespace && r.namespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace {
+		// continue with flattened downstream object
+		var ok bool
+		if ns, name, ok = mangling.Unmangle(obj.GetName()); !ok {
+			logger.V(3).Info("skipping upstream object without mangled name")
+			return nil
+		}
+		if downstream, err := r.lookupConsumerObject(obj.GetName()); err != nil && !errors.IsNotFound(err) {
+			return err
+		} else if err == nil {
+			name = downstream.GetName() // the mangled name might have been shortened
+		}
+	} else if ns != "" {
 		sn, err := r.getServiceNamespace(ns)
 		if err != nil && !errors.IsNotFound(err) {
 			return err
@@ -62,20 +99,26 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 		ns = sn.Name
 	}
 
-	downstream, err := r.getConsumerObject(ns, obj.GetName())
+	downstream, err := r.getConsumerObject(ns, name)
 	if err != nil && !errors.IsNotFound(err) {
-		logger.Info("failed to get downstream object", "error", err, "downstreamNamespace", ns, "downstreamName", obj.GetName())
+		logger.Info("failed to get downstream object", "error", err, "downstreamNamespace", ns, "downstreamName", name)
 		return err
 	} else if errors.IsNotFound(err) {
+		if r.providerObjects == kubebindv1alpha1.ProviderObjectsPolicySync && provenance.ProviderCreated(obj) {
+			return r.createProviderOwnedObject(ctx, ns, name, obj)
+		}
+
 		// downstream is gone. Delete upstream too. Note that we cannot rely on the spec controller because
 		// due to konnector restart it might have missed the deletion event.
-		logger.Info("Deleting upstream object because downstream is gone", "downstreamNamespace", ns, "downstreamName", obj.GetName())
+		logger.Info("Deleting upstream object because downstream is gone", "downstreamNamespace", ns, "downstreamName", name)
 		if err := r.deleteProviderObject(ctx, obj.GetNamespace(), obj.GetName()); err != nil {
 			return err
 		}
 		return nil
 	}
 
+	// note: this includes the status replicas of the scale subresource, and the label
+	// selector if it lives in the status.
 	orig := downstream
 	downstream = downstream.DeepCopy()
 	status, found, err := unstructured.NestedFieldNoCopy(obj.Object, "status")
@@ -91,12 +134,116 @@ func (r *reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructur
 	} else {
 		unstructured.RemoveNestedField(downstream.Object, "status")
 	}
-	if !reflect.DeepEqual(orig, downstream) {
-		logger.Info("Updating downstream object status", "downstreamNamespace", ns, "downstreamName", obj.GetName())
-		if _, err := r.updateConsumerObjectStatus(ctx, downstream); err != nil {
+	// observedGenerations refer to upstream generations, but the consumer expects downstream ones
+	if err := syncstatus.TranslateObservedGenerations(downstream, orig.GetAnnotations()); err != nil {
+		runtime.HandleError(err)
+		return nil // nothing we can do here
+	}
+	// some conditions are owned by the konnector, not by the service provider
+	for _, conditionType := range []string{kubebindv1alpha1.DownstreamConditionSynced, kubebindv1alpha1.DownstreamConditionUpstreamDeleted} {
+		if err := syncstatus.CopyCondition(orig, downstream, conditionType); err != nil {
+			runtime.HandleError(err)
+			return nil // nothing we can do here
+		}
+	}
+	// The upstream resourceVersion is only recorded together with a status change. Upstream
+	// changes without one, e.g. by the spec syncer itself, are not worth a consumer write
+	// that in turn triggers a spec reconcile.
+	statusChanged := !reflect.DeepEqual(orig, downstream)
+	if _, found := orig.GetAnnotations()[kubebindv1alpha1.UpstreamResourceVersionAnnotationKey]; found && !statusChanged {
+		return nil
+	}
+
+	if !r.statusSubresource {
+		// status is part of the object, hence status and annotation go into a single write
+		annotations := downstream.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[kubebindv1alpha1.UpstreamResourceVersionAnnotationKey] = obj.GetResourceVersion()
+		downstream.SetAnnotations(annotations)
+		logger.Info("Updating downstream object status", "downstreamNamespace", ns, "downstreamName", name)
+		if _, err := r.updateConsumerObject(ctx, downstream); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if statusChanged {
+		logger.Info("Updating downstream object status", "downstreamNamespace", ns, "downstreamName", name)
+		if downstream, err = r.updateConsumerObjectStatus(ctx, downstream); err != nil {
+			return err
+		}
+	}
+
+	// The status subresource drops metadata changes, hence the annotation needs a second
+	// write. Only patch it, not to conflict with spec updates and not to write the whole object.
+	if downstream.GetAnnotations()[kubebindv1alpha1.UpstreamResourceVersionAnnotationKey] != obj.GetResourceVersion() {
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{
+					kubebindv1alpha1.UpstreamResourceVersionAnnotationKey: obj.GetResourceVersion(),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		logger.V(2).Info("Patching upstream resourceVersion annotation on downstream object", "downstreamNamespace", ns, "downstreamName", name)
+		if _, err := r.patchConsumerObject(ctx, downstream.GetNamespace(), downstream.GetName(), patch); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// createProviderOwnedObject creates a read-only downstream object for an upstream object
+// that was created by the service provider. The status follows when the new downstream
+// object is reconciled.
+func (r *reconciler) createProviderOwnedObject(ctx context.Context, ns, name string, obj *unstructured.Unstructured) error {
+	logger := klog.FromContext(ctx)
+
+	downstream := obj.DeepCopy()
+	downstream.SetUID("")
+	downstream.SetResourceVersion("")
+	downstream.SetNamespace(ns)
+	downstream.SetName(name)
+	downstream.SetManagedFields(nil)
+	downstream.SetDeletionTimestamp(nil)
+	downstream.SetDeletionGracePeriodSeconds(nil)
+	downstream.SetOwnerReferences(nil)
+	downstream.SetFinalizers(nil)
+	unstructured.RemoveNestedField(downstream.Object, "status")
+
+	labels := downstream.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[kubebindv1alpha1.OriginLabelKey] = kubebindv1alpha1.OriginProvider
+	downstream.SetLabels(labels)
+
+	logger.Info("Creating provider-owned downstream object", "downstreamNamespace", ns, "downstreamName", name)
+	if _, err := r.createConsumerObject(ctx, downstream); isNamespaceNotFound(err) {
+		// retrying does not help. The APIServiceNamespace goes away with the namespace, and the
+		// upstream objects are requeued when it is created again.
+		logger.Info("Skipping provider-owned downstream object because the namespace does not exist", "downstreamNamespace", ns, "downstreamName", name)
+		return nil
+	} else if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// isNamespaceNotFound returns true if err is a NotFound error for the namespace
+// of the object, not for the object itself.
+func isNamespaceNotFound(err error) bool {
+	if !errors.IsNotFound(err) {
+		return false
+	}
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return false
+	}
+	return status.Status().Details.Kind == "namespaces"
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+type fakeConsumer struct {
+	downstream *unstructured.Unstructured
+	createErr  error
+
+	creates        []*unstructured.Unstructured
+	updates        []*unstructured.Unstructured
+	statusUpdates  []*unstructured.Unstructured
+	patches        []string
+	upstreamDelete int
+}
+
+func newTestReconciler(c *fakeConsumer) *reconciler {
+	return &reconciler{
+		providerNamespace: "kube-bind-abc",
+		consumerClusterID: "cluster",
+		statusSubresource: true,
+		getServiceNamespace: func(upstreamNamespace string) (*kubebindv1alpha1.APIServiceNamespace, error) {
+			sn := &kubebindv1alpha1.APIServiceNamespace{}
+			sn.Name = "default"
+			sn.Namespace = "kube-bind-abc"
+			sn.Status.Namespace = upstreamNamespace
+			return sn, nil
+		},
+		getConsumerObject: func(ns, name string) (*unstructured.Unstructured, error) {
+			if c.downstream == nil {
+				return nil, apierrors.NewNotFound(schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}, name)
+			}
+			return c.downstream.DeepCopy(), nil
+		},
+		createConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			c.creates = append(c.creates, obj)
+			return obj, c.createErr
+		},
+		updateConsumerObject: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			c.updates = append(c.updates, obj)
+			return obj, nil
+		},
+		patchConsumerObject: func(ctx context.Context, ns, name string, patch []byte) (*unstructured.Unstructured, error) {
+			c.patches = append(c.patches, string(patch))
+			return c.downstream, nil
+		},
+		updateConsumerObjectStatus: func(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			c.statusUpdates = append(c.statusUpdates, obj)
+			return obj, nil
+		},
+		deleteProviderObject: func(ctx context.Context, ns, name string) error {
+			c.upstreamDelete++
+			return nil
+		},
+	}
+}
+
+func newTestObject(ns, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "mangodb.com/v1alpha1",
+		"kind":       "MangoDB",
+		"spec":       map[string]interface{}{"tier": "Dedicated"},
+		"status":     map[string]interface{}{"phase": "Running"},
+	}}
+	obj.SetNamespace(ns)
+	obj.SetName(name)
+	obj.SetUID("uid")
+	obj.SetResourceVersion("42")
+	return obj
+}
+
+func TestReconcileMissingDownstream(t *testing.T) {
+	consumerCreated := func(obj *unstructured.Unstructured) *unstructured.Unstructured {
+		obj.SetLabels(map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginConsumer})
+		return obj
+	}
+
+	tests := []struct {
+		name            string
+		providerObjects kubebindv1alpha1.ProviderObjectsPolicy
+		upstream        *unstructured.Unstructured
+		createErr       error
+		wantCreated     bool
+		wantDeleted     bool
+	}{
+		{
+			name:            "creates provider-owned object",
+			providerObjects: kubebindv1alpha1.ProviderObjectsPolicySync,
+			upstream:        newTestObject("kube-bind-abc-default", "foo"),
+			wantCreated:     true,
+		},
+		{
+			name:            "ignores existing provider-owned object",
+			providerObjects: kubebindv1alpha1.ProviderObjectsPolicySync,
+			upstream:        newTestObject("kube-bind-abc-default", "foo"),
+			createErr:       apierrors.NewAlreadyExists(schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}, "foo"),
+			wantCreated:     true,
+		},
+		{
+			name:            "skips provider-owned object in missing namespace",
+			providerObjects: kubebindv1alpha1.ProviderObjectsPolicySync,
+			upstream:        newTestObject("kube-bind-abc-default", "foo"),
+			createErr:       apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "default"),
+			wantCreated:     true,
+		},
+		{
+			name:            "deletes consumer-created upstream object",
+			providerObjects: kubebindv1alpha1.ProviderObjectsPolicySync,
+			upstream:        consumerCreated(newTestObject("kube-bind-abc-default", "foo")),
+			wantDeleted:     true,
+		},
+		{
+			name:            "deletes provider-created upstream object without sync",
+			providerObjects: kubebindv1alpha1.ProviderObjectsPolicyNone,
+			upstream:        newTestObject("kube-bind-abc-default", "foo"),
+			wantDeleted:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeConsumer{createErr: tt.createErr}
+			r := newTestReconciler(c)
+			r.providerObjects = tt.providerObjects
+
+			require.NoError(t, r.reconcile(context.Background(), tt.upstream))
+
+			require.Equal(t, tt.wantDeleted, c.upstreamDelete > 0)
+			if !tt.wantCreated {
+				require.Empty(t, c.creates)
+				return
+			}
+			require.Len(t, c.creates, 1)
+			created := c.creates[0]
+			require.Equal(t, "default", created.GetNamespace())
+			require.Equal(t, "foo", created.GetName())
+			require.Empty(t, created.GetUID())
+			require.Empty(t, created.GetResourceVersion())
+			require.Equal(t, kubebindv1alpha1.OriginProvider, created.GetLabels()[kubebindv1alpha1.OriginLabelKey])
+			_, found := created.Object["status"]
+			require.False(t, found, "status should follow when the downstream object is reconciled")
+		})
+	}
+}
+
+func TestReconcileMissingDownstreamRetries(t *testing.T) {
+	c := &fakeConsumer{createErr: apierrors.NewNotFound(schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}, "foo")}
+	r := newTestReconciler(c)
+	r.providerObjects = kubebindv1alpha1.ProviderObjectsPolicySync
+
+	require.Error(t, r.reconcile(context.Background(), newTestObject("kube-bind-abc-default", "foo")), "only a missing namespace should be skipped")
+}
+
+func TestReconcileUpstreamResourceVersion(t *testing.T) {
+	withStatus := func(obj *unstructured.Unstructured, phase string) *unstructured.Unstructured {
+		obj.Object["status"] = map[string]interface{}{"phase": phase}
+		return obj
+	}
+	withResourceVersion := func(obj *unstructured.Unstructured, rv string) *unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{kubebindv1alpha1.UpstreamResourceVersionAnnotationKey: rv})
+		return obj
+	}
+
+	tests := []struct {
+		name              string
+		statusSubresource bool
+		downstream        *unstructured.Unstructured
+		wantUpdates       int
+		wantStatusUpdates int
+		wantPatches       []string
+	}{
+		{
+			name:              "status subresource, status changed",
+			statusSubresource: true,
+			downstream:        withResourceVersion(withStatus(newTestObject("default", "foo"), "Pending"), "41"),
+			wantStatusUpdates: 1,
+			wantPatches:       []string{`{"metadata":{"annotations":{"kube-bind.io/upstream-resource-version":"42"}}}`},
+		},
+		{
+			name:              "status subresource, status unchanged",
+			statusSubresource: true,
+			downstream:        withResourceVersion(newTestObject("default", "foo"), "41"),
+		},
+		{
+			name:              "status subresource, status unchanged, no annotation yet",
+			statusSubresource: true,
+			downstream:        newTestObject("default", "foo"),
+			wantPatches:       []string{`{"metadata":{"annotations":{"kube-bind.io/upstream-resource-version":"42"}}}`},
+		},
+		{
+			name:        "no status subresource, status changed",
+			downstream:  withResourceVersion(withStatus(newTestObject("default", "foo"), "Pending"), "41"),
+			wantUpdates: 1,
+		},
+		{
+			name:       "no status subresource, status unchanged",
+			downstream: withResourceVersion(newTestObject("default", "foo"), "41"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeConsumer{downstream: tt.downstream}
+			r := newTestReconciler(c)
+			r.statusSubresource = tt.statusSubresource
+
+			require.NoError(t, r.reconcile(context.Background(), newTestObject("kube-bind-abc-default", "foo")))
+
+			require.Len(t, c.updates, tt.wantUpdates)
+			for _, obj := range c.updates {
+				require.Equal(t, "42", obj.GetAnnotations()[kubebindv1alpha1.UpstreamResourceVersionAnnotationKey], "annotation should be written together with the status")
+				require.Equal(t, "Running", obj.Object["status"].(map[string]interface{})["phase"])
+			}
+			require.Len(t, c.statusUpdates, tt.wantStatusUpdates)
+			require.Equal(t, tt.wantPatches, c.patches)
+			require.Zero(t, c.upstreamDelete)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncstatus
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GetCondition returns the condition of the given type in status.conditions of
+// the given object, or nil if it does not exist.
+func GetCondition(obj *unstructured.Unstructured, conditionType string) (*metav1.Condition, error) {
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok || m["type"] != conditionType {
+			continue
+		}
+		var cond metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &cond); err != nil {
+			return nil, err
+		}
+		return &cond, nil
+	}
+	return nil, nil
+}
+
+// SetCondition sets the given condition in status.conditions of the given object,
+// replacing an existing condition of the same type. The lastTransitionTime is kept
+// if the status did not change. It returns true if the object was changed.
+func SetCondition(obj *unstructured.Unstructured, cond metav1.Condition) (bool, error) {
+	existing, err := GetCondition(obj, cond.Type)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && existing.Status == cond.Status {
+		cond.LastTransitionTime = existing.LastTransitionTime
+	} else if cond.LastTransitionTime.IsZero() {
+		cond.LastTransitionTime = metav1.Now()
+	}
+	if existing != nil && reflect.DeepEqual(*existing, cond) {
+		return false, nil
+	}
+
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&cond)
+	if err != nil {
+		return false, err
+	}
+	if err := setRawCondition(obj, cond.Type, m); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CopyCondition copies the condition of the given type from src to dst, replacing
+// an existing condition of the same type in dst. If src has no such condition, it
+// is removed from dst.
+func CopyCondition(src, dst *unstructured.Unstructured, conditionType string) error {
+	conditions, _, err := unstructured.NestedSlice(src.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionType {
+			return setRawCondition(dst, conditionType, m)
+		}
+	}
+	return setRawCondition(dst, conditionType, nil)
+}
+
+// setRawCondition replaces the condition of the given type in status.conditions by m,
+// or removes it if m is nil.
+func setRawCondition(obj *unstructured.Unstructured, conditionType string, m map[string]interface{}) error {
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+
+	ret := make([]interface{}, 0, len(conditions)+1)
+	found := false
+	for _, c := range conditions {
+		if existing, ok := c.(map[string]interface{}); ok && existing["type"] == conditionType {
+			found = true
+			if m != nil {
+				ret = append(ret, m)
+			}
+			continue
+		}
+		ret = append(ret, c)
+	}
+	if !found && m != nil {
+		ret = append(ret, m)
+	}
+
+	if len(ret) == 0 {
+		if len(conditions) > 0 {
+			unstructured.RemoveNestedField(obj.Object, "status", "conditions")
+		}
+		return nil
+	}
+	return unstructured.SetNestedSlice(obj.Object, ret, "status", "conditions")
+}
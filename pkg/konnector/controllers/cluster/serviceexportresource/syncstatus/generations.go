@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncstatus
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+// maxGenerationMappings is the number of upstream generations remembered
+// per downstream object.
+const maxGenerationMappings = 10
+
+// generationMapping maps upstream generations to the downstream generation that
+// produced them.
+type generationMapping struct {
+	upstream, downstream int64
+}
+
+// RecordGeneration records in the given annotations that the upstream generation
+// was produced by the downstream generation. Only the latest mappings are kept.
+func RecordGeneration(annotations map[string]string, upstreamGeneration, downstreamGeneration int64) {
+	mappings := parseGenerationMappings(annotations[kubebindv1alpha1.UpstreamGenerationsAnnotationKey])
+
+	found := false
+	for i := range mappings {
+		if mappings[i].upstream == upstreamGeneration {
+			mappings[i].downstream = downstreamGeneration
+			found = true
+			break
+		}
+	}
+	if !found {
+		mappings = append(mappings, generationMapping{upstream: upstreamGeneration, downstream: downstreamGeneration})
+	}
+
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].upstream > mappings[j].upstream
+	})
+	if len(mappings) > maxGenerationMappings {
+		mappings = mappings[:maxGenerationMappings]
+	}
+
+	parts := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		parts = append(parts, fmt.Sprintf("%d=%d", m.upstream, m.downstream))
+	}
+	annotations[kubebindv1alpha1.UpstreamGenerationsAnnotationKey] = strings.Join(parts, ",")
+}
+
+// TranslateGeneration maps an upstream generation to the downstream generation
+// that produced it, using the mappings in the given annotations. Upstream
+// generations not caused by the konnector belong to the downstream generation of
+// the latest preceding sync. Upstream generations older than all known mappings
+// are mapped to a downstream generation older than all known ones. The second
+// return value is false if there are no mappings at all.
+func TranslateGeneration(annotations map[string]string, upstreamGeneration int64) (int64, bool) {
+	mappings := parseGenerationMappings(annotations[kubebindv1alpha1.UpstreamGenerationsAnnotationKey])
+	if len(mappings) == 0 {
+		return 0, false
+	}
+
+	// mappings are sorted by descending upstream generation
+	for _, m := range mappings {
+		if m.upstream <= upstreamGeneration {
+			return m.downstream, true
+		}
+	}
+
+	oldest := mappings[len(mappings)-1].downstream - 1
+	if oldest < 0 {
+		oldest = 0
+	}
+	return oldest, true
+}
+
+// TranslateObservedGenerations rewrites status.observedGeneration and the
+// observedGeneration of all conditions in status.conditions of the given object
+// from upstream to downstream generations, using the mappings in the given
+// annotations. If there are no mappings, the object is left untouched.
+func TranslateObservedGenerations(obj *unstructured.Unstructured, annotations map[string]string) error {
+	if len(parseGenerationMappings(annotations[kubebindv1alpha1.UpstreamGenerationsAnnotationKey])) == 0 {
+		return nil
+	}
+
+	if g, found, err := nestedInt64(obj.Object, "status", "observedGeneration"); err != nil {
+		return err
+	} else if found {
+		translated, _ := TranslateGeneration(annotations, g)
+		if err := unstructured.SetNestedField(obj.Object, translated, "status", "observedGeneration"); err != nil {
+			return err
+		}
+	}
+
+	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil || !found {
+		return err
+	}
+	for i, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		g, found, err := nestedInt64(m, "observedGeneration")
+		if err != nil {
+			return err
+		} else if !found {
+			continue
+		}
+		translated, _ := TranslateGeneration(annotations, g)
+		m["observedGeneration"] = translated
+		conditions[i] = m
+	}
+	return unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+}
+
+func parseGenerationMappings(value string) []generationMapping {
+	if value == "" {
+		return nil
+	}
+
+	var mappings []generationMapping
+	for _, part := range strings.Split(value, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		upstream, err := strconv.ParseInt(kv[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		downstream, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		mappings = append(mappings, generationMapping{upstream: upstream, downstream: downstream})
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].upstream > mappings[j].upstream
+	})
+	return mappings
+}
+
+// nestedInt64 is like unstructured.NestedInt64, but also accepts float64 values
+// as produced by generic JSON decoding.
+func nestedInt64(obj map[string]interface{}, fields ...string) (int64, bool, error) {
+	val, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if err != nil || !found {
+		return 0, found, err
+	}
+	switch v := val.(type) {
+	case int64:
+		return v, true, nil
+	case float64:
+		return int64(v), true, nil
+	default:
+		return 0, false, fmt.Errorf("%v accessor error: %v is of the type %T, expected int64", strings.Join(fields, "."), val, val)
+	}
+}
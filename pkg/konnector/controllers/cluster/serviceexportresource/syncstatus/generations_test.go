@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncstatus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+const key = kubebindv1alpha1.UpstreamGenerationsAnnotationKey
+
+func TestRecordGeneration(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   string
+		upstream   int64
+		downstream int64
+		want       string
+	}{
+		{
+			name:       "first",
+			upstream:   1,
+			downstream: 1,
+			want:       "1=1",
+		},
+		{
+			name:       "newest first",
+			existing:   "2=1,1=1",
+			upstream:   3,
+			downstream: 2,
+			want:       "3=2,2=1,1=1",
+		},
+		{
+			name:       "out of order",
+			existing:   "5=3,1=1",
+			upstream:   3,
+			downstream: 2,
+			want:       "5=3,3=2,1=1",
+		},
+		{
+			name:       "update existing",
+			existing:   "3=2,1=1",
+			upstream:   3,
+			downstream: 3,
+			want:       "3=3,1=1",
+		},
+		{
+			name:       "capped, oldest dropped",
+			existing:   "10=10,9=9,8=8,7=7,6=6,5=5,4=4,3=3,2=2,1=1",
+			upstream:   11,
+			downstream: 11,
+			want:       "11=11,10=10,9=9,8=8,7=7,6=6,5=5,4=4,3=3,2=2",
+		},
+		{
+			name:       "capped, older than all kept",
+			existing:   "11=11,10=10,9=9,8=8,7=7,6=6,5=5,4=4,3=3,2=2",
+			upstream:   1,
+			downstream: 1,
+			want:       "11=11,10=10,9=9,8=8,7=7,6=6,5=5,4=4,3=3,2=2",
+		},
+		{
+			name:       "malformed entries dropped",
+			existing:   "2=1,foo,x=1,3=y,",
+			upstream:   3,
+			downstream: 2,
+			want:       "3=2,2=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tt.existing != "" {
+				annotations[key] = tt.existing
+			}
+			RecordGeneration(annotations, tt.upstream, tt.downstream)
+			require.Equal(t, tt.want, annotations[key])
+		})
+	}
+}
+
+func TestTranslateGeneration(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings string
+		upstream int64
+		want     int64
+		wantOK   bool
+	}{
+		{name: "no mappings", upstream: 3},
+		{name: "only malformed mappings", mappings: "foo,1=x", upstream: 3},
+		{name: "exact", mappings: "5=3,2=1", upstream: 5, want: 3, wantOK: true},
+		{name: "provider-side change after sync", mappings: "5=3,2=1", upstream: 7, want: 3, wantOK: true},
+		{name: "between syncs", mappings: "5=3,2=1", upstream: 4, want: 1, wantOK: true},
+		{name: "older than all mappings", mappings: "5=3,2=2", upstream: 1, want: 1, wantOK: true},
+		{name: "older than all mappings, never negative", mappings: "5=3,2=0", upstream: 1, want: 0, wantOK: true},
+		{name: "unsorted annotation", mappings: "2=1,5=3", upstream: 6, want: 3, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TranslateGeneration(map[string]string{key: tt.mappings}, tt.upstream)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTranslateObservedGenerations(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings string
+		status   map[string]interface{}
+		want     map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name:   "no mappings, untouched",
+			status: map[string]interface{}{"observedGeneration": int64(5)},
+			want:   map[string]interface{}{"observedGeneration": int64(5)},
+		},
+		{
+			name:     "int64",
+			mappings: "5=3,2=1",
+			status:   map[string]interface{}{"observedGeneration": int64(5)},
+			want:     map[string]interface{}{"observedGeneration": int64(3)},
+		},
+		{
+			name:     "float64 from JSON",
+			mappings: "5=3,2=1",
+			status:   map[string]interface{}{"observedGeneration": float64(4)},
+			want:     map[string]interface{}{"observedGeneration": int64(1)},
+		},
+		{
+			name:     "conditions",
+			mappings: "5=3,2=1",
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "observedGeneration": int64(5)},
+					map[string]interface{}{"type": "Synced", "observedGeneration": float64(2)},
+					map[string]interface{}{"type": "Other"},
+				},
+			},
+			want: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "observedGeneration": int64(3)},
+					map[string]interface{}{"type": "Synced", "observedGeneration": int64(1)},
+					map[string]interface{}{"type": "Other"},
+				},
+			},
+		},
+		{
+			name:     "no status",
+			mappings: "5=3",
+		},
+		{
+			name:     "wrong type",
+			mappings: "5=3",
+			status:   map[string]interface{}{"observedGeneration": "5"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			if tt.status != nil {
+				obj.Object["status"] = tt.status
+			}
+			err := TranslateObservedGenerations(obj, map[string]string{key: tt.mappings})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			status, _, err := unstructured.NestedMap(obj.Object, "status")
+			require.NoError(t, err)
+			if tt.want == nil {
+				require.Empty(t, status)
+				return
+			}
+			require.Equal(t, tt.want, status)
+		})
+	}
+}
@@ -44,6 +44,7 @@ import (
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/dynamic"
 	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/servicebinding"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 const (
@@ -57,6 +58,7 @@ func New(
 	secretInformer coreinformers.SecretInformer,
 	namespaceInformer coreinformers.NamespaceInformer,
 	crdInformer crdinformers.CustomResourceDefinitionInformer,
+	validators webhook.Registry,
 ) (*Controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -109,6 +111,7 @@ func New(
 					namespaceDynamicInformer,
 					serviceBindingDynamicInformer,
 					crdDynamicInformer,
+					validators,
 				)
 			},
 		},
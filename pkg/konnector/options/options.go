@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 
 	"github.com/spf13/pflag"
 
@@ -39,6 +40,18 @@ type ExtraOptions struct {
 	LeaseLockName      string
 	LeaseLockNamespace string
 	LeaseLockIdentity  string
+
+	WebhookBindAddress   string
+	WebhookCertFile      string
+	WebhookKeyFile       string
+	WebhookCAFile        string
+	WebhookService       string
+	WebhookFailurePolicy string
+
+	// PodNamespace and PodName identify the pod of this replica. The webhook
+	// Service selects the pod of the leader by a label.
+	PodNamespace string
+	PodName      string
 }
 
 type completedOptions struct {
@@ -63,6 +76,11 @@ func NewOptions() *Options {
 			LeaseLockName:      "kube-bind",
 			LeaseLockNamespace: os.Getenv("POD_NAMESPACE"),
 			LeaseLockIdentity:  os.Getenv("POD_NAME"),
+
+			WebhookFailurePolicy: "Ignore",
+
+			PodNamespace: os.Getenv("POD_NAMESPACE"),
+			PodName:      os.Getenv("POD_NAME"),
 		},
 	}
 
@@ -79,6 +97,13 @@ func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.KubeConfigPath, "kubeconfig", options.KubeConfigPath, "Kubeconfig file for the local cluster.")
 	fs.StringVar(&options.LeaseLockName, "lease-name", options.LeaseLockName, "Name of lease lock")
 	fs.StringVar(&options.LeaseLockNamespace, "lease-namespace", options.LeaseLockNamespace, "Name of lease lock namespace")
+
+	fs.StringVar(&options.WebhookBindAddress, "webhook-bind-address", options.WebhookBindAddress, "Address to serve the validating admission webhook for bound resources on, e.g. :9443. The webhook is disabled if empty.")
+	fs.StringVar(&options.WebhookCertFile, "webhook-cert-file", options.WebhookCertFile, "TLS certificate file of the admission webhook.")
+	fs.StringVar(&options.WebhookKeyFile, "webhook-key-file", options.WebhookKeyFile, "TLS key file of the admission webhook.")
+	fs.StringVar(&options.WebhookCAFile, "webhook-ca-file", options.WebhookCAFile, "CA bundle file the API server uses to verify the admission webhook certificate.")
+	fs.StringVar(&options.WebhookService, "webhook-service", options.WebhookService, "Service in the form <namespace>/<name>[:port] the API server calls the admission webhook through.")
+	fs.StringVar(&options.WebhookFailurePolicy, "webhook-failure-policy", options.WebhookFailurePolicy, "Failure policy of the admission webhook, either Ignore or Fail.")
 }
 
 func (options *Options) Complete() (*CompletedOptions, error) {
@@ -95,5 +120,25 @@ func (options *Options) Complete() (*CompletedOptions, error) {
 }
 
 func (options *CompletedOptions) Validate() error {
+	if options.WebhookBindAddress == "" {
+		return nil
+	}
+	if options.WebhookCertFile == "" || options.WebhookKeyFile == "" {
+		return fmt.Errorf("--webhook-cert-file and --webhook-key-file are required with --webhook-bind-address")
+	}
+	if options.WebhookCAFile == "" {
+		return fmt.Errorf("--webhook-ca-file is required with --webhook-bind-address")
+	}
+	if ns, name, ok := strings.Cut(options.WebhookService, "/"); !ok || ns == "" || name == "" {
+		return fmt.Errorf("--webhook-service must be of the form <namespace>/<name>[:port]")
+	}
+	if options.PodNamespace == "" || options.PodName == "" {
+		return fmt.Errorf("POD_NAMESPACE and POD_NAME environment variables are required with --webhook-bind-address")
+	}
+	switch options.WebhookFailurePolicy {
+	case "Ignore", "Fail":
+	default:
+		return fmt.Errorf("--webhook-failure-policy must be Ignore or Fail")
+	}
 	return nil
 }
@@ -25,6 +25,7 @@ import (
 
 	"github.com/kube-bind/kube-bind/deploy/crd"
 	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
 )
 
 type Server struct {
@@ -33,6 +34,11 @@ type Server struct {
 }
 
 func NewServer(config *Config) (*Server, error) {
+	var validators webhook.Registry
+	if config.Webhook != nil {
+		validators = config.Webhook
+	}
+
 	// construct controllers
 	k, err := New(
 		config.ClientConfig,
@@ -40,6 +46,7 @@ func NewServer(config *Config) (*Server, error) {
 		config.KubeInformers.Core().V1().Secrets(), // TODO(sttts): watch individual secrets for security and memory consumption
 		config.KubeInformers.Core().V1().Namespaces(),
 		config.ApiextensionsInformers.Apiextensions().V1().CustomResourceDefinitions(),
+		validators,
 	)
 	if err != nil {
 		return nil, err
@@ -72,6 +79,15 @@ func (s *Server) OptionallyStartInformers(ctx context.Context) {
 	)
 }
 
+// OptionallyStartWebhook serves the admission webhook if enabled. It is started on all
+// replicas, but only the leader registers validators and is selected by the webhook Service.
+func (s *Server) OptionallyStartWebhook(ctx context.Context) error {
+	if s.Config.Webhook == nil {
+		return nil
+	}
+	return s.Config.Webhook.Start(ctx)
+}
+
 func (s *Server) Run(ctx context.Context) error {
 	// install/upgrade CRDs
 	if err := crd.Create(ctx,
@@ -81,6 +97,10 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 
+	if s.Config.Webhook != nil {
+		go s.Config.Webhook.Lead(ctx)
+	}
+
 	s.Controller.Start(ctx, 2)
 	return nil
 }
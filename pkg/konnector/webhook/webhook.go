@@ -0,0 +1,399 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ConfigurationName is the name of the ValidatingWebhookConfiguration maintained by the konnector.
+	ConfigurationName = "kube-bind-konnector"
+
+	// LeaderLabelKey is set on the pod of the leading konnector. The webhook Service selects
+	// it, such that admission requests reach the replica that has registered the validators.
+	LeaderLabelKey = "kube-bind.io/konnector-leader"
+
+	webhookName = "validate.konnector.kube-bind.io"
+	path        = "/validate"
+)
+
+// Validator validates a consumer object against the service provider. obj is nil
+// for deletions, old is nil for creations.
+type Validator func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error
+
+// Registry collects the validators of the bound resources.
+type Registry interface {
+	// Register registers a validator for the given resource. The returned function
+	// unregisters it again.
+	Register(gr schema.GroupResource, validator Validator) func()
+}
+
+// Options configure the webhook server.
+type Options struct {
+	BindAddress   string
+	CertFile      string
+	KeyFile       string
+	CABundle      []byte
+	Service       admissionregistrationv1.ServiceReference
+	FailurePolicy admissionregistrationv1.FailurePolicyType
+
+	// PodNamespace and PodName identify the pod of this replica, labeled with
+	// LeaderLabelKey while leading.
+	PodNamespace string
+	PodName      string
+}
+
+// Server serves a validating admission webhook for the bound resources, forwarding
+// requests as server-side dry-run to the service provider. It maintains a
+// ValidatingWebhookConfiguration matching the registered resources.
+//
+// Validators are only registered by the leading konnector. Hence, only the pod of the
+// leader is labeled with LeaderLabelKey, and selected by the webhook Service. Other
+// replicas would admit all requests.
+type Server struct {
+	options    Options
+	kubeClient kubernetesclient.Interface
+
+	lock       sync.RWMutex
+	validators map[schema.GroupResource]*registration
+
+	changed chan struct{}
+}
+
+type registration struct {
+	validator Validator
+}
+
+// NewServer returns a webhook server.
+func NewServer(options Options, kubeClient kubernetesclient.Interface) *Server {
+	return &Server{
+		options:    options,
+		kubeClient: kubeClient,
+		validators: map[schema.GroupResource]*registration{},
+		changed:    make(chan struct{}, 1),
+	}
+}
+
+// Register implements Registry.
+func (s *Server) Register(gr schema.GroupResource, validator Validator) func() {
+	reg := &registration{validator: validator}
+
+	s.lock.Lock()
+	s.validators[gr] = reg
+	s.lock.Unlock()
+	s.triggerSync()
+
+	return func() {
+		s.lock.Lock()
+		if s.validators[gr] == reg {
+			delete(s.validators, gr)
+		}
+		s.lock.Unlock()
+		s.triggerSync()
+	}
+}
+
+func (s *Server) triggerSync() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Start serves the webhook and maintains the ValidatingWebhookConfiguration until
+// ctx is done.
+func (s *Server) Start(ctx context.Context) error {
+	logger := klog.FromContext(ctx).WithValues("component", "webhook")
+
+	// a restarted leader might still be labeled
+	if err := s.labelPod(ctx, false); err != nil {
+		return fmt.Errorf("failed to remove leader label from pod %s/%s: %w", s.options.PodNamespace, s.options.PodName, err)
+	}
+
+	listener, err := net.Listen("tcp", s.options.BindAddress)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, s.serveValidate)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close() // nolint:errcheck
+	}()
+	go func() {
+		logger.Info("Serving admission webhook", "address", s.options.BindAddress)
+		if err := server.ServeTLS(listener, s.options.CertFile, s.options.KeyFile); err != nil && err != http.ErrServerClosed {
+			runtime.HandleError(err)
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.changed:
+			}
+			if err := wait.ExponentialBackoffWithContext(ctx, wait.Backoff{Duration: time.Second, Factor: 2, Steps: 10, Cap: time.Minute}, func() (bool, error) {
+				if err := s.syncConfiguration(ctx); err != nil {
+					logger.Error(err, "failed to sync ValidatingWebhookConfiguration")
+					return false, nil
+				}
+				return true, nil
+			}); err != nil {
+				runtime.HandleError(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Lead labels the pod of this replica with LeaderLabelKey until ctx is done, such that
+// the webhook Service routes admission requests to it.
+func (s *Server) Lead(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithValues("component", "webhook")
+
+	if err := wait.PollImmediateInfiniteWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		if err := s.labelPod(ctx, true); err != nil {
+			logger.Error(err, "failed to add leader label to pod", "namespace", s.options.PodNamespace, "name", s.options.PodName)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return // not leading anymore
+	}
+	logger.Info("Routing admission requests to this replica", "namespace", s.options.PodNamespace, "name", s.options.PodName)
+
+	<-ctx.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.labelPod(ctx, false); err != nil {
+		logger.Error(err, "failed to remove leader label from pod", "namespace", s.options.PodNamespace, "name", s.options.PodName)
+	}
+}
+
+// labelPod adds or removes LeaderLabelKey on the pod of this replica.
+func (s *Server) labelPod(ctx context.Context, leading bool) error {
+	var value interface{} // nil removes the label
+	if leading {
+		value = "true"
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				LeaderLabelKey: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.kubeClient.CoreV1().Pods(s.options.PodNamespace).Patch(ctx, s.options.PodName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// syncConfiguration creates, updates or deletes the ValidatingWebhookConfiguration
+// according to the registered resources.
+func (s *Server) syncConfiguration(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+
+	s.lock.RLock()
+	grs := make([]schema.GroupResource, 0, len(s.validators))
+	for gr := range s.validators {
+		grs = append(grs, gr)
+	}
+	s.lock.RUnlock()
+	sort.Slice(grs, func(i, j int) bool {
+		return grs[i].String() < grs[j].String()
+	})
+
+	client := s.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	existing, err := client.Get(ctx, ConfigurationName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if len(grs) == 0 {
+		if existing == nil || errors.IsNotFound(err) {
+			return nil
+		}
+		logger.Info("Deleting ValidatingWebhookConfiguration", "name", ConfigurationName)
+		if err := client.Delete(ctx, ConfigurationName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(grs))
+	for _, gr := range grs {
+		rules = append(rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{gr.Group},
+				APIVersions: []string{"*"},
+				Resources:   []string{gr.Resource},
+			},
+		})
+	}
+	service := s.options.Service
+	service.Path = &[]string{path}[0]
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	failurePolicy := s.options.FailurePolicy
+	timeout := int32(10)
+	webhooks := []admissionregistrationv1.ValidatingWebhook{{
+		Name: webhookName,
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service:  &service,
+			CABundle: s.options.CABundle,
+		},
+		Rules:                   rules,
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1"},
+		TimeoutSeconds:          &timeout,
+	}}
+
+	if errors.IsNotFound(err) {
+		logger.Info("Creating ValidatingWebhookConfiguration", "name", ConfigurationName, "resources", len(grs))
+		_, err := client.Create(ctx, &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ConfigurationName,
+			},
+			Webhooks: webhooks,
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Webhooks, webhooks) {
+		return nil
+	}
+	logger.Info("Updating ValidatingWebhookConfiguration", "name", ConfigurationName, "resources", len(grs))
+	existing = existing.DeepCopy()
+	existing.Webhooks = webhooks
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *Server) serveValidate(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context()).WithValues("component", "webhook")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "missing request", http.StatusBadRequest)
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+	if err := s.validate(klog.NewContext(r.Context(), logger), review.Request); err != nil {
+		response.Allowed = false
+		if status, ok := err.(errors.APIStatus); ok {
+			result := status.Status()
+			response.Result = &result
+		} else {
+			response.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+				Reason:  metav1.StatusReasonInvalid,
+				Code:    http.StatusUnprocessableEntity,
+			}
+		}
+	}
+
+	review.Request = nil
+	review.Response = response
+	bs, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bs) // nolint:errcheck
+}
+
+func (s *Server) validate(ctx context.Context, req *admissionv1.AdmissionRequest) error {
+	gr := schema.GroupResource{Group: req.Resource.Group, Resource: req.Resource.Resource}
+	if req.SubResource != "" {
+		return nil
+	}
+
+	s.lock.RLock()
+	reg, found := s.validators[gr]
+	s.lock.RUnlock()
+	if !found {
+		return nil // not leading, or not bound (anymore)
+	}
+
+	var obj, old *unstructured.Unstructured
+	if len(req.Object.Raw) > 0 {
+		obj = &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+			return fmt.Errorf("failed to decode object: %w", err)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(req.Namespace)
+		}
+	}
+	if len(req.OldObject.Raw) > 0 {
+		old = &unstructured.Unstructured{}
+		if err := old.UnmarshalJSON(req.OldObject.Raw); err != nil {
+			return fmt.Errorf("failed to decode old object: %w", err)
+		}
+	}
+
+	klog.FromContext(ctx).V(2).Info("validating against service provider", "resource", gr, "operation", req.Operation, "namespace", req.Namespace, "name", req.Name)
+	return reg.validator(ctx, req.Operation, obj, old)
+}
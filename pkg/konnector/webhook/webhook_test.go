@@ -0,0 +1,368 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var mangodbs = schema.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+
+const (
+	eventuallyTimeout = 10 * time.Second
+	eventuallyTick    = 10 * time.Millisecond
+)
+
+func newTestServer(objs ...runtime.Object) (*Server, *kubefake.Clientset) {
+	client := kubefake.NewSimpleClientset(objs...)
+	return NewServer(Options{
+		Service:       admissionregistrationv1.ServiceReference{Namespace: "kube-bind", Name: "konnector-webhook"},
+		FailurePolicy: admissionregistrationv1.Fail,
+		PodNamespace:  "kube-bind",
+		PodName:       "konnector-0",
+	}, client), client
+}
+
+func allow(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+	return nil
+}
+
+func TestSyncConfiguration(t *testing.T) {
+	tests := []struct {
+		name        string
+		registered  []schema.GroupResource
+		existing    func(s *Server) *admissionregistrationv1.ValidatingWebhookConfiguration
+		wantVerbs   []string
+		wantGroups  []string
+		wantDeleted bool
+	}{
+		{
+			name:      "nothing registered, nothing to do",
+			wantVerbs: []string{"get"},
+		},
+		{
+			name: "nothing registered deletes the configuration",
+			existing: func(s *Server) *admissionregistrationv1.ValidatingWebhookConfiguration {
+				return &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName}}
+			},
+			wantVerbs:   []string{"get", "delete"},
+			wantDeleted: true,
+		},
+		{
+			name:       "creates the configuration",
+			registered: []schema.GroupResource{mangodbs, {Group: "backup.mangodb.com", Resource: "backups"}},
+			wantVerbs:  []string{"get", "create"},
+			wantGroups: []string{"backup.mangodb.com", "mangodb.com"},
+		},
+		{
+			name:       "keeps an up-to-date configuration",
+			registered: []schema.GroupResource{mangodbs},
+			existing: func(s *Server) *admissionregistrationv1.ValidatingWebhookConfiguration {
+				// the configuration of another replica with the same resources
+				other, client := newTestServer()
+				other.Register(mangodbs, allow)
+				require.NoError(t, other.syncConfiguration(context.Background()))
+				existing, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), ConfigurationName, metav1.GetOptions{})
+				require.NoError(t, err)
+				return existing
+			},
+			wantVerbs:  []string{"get"},
+			wantGroups: []string{"mangodb.com"},
+		},
+		{
+			name:       "updates an outdated configuration",
+			registered: []schema.GroupResource{mangodbs},
+			existing: func(s *Server) *admissionregistrationv1.ValidatingWebhookConfiguration {
+				return &admissionregistrationv1.ValidatingWebhookConfiguration{
+					ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName},
+					Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: webhookName}},
+				}
+			},
+			wantVerbs:  []string{"get", "update"},
+			wantGroups: []string{"mangodb.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestServer()
+			for _, gr := range tt.registered {
+				s.Register(gr, allow)
+			}
+			if tt.existing != nil {
+				existing := tt.existing(s)
+				require.NoError(t, client.Tracker().Add(existing))
+			}
+			client.ClearActions()
+
+			require.NoError(t, s.syncConfiguration(context.Background()))
+
+			var verbs []string
+			for _, action := range client.Actions() {
+				verbs = append(verbs, action.GetVerb())
+			}
+			require.Equal(t, tt.wantVerbs, verbs)
+
+			config, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), ConfigurationName, metav1.GetOptions{})
+			if tt.wantGroups == nil {
+				require.True(t, apierrors.IsNotFound(err), "expected no configuration, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, config.Webhooks, 1)
+			webhook := config.Webhooks[0]
+			require.Equal(t, "konnector-webhook", webhook.ClientConfig.Service.Name)
+			require.Equal(t, path, *webhook.ClientConfig.Service.Path)
+			require.Equal(t, admissionregistrationv1.Fail, *webhook.FailurePolicy)
+			var groups []string
+			for _, rule := range webhook.Rules {
+				groups = append(groups, rule.APIGroups...)
+				require.Equal(t, []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}, rule.Operations)
+			}
+			require.Equal(t, tt.wantGroups, groups)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s, _ := newTestServer()
+
+	unregister := s.Register(mangodbs, allow)
+	require.Len(t, s.validators, 1)
+
+	// a newer registration survives the unregistration of the older one
+	unregisterNewer := s.Register(mangodbs, allow)
+	unregister()
+	require.Len(t, s.validators, 1)
+
+	unregisterNewer()
+	require.Empty(t, s.validators)
+}
+
+func TestLead(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "konnector-0", Labels: map[string]string{"app": "konnector"}}}
+	s, client := newTestServer(pod)
+
+	labels := func() map[string]string {
+		pod, err := client.CoreV1().Pods("kube-bind").Get(context.Background(), "konnector-0", metav1.GetOptions{})
+		require.NoError(t, err)
+		return pod.Labels
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Lead(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return labels()[LeaderLabelKey] == "true"
+	}, eventuallyTimeout, eventuallyTick)
+
+	cancel()
+	<-done
+	require.Equal(t, map[string]string{"app": "konnector"}, labels(), "leader label must be removed when not leading anymore")
+}
+
+func TestLeadRetries(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "konnector-0"}}
+	s, client := newTestServer(pod)
+
+	failures := 1
+	client.PrependReactor("patch", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, apierrors.NewServiceUnavailable("not now")
+		}
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Lead(ctx)
+
+	require.Eventually(t, func() bool {
+		pod, err := client.CoreV1().Pods("kube-bind").Get(context.Background(), "konnector-0", metav1.GetOptions{})
+		require.NoError(t, err)
+		return pod.Labels[LeaderLabelKey] == "true"
+	}, eventuallyTimeout, eventuallyTick)
+}
+
+func TestServeValidate(t *testing.T) {
+	gk := schema.GroupKind{Group: "mangodb.com", Kind: "MangoDB"}
+
+	object := []byte(`{"apiVersion":"mangodb.com/v1alpha1","kind":"MangoDB","metadata":{"name":"foo"},"spec":{"tier":"Dedicated"}}`)
+	oldObject := []byte(`{"apiVersion":"mangodb.com/v1alpha1","kind":"MangoDB","metadata":{"name":"foo","namespace":"default"},"spec":{"tier":"Shared"}}`)
+
+	review := func(resource schema.GroupResource, subResource string, operation admissionv1.Operation, obj, old []byte) []byte {
+		bs, err := json.Marshal(admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:         "uid",
+				Resource:    metav1.GroupVersionResource{Group: resource.Group, Version: "v1alpha1", Resource: resource.Resource},
+				SubResource: subResource,
+				Namespace:   "default",
+				Name:        "foo",
+				Operation:   operation,
+				Object:      runtime.RawExtension{Raw: obj},
+				OldObject:   runtime.RawExtension{Raw: old},
+			},
+		})
+		require.NoError(t, err)
+		return bs
+	}
+
+	tests := []struct {
+		name      string
+		body      []byte
+		validator Validator
+
+		wantCode    int
+		wantAllowed bool
+		wantCalled  bool
+		wantResult  *metav1.Status
+	}{
+		{
+			name:     "invalid body",
+			body:     []byte("{"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing request",
+			body:     []byte(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "not leading admits everything",
+			body:        review(mangodbs, "", admissionv1.Create, object, nil),
+			wantCode:    http.StatusOK,
+			wantAllowed: true,
+		},
+		{
+			name: "other resource is admitted",
+			body: review(schema.GroupResource{Group: "example.com", Resource: "others"}, "", admissionv1.Create, object, nil),
+			validator: func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+				return errors.New("never")
+			},
+			wantCode:    http.StatusOK,
+			wantAllowed: true,
+		},
+		{
+			name: "subresource is admitted",
+			body: review(mangodbs, "status", admissionv1.Update, object, oldObject),
+			validator: func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+				return errors.New("never")
+			},
+			wantCode:    http.StatusOK,
+			wantAllowed: true,
+		},
+		{
+			name: "create allowed",
+			body: review(mangodbs, "", admissionv1.Create, object, nil),
+			validator: func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+				require.Equal(t, admissionv1.Create, operation)
+				require.Equal(t, "default", obj.GetNamespace(), "namespace must be defaulted from the request")
+				require.Nil(t, old)
+				return nil
+			},
+			wantCode:    http.StatusOK,
+			wantAllowed: true,
+			wantCalled:  true,
+		},
+		{
+			name: "update denied by the provider",
+			body: review(mangodbs, "", admissionv1.Update, object, oldObject),
+			validator: func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+				tier, _, _ := unstructured.NestedString(old.Object, "spec", "tier")
+				require.Equal(t, "Shared", tier)
+				return apierrors.NewInvalid(gk, "foo", field.ErrorList{field.Invalid(field.NewPath("spec", "tier"), "Dedicated", "field is immutable")})
+			},
+			wantCode:   http.StatusOK,
+			wantCalled: true,
+			wantResult: &metav1.Status{Reason: metav1.StatusReasonInvalid, Code: http.StatusUnprocessableEntity},
+		},
+		{
+			name: "other error",
+			body: review(mangodbs, "", admissionv1.Create, object, nil),
+			validator: func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+				return errors.New("boom")
+			},
+			wantCode:   http.StatusOK,
+			wantCalled: true,
+			wantResult: &metav1.Status{Reason: metav1.StatusReasonInvalid, Code: http.StatusUnprocessableEntity, Message: "boom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer()
+			called := false
+			if tt.validator != nil {
+				s.Register(mangodbs, func(ctx context.Context, operation admissionv1.Operation, obj, old *unstructured.Unstructured) error {
+					called = true
+					return tt.validator(ctx, operation, obj, old)
+				})
+			}
+
+			w := httptest.NewRecorder()
+			s.serveValidate(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(tt.body)))
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			require.Equal(t, tt.wantCalled, called)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var response admissionv1.AdmissionReview
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.Nil(t, response.Request)
+			require.NotNil(t, response.Response)
+			require.Equal(t, "uid", string(response.Response.UID))
+			require.Equal(t, tt.wantAllowed, response.Response.Allowed)
+			if tt.wantResult == nil {
+				require.Nil(t, response.Response.Result)
+				return
+			}
+			require.NotNil(t, response.Response.Result)
+			require.Equal(t, metav1.StatusFailure, response.Response.Result.Status)
+			require.Equal(t, tt.wantResult.Reason, response.Response.Result.Reason)
+			require.Equal(t, tt.wantResult.Code, response.Response.Result.Code)
+			if tt.wantResult.Message != "" {
+				require.Equal(t, tt.wantResult.Message, response.Response.Result.Message)
+			}
+		})
+	}
+}
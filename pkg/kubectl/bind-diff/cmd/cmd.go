@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind-diff/plugin"
+)
+
+var (
+	diffExampleUses = `
+	# compare a bound object with its upstream twin at the service provider.
+	%[1]s diff mangodbs my-db -n default
+
+	# the resource can also be given by kind or with its group.
+	%[1]s diff MangoDB my-db -n default
+	%[1]s diff mangodbs.mangodb.com my-db -n default
+	`
+)
+
+func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
+	opts := plugin.NewDiffOptions(streams)
+	cmd := &cobra.Command{
+		Use:          "diff <kind> <name>",
+		Short:        "Show the differences between a bound object and its upstream twin at the service provider",
+		Example:      fmt.Sprintf(diffExampleUses, "kubectl bind"),
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yellow := color.New(color.BgRed, color.FgBlack).SprintFunc()
+			fmt.Fprintf(streams.ErrOut, yellow("DISCLAIMER: This is a prototype. It will change in incompatible ways at any time.")+"\n\n") // nolint: errcheck
+
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	opts.BindFlags(cmd)
+
+	return cmd, nil
+}
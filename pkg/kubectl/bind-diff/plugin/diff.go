@@ -0,0 +1,324 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/konnector/controllers/cluster/serviceexportresource/mangling"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// DiffOptions are the options for the kubectl-bind-diff command.
+type DiffOptions struct {
+	Options *base.Options
+
+	// kind is the resource argument, e.g. "mangodbs", "MangoDB" or "mangodbs.mangodb.com".
+	kind string
+	// name is the name of the consumer object.
+	name string
+}
+
+// NewDiffOptions returns new DiffOptions.
+func NewDiffOptions(streams genericclioptions.IOStreams) *DiffOptions {
+	return &DiffOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (d *DiffOptions) BindFlags(cmd *cobra.Command) {
+	d.Options.BindFlags(cmd)
+}
+
+// Complete ensures all fields are initialized.
+func (d *DiffOptions) Complete(args []string) error {
+	if err := d.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		d.kind = args[0]
+	}
+	if len(args) > 1 {
+		d.name = args[1]
+	}
+	return nil
+}
+
+// Validate validates the DiffOptions are complete and usable.
+func (d *DiffOptions) Validate() error {
+	if d.kind == "" || d.name == "" {
+		return errors.New("kind and name are required")
+	}
+	return d.Options.Validate()
+}
+
+// Run fetches the consumer object and its upstream twin at the service provider, and
+// prints the differences of metadata, spec and status.
+func (d *DiffOptions) Run(ctx context.Context) error {
+	config, err := d.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	namespace, _, err := d.Options.ClientConfig.Namespace()
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clients, err := summary.NewClients(config)
+	if err != nil {
+		return err
+	}
+
+	crd, bindingName, err := d.findCRD(ctx, clients)
+	if err != nil {
+		return err
+	}
+	binding, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, bindingName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	version := servedVersion(crd)
+	if version == "" {
+		return fmt.Errorf("CustomResourceDefinition %s serves no version", crd.Name)
+	}
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: version, Resource: crd.Spec.Names.Plural}
+	if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+		namespace = ""
+	}
+
+	consumer, err := clients.Dynamic.Resource(gvr).Namespace(namespace).Get(ctx, d.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// the service provider side
+	providerConfig, providerNamespace, err := summary.ProviderConfig(ctx, clients.Kube, binding)
+	if err != nil {
+		return err
+	}
+	providerBindClient, err := bindclient.NewForConfig(providerConfig)
+	if err != nil {
+		return err
+	}
+	providerDynamicClient, err := dynamic.NewForConfig(providerConfig)
+	if err != nil {
+		return err
+	}
+	export, err := providerBindClient.KubeBindV1alpha1().APIServiceExports(providerNamespace).Get(ctx, binding.Spec.Export, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get APIServiceExport %s/%s at the service provider: %w", providerNamespace, binding.Spec.Export, err)
+	}
+
+	upstreamNamespace, upstreamName, err := upstreamKey(ctx, providerBindClient, providerNamespace, export, consumer)
+	if err != nil {
+		return err
+	}
+	provider, err := providerDynamicClient.Resource(gvr).Namespace(upstreamNamespace).Get(ctx, upstreamName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("upstream object %s not found at the service provider. Check the konnector with \"kubectl bind doctor %s\"", key(upstreamNamespace, upstreamName), binding.Name)
+	} else if err != nil {
+		return err
+	}
+
+	out := d.Options.Out
+	fmt.Fprintf(out, "--- consumer %s %s\n", gvr.GroupResource(), key(namespace, d.name))                                                    // nolint: errcheck
+	fmt.Fprintf(out, "+++ provider %s %s (APIServiceBinding %s)\n", gvr.GroupResource(), key(upstreamNamespace, upstreamName), binding.Name) // nolint: errcheck
+	for _, section := range []struct {
+		name               string
+		consumer, provider interface{}
+	}{
+		{"metadata", comparableMetadata(consumer), comparableMetadata(provider)},
+		{"spec", consumer.Object["spec"], provider.Object["spec"]},
+		{"status", consumer.Object["status"], provider.Object["status"]},
+	} {
+		fmt.Fprintf(out, "\n%s:\n", section.name) // nolint: errcheck
+		if diff := cmp.Diff(section.consumer, section.provider); diff != "" {
+			fmt.Fprint(out, diff) // nolint: errcheck
+		} else {
+			fmt.Fprintln(out, "  no differences") // nolint: errcheck
+		}
+	}
+
+	return nil
+}
+
+// findCRD finds the bound CustomResourceDefinition matching the kind argument, and
+// returns it with the name of the owning APIServiceBinding.
+func (d *DiffOptions) findCRD(ctx context.Context, clients *summary.Clients) (*apiextensionsv1.CustomResourceDefinition, string, error) {
+	crds, err := clients.Apiextensions.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	resource, group, _ := strings.Cut(strings.ToLower(d.kind), ".")
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		if group != "" && crd.Spec.Group != group {
+			continue
+		}
+		names := crd.Spec.Names
+		candidates := append([]string{names.Plural, names.Singular, strings.ToLower(names.Kind)}, names.ShortNames...)
+		found := false
+		for _, c := range candidates {
+			if c == resource {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		for _, ref := range crd.OwnerReferences {
+			parts := strings.SplitN(ref.APIVersion, "/", 2)
+			if parts[0] == kubebindv1alpha1.SchemeGroupVersion.Group && ref.Kind == "APIServiceBinding" {
+				return crd, ref.Name, nil
+			}
+		}
+		return nil, "", fmt.Errorf("CustomResourceDefinition %s is not bound by kube-bind", crd.Name)
+	}
+
+	return nil, "", fmt.Errorf("no bound resource %q found", d.kind)
+}
+
+// upstreamKey returns the namespace and name of the upstream object. These are the ones the
+// konnector has recorded on the consumer object, or else the ones it would map the consumer
+// object to according to the isolation of the export.
+func upstreamKey(ctx context.Context, providerBindClient bindclient.Interface, providerNamespace string, export *kubebindv1alpha1.APIServiceExport, consumer *unstructured.Unstructured) (string, string, error) {
+	annotations := consumer.GetAnnotations()
+	if name := annotations[kubebindv1alpha1.UpstreamNameAnnotationKey]; name != "" {
+		return annotations[kubebindv1alpha1.UpstreamNamespaceAnnotationKey], name, nil
+	}
+
+	ns, name := consumer.GetNamespace(), consumer.GetName()
+	switch {
+	case ns != "" && export.Spec.NamespaceIsolation == kubebindv1alpha1.NamespaceIsolationClusterNamespace:
+		return providerNamespace, mangling.Name(ns, name), nil
+	case ns != "":
+		sn, err := providerBindClient.KubeBindV1alpha1().APIServiceNamespaces(providerNamespace).Get(ctx, ns, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return "", "", fmt.Errorf("APIServiceNamespace %s/%s not found at the service provider. The konnector has not synced namespace %s yet", providerNamespace, ns, ns)
+		} else if err != nil {
+			return "", "", err
+		}
+		if sn.Status.Namespace == "" {
+			return "", "", fmt.Errorf("APIServiceNamespace %s/%s has no namespace assigned by the service provider yet", providerNamespace, ns)
+		}
+		return sn.Status.Namespace, name, nil
+	case export.Spec.ClusterScopedIsolation == kubebindv1alpha1.IsolationNamespaced:
+		return providerNamespace, name, nil
+	}
+	return "", name, nil
+}
+
+// comparableMetadata returns the labels, annotations and finalizers of the object without
+// those the konnector sets on one side only, i.e. its bookkeeping on the consumer object and
+// the provenance on the upstream object. Names, namespaces and generations differ by design.
+func comparableMetadata(obj *unstructured.Unstructured) map[string]interface{} {
+	return map[string]interface{}{
+		"labels":      without(obj.GetLabels(), konnectorLabels),
+		"annotations": without(obj.GetAnnotations(), konnectorAnnotations),
+		"finalizers":  withoutFinalizer(obj.GetFinalizers(), kubebindv1alpha1.DownstreamFinalizer),
+	}
+}
+
+// konnectorLabels are the labels the konnector sets on only one side.
+var konnectorLabels = sets.NewString(
+	kubebindv1alpha1.OriginLabelKey,
+	kubebindv1alpha1.ConsumerClusterLabelKey,
+	kubebindv1alpha1.ConsumerNamespaceLabelKey,
+	kubebindv1alpha1.ConsumerUIDLabelKey,
+)
+
+// konnectorAnnotations are the annotations the konnector sets on only one side.
+var konnectorAnnotations = sets.NewString(
+	kubebindv1alpha1.UpstreamNamespaceAnnotationKey,
+	kubebindv1alpha1.UpstreamNameAnnotationKey,
+	kubebindv1alpha1.SyncedGenerationAnnotationKey,
+	kubebindv1alpha1.UpstreamResourceVersionAnnotationKey,
+	kubebindv1alpha1.UpstreamUIDAnnotationKey,
+	kubebindv1alpha1.UpstreamGenerationsAnnotationKey,
+	kubebindv1alpha1.SyncErrorAnnotationKey,
+	kubebindv1alpha1.ReplacingUpstreamAnnotationKey,
+	kubebindv1alpha1.ProviderAnnotationKey,
+	kubebindv1alpha1.ConsumerNameAnnotationKey,
+)
+
+func without(m map[string]string, keys sets.String) map[string]string {
+	ret := map[string]string{}
+	for k, v := range m {
+		if !keys.Has(k) {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func withoutFinalizer(finalizers []string, finalizer string) []string {
+	ret := []string{}
+	for _, f := range finalizers {
+		if f != finalizer {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+func servedVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Served && v.Storage {
+			return v.Name
+		}
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+func key(ns, name string) string {
+	if ns == "" {
+		return name
+	}
+	return ns + "/" + name
+}
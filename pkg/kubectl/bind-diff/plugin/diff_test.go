@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestComparableMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		finalizers  []string
+		want        map[string]interface{}
+	}{
+		{
+			name: "empty",
+			want: map[string]interface{}{
+				"labels":      map[string]string{},
+				"annotations": map[string]string{},
+				"finalizers":  []string{},
+			},
+		},
+		{
+			name: "consumer bookkeeping",
+			labels: map[string]string{
+				"app":                           "foo",
+				kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginProvider,
+			},
+			annotations: map[string]string{
+				"note": "bar",
+				kubebindv1alpha1.UpstreamNamespaceAnnotationKey:       "kube-bind-abc",
+				kubebindv1alpha1.UpstreamNameAnnotationKey:            "foo",
+				kubebindv1alpha1.SyncedGenerationAnnotationKey:        "3",
+				kubebindv1alpha1.UpstreamResourceVersionAnnotationKey: "42",
+				kubebindv1alpha1.UpstreamUIDAnnotationKey:             "uid",
+				kubebindv1alpha1.UpstreamGenerationsAnnotationKey:     "3:4",
+				kubebindv1alpha1.SyncErrorAnnotationKey:               "",
+				kubebindv1alpha1.ReplacingUpstreamAnnotationKey:       "uid",
+				kubebindv1alpha1.ProviderAnnotationKey:                "provider",
+			},
+			finalizers: []string{"example.com/foo", kubebindv1alpha1.DownstreamFinalizer},
+			want: map[string]interface{}{
+				"labels":      map[string]string{"app": "foo"},
+				"annotations": map[string]string{"note": "bar"},
+				"finalizers":  []string{"example.com/foo"},
+			},
+		},
+		{
+			name: "upstream provenance",
+			labels: map[string]string{
+				"app":                                      "foo",
+				kubebindv1alpha1.OriginLabelKey:            kubebindv1alpha1.OriginConsumer,
+				kubebindv1alpha1.ConsumerClusterLabelKey:   "cluster",
+				kubebindv1alpha1.ConsumerNamespaceLabelKey: "default",
+				kubebindv1alpha1.ConsumerUIDLabelKey:       "uid",
+			},
+			annotations: map[string]string{
+				kubebindv1alpha1.ConsumerNameAnnotationKey: "foo",
+			},
+			want: map[string]interface{}{
+				"labels":      map[string]string{"app": "foo"},
+				"annotations": map[string]string{},
+				"finalizers":  []string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetLabels(tt.labels)
+			obj.SetAnnotations(tt.annotations)
+			obj.SetFinalizers(tt.finalizers)
+			obj.SetGeneration(5)
+			require.Equal(t, tt.want, comparableMetadata(obj))
+		})
+	}
+}
+
+func TestUpstreamKeyFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		wantNamespace string
+		wantName      string
+	}{
+		{
+			name: "namespaced",
+			annotations: map[string]string{
+				kubebindv1alpha1.UpstreamNamespaceAnnotationKey: "kube-bind-abc",
+				kubebindv1alpha1.UpstreamNameAnnotationKey:      "default-foo",
+			},
+			wantNamespace: "kube-bind-abc",
+			wantName:      "default-foo",
+		},
+		{
+			name: "cluster-scoped",
+			annotations: map[string]string{
+				kubebindv1alpha1.UpstreamNameAnnotationKey: "foo",
+			},
+			wantName: "foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetNamespace("default")
+			obj.SetName("foo")
+			obj.SetAnnotations(tt.annotations)
+
+			// no client and export needed because the annotations win
+			ns, name, err := upstreamKey(context.Background(), nil, "kube-bind-abc", nil, obj)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantNamespace, ns)
+			require.Equal(t, tt.wantName, name)
+		})
+	}
+}
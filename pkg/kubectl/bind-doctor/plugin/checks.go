@@ -0,0 +1,341 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	kubebindhelpers "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1/helpers"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// Result is the outcome of a check.
+type Result string
+
+const (
+	ResultPass Result = "Pass"
+	ResultWarn Result = "Warn"
+	ResultFail Result = "Fail"
+	// ResultSkip means the check could not run because a check it depends on failed.
+	ResultSkip Result = "Skip"
+)
+
+// Check is a single diagnostic, with a remediation hint if it did not pass.
+type Check struct {
+	Name    string `json:"name"`
+	Result  Result `json:"result"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Report are the checks of one APIServiceBinding.
+type Report struct {
+	Binding string  `json:"binding"`
+	Checks  []Check `json:"checks"`
+}
+
+func pass(name, format string, args ...interface{}) Check {
+	return Check{Name: name, Result: ResultPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(name, reason, format string, args ...interface{}) Check {
+	return Check{Name: name, Result: ResultFail, Reason: reason, Message: fmt.Sprintf(format, args...), Hint: hint(reason)}
+}
+
+func warn(name, reason, format string, args ...interface{}) Check {
+	return Check{Name: name, Result: ResultWarn, Reason: reason, Message: fmt.Sprintf(format, args...), Hint: hint(reason)}
+}
+
+func skip(name, format string, args ...interface{}) Check {
+	return Check{Name: name, Result: ResultSkip, Message: fmt.Sprintf(format, args...)}
+}
+
+// checkKonnector checks that the konnector is installed, ready and compatible.
+func checkKonnector(ctx context.Context, clients *summary.Clients) Check {
+	const name = "Konnector"
+
+	installation, err := kubectlkonnector.Installed(ctx, clients.Kube)
+	if err != nil {
+		return Check{Name: name, Result: ResultFail, Message: err.Error(), Hint: "Check the permissions of the current kubeconfig."}
+	}
+	if installation == nil {
+		return Check{Name: name, Result: ResultFail, Reason: "KonnectorNotFound", Message: "The konnector is not installed.", Hint: "Rerun \"kubectl bind\" to install it."}
+	}
+	if installation.ReadyReplicas == 0 {
+		return Check{Name: name, Result: ResultFail, Reason: "KonnectorNotReady", Message: fmt.Sprintf("No konnector replica of %d is ready.", installation.Replicas), Hint: "Check the konnector pods: kubectl get pods -n kube-bind"}
+	}
+	if warning := kubectlkonnector.CompatibilityWarning(installation.Version); warning != "" {
+		return Check{Name: name, Result: ResultWarn, Reason: "KonnectorIncompatible", Message: warning, Hint: "Run \"kubectl bind konnector upgrade\"."}
+	}
+	return pass(name, "Version %s, %d/%d replicas ready.", installation.Version, installation.ReadyReplicas, installation.Replicas)
+}
+
+// diagnose runs the checks of the given binding on the consumer and the service provider side.
+func diagnose(ctx context.Context, clients *summary.Clients, binding *kubebindv1alpha1.APIServiceBinding) []Check {
+	var checks []Check
+
+	// consumer side: the conditions the konnector has set
+	checks = append(checks, checkConditions(binding)...)
+
+	// kubeconfig secret
+	providerClient, providerNamespace, check := checkKubeconfigSecret(ctx, clients, binding)
+	checks = append(checks, check)
+	if providerClient == nil {
+		return append(checks, skip("Service provider", "Kubeconfig secret is invalid."))
+	}
+
+	// provider reachability and heartbeats
+	clusterBinding, err := providerClient.KubeBindV1alpha1().ClusterBindings(providerNamespace).Get(ctx, "cluster", metav1.GetOptions{})
+	if err != nil {
+		check := Check{
+			Name:    "Service provider",
+			Result:  ResultFail,
+			Reason:  "ProviderUnreachable",
+			Message: fmt.Sprintf("Cannot get ClusterBinding %s/cluster: %v", providerNamespace, err),
+			Hint:    "Check the network path to the service provider, and that the kubeconfig in the secret is still authorized. Rerun \"kubectl bind\" for repair.",
+		}
+		if apierrors.IsNotFound(err) {
+			check.Reason = "ClusterBindingNotFound"
+			check.Hint = "The service provider removed the binding of this cluster. Rerun \"kubectl bind\" for repair."
+		}
+		return append(checks, check)
+	}
+	checks = append(checks, pass("Service provider", "ClusterBinding %s/cluster is reachable.", providerNamespace))
+	checks = append(checks, checkHeartbeat(clusterBinding))
+
+	// export and its resources
+	export, err := providerClient.KubeBindV1alpha1().APIServiceExports(providerNamespace).Get(ctx, binding.Spec.Export, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return append(checks, fail("APIServiceExport", "APIServiceExportNotFound", "APIServiceExport %s/%s not found.", providerNamespace, binding.Spec.Export))
+	} else if err != nil {
+		return append(checks, Check{Name: "APIServiceExport", Result: ResultFail, Message: err.Error()})
+	}
+	checks = append(checks, checkExport(export))
+	for _, resource := range export.Spec.Resources {
+		checks = append(checks, checkExportResource(ctx, providerClient, providerNamespace, export, resource.GroupResource))
+		checks = append(checks, checkCRD(ctx, clients, binding, resource.GroupResource))
+	}
+
+	// namespaces
+	checks = append(checks, checkNamespaces(ctx, providerClient, providerNamespace))
+
+	return checks
+}
+
+// checkConditions reports the conditions of the binding which are not true, with the
+// reasons the konnector has set.
+func checkConditions(binding *kubebindv1alpha1.APIServiceBinding) []Check {
+	var checks []Check
+	for _, c := range binding.Status.Conditions {
+		if c.Type == conditionsapi.ReadyCondition {
+			continue
+		}
+		name := "Condition " + string(c.Type)
+		switch {
+		case c.Status == corev1.ConditionTrue:
+			checks = append(checks, pass(name, "True"))
+		case c.Severity == conditionsapi.ConditionSeverityError:
+			checks = append(checks, fail(name, c.Reason, "%s", c.Message))
+		default:
+			checks = append(checks, warn(name, c.Reason, "%s", c.Message))
+		}
+	}
+	if len(binding.Status.Conditions) == 0 {
+		checks = append(checks, Check{Name: "Conditions", Result: ResultWarn, Message: "The konnector has not reconciled the APIServiceBinding yet.", Hint: "Check that the konnector runs: kubectl bind konnector status"})
+	}
+	return checks
+}
+
+// checkKubeconfigSecret validates the kubeconfig secret like the konnector does, and
+// returns a client and the namespace at the service provider.
+func checkKubeconfigSecret(ctx context.Context, clients *summary.Clients, binding *kubebindv1alpha1.APIServiceBinding) (bindclient.Interface, string, Check) {
+	const name = "Kubeconfig secret"
+
+	ref := binding.Spec.KubeconfigSecretRef
+	providerConfig, ns, err := summary.ProviderConfig(ctx, clients.Kube, binding)
+	var status apierrors.APIStatus
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, "", fail(name, "KubeconfigSecretNotFound", "Secret %s/%s not found.", ref.Namespace, ref.Name)
+	case errors.As(err, &status):
+		return nil, "", Check{Name: name, Result: ResultFail, Message: err.Error()}
+	case err != nil:
+		return nil, "", fail(name, "KubeconfigSecretInvalid", "%s.", capitalize(err.Error()))
+	}
+	providerClient, err := bindclient.NewForConfig(providerConfig)
+	if err != nil {
+		return nil, "", fail(name, "KubeconfigSecretInvalid", "Secret %s/%s has an invalid kubeconfig: %v", ref.Namespace, ref.Name, err)
+	}
+
+	return providerClient, ns, pass(name, "Secret %s/%s points to namespace %s at %s.", ref.Namespace, ref.Name, ns, providerConfig.Host)
+}
+
+// capitalize turns an error message into a sentence.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// checkHeartbeat checks the Healthy condition the service provider sets on the ClusterBinding.
+func checkHeartbeat(clusterBinding *kubebindv1alpha1.ClusterBinding) Check {
+	const name = "Heartbeat"
+
+	for _, c := range clusterBinding.Status.Conditions {
+		if c.Type != kubebindv1alpha1.ClusterBindingConditionHealthy {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			return pass(name, "Last heartbeat at %s.", clusterBinding.Status.LastHeartbeatTime.Format(time.RFC3339))
+		}
+		if c.Severity == conditionsapi.ConditionSeverityError {
+			return fail(name, c.Reason, "%s", c.Message)
+		}
+		return warn(name, c.Reason, "%s", c.Message)
+	}
+
+	// the service provider does not maintain the condition. Check ourselves.
+	interval := clusterBinding.Status.HeartbeatInterval.Duration
+	switch {
+	case clusterBinding.Status.LastHeartbeatTime.IsZero():
+		return warn(name, "FirstHeartbeatPending", "Waiting for first heartbeat.")
+	case interval > 0 && time.Since(clusterBinding.Status.LastHeartbeatTime.Time) > 2*interval:
+		return fail(name, "HeartbeatTimeout", "Expected heartbeat within %s, but last one has been at %s.", interval, clusterBinding.Status.LastHeartbeatTime.Format(time.RFC3339))
+	}
+	return pass(name, "Last heartbeat at %s.", clusterBinding.Status.LastHeartbeatTime.Format(time.RFC3339))
+}
+
+// checkExport reports the conditions of the APIServiceExport which are not true.
+func checkExport(export *kubebindv1alpha1.APIServiceExport) Check {
+	const name = "APIServiceExport"
+
+	var failed, warned []string
+	var reason string
+	for _, c := range export.Status.Conditions {
+		if c.Type == conditionsapi.ReadyCondition || c.Status == corev1.ConditionTrue {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %s", c.Type, c.Message)
+		if c.Severity == conditionsapi.ConditionSeverityError {
+			failed = append(failed, msg)
+			if reason == "" {
+				reason = c.Reason
+			}
+		} else {
+			warned = append(warned, msg)
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		return fail(name, reason, "%s/%s: %s", export.Namespace, export.Name, strings.Join(append(failed, warned...), "; "))
+	case len(warned) > 0:
+		return Check{Name: name, Result: ResultWarn, Message: fmt.Sprintf("%s/%s: %s", export.Namespace, export.Name, strings.Join(warned, "; "))}
+	}
+	return pass(name, "%s/%s is ready.", export.Namespace, export.Name)
+}
+
+// checkExportResource checks the APIServiceExportResource like the konnector does.
+func checkExportResource(ctx context.Context, providerClient bindclient.Interface, ns string, export *kubebindv1alpha1.APIServiceExport, gr kubebindv1alpha1.GroupResource) Check {
+	resourceName := gr.Resource + "." + gr.Group
+	name := "APIServiceExportResource " + resourceName
+
+	resource, err := providerClient.KubeBindV1alpha1().APIServiceExportResources(ns).Get(ctx, resourceName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fail(name, "ServiceExportResourceNotFound", "APIServiceExportResource %s/%s not found.", ns, resourceName)
+	} else if err != nil {
+		return Check{Name: name, Result: ResultFail, Message: err.Error()}
+	}
+	if resource.Spec.Scope != apiextensionsv1.NamespaceScoped && export.Spec.Scope != kubebindv1alpha1.ClusterScope {
+		return fail(name, "ServiceExportResourceWrongScope", "APIServiceExportResource %s is Cluster scope, but the APIServiceExport is not.", resourceName)
+	}
+	if _, err := kubebindhelpers.ServiceExportResourceToCRD(resource); err != nil {
+		return fail(name, "ServiceExportResourceInvalid", "APIServiceExportResource %s is invalid: %v", resourceName, err)
+	}
+	return pass(name, "Valid.")
+}
+
+// checkCRD checks that the CustomResourceDefinition exists in the consumer cluster and
+// is owned by the binding.
+func checkCRD(ctx context.Context, clients *summary.Clients, binding *kubebindv1alpha1.APIServiceBinding, gr kubebindv1alpha1.GroupResource) Check {
+	crdName := gr.Resource + "." + gr.Group
+	name := "CustomResourceDefinition " + crdName
+
+	crd, err := clients.Apiextensions.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fail(name, "CustomResourceDefinitionNotFound", "CustomResourceDefinition %s not found in the consumer cluster.", crdName)
+	} else if err != nil {
+		return Check{Name: name, Result: ResultFail, Message: err.Error()}
+	}
+
+	var owner string
+	for _, ref := range crd.OwnerReferences {
+		parts := strings.SplitN(ref.APIVersion, "/", 2)
+		if parts[0] != kubebindv1alpha1.SchemeGroupVersion.Group || ref.Kind != "APIServiceBinding" {
+			continue
+		}
+		if ref.Name == binding.Name {
+			return pass(name, "Owned by APIServiceBinding %s.", binding.Name)
+		}
+		owner = ref.Name
+	}
+	if owner != "" {
+		return fail(name, "ForeignCustomResourceDefinition", "CustomResourceDefinition %s is owned by APIServiceBinding %s.", crdName, owner)
+	}
+	return fail(name, "ForeignCustomResourceDefinition", "CustomResourceDefinition %s is not owned by kube-bind.io.", crdName)
+}
+
+// checkNamespaces checks that the service provider has assigned a namespace to every
+// APIServiceNamespace.
+func checkNamespaces(ctx context.Context, providerClient bindclient.Interface, ns string) Check {
+	const name = "APIServiceNamespaces"
+
+	namespaces, err := providerClient.KubeBindV1alpha1().APIServiceNamespaces(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return Check{Name: name, Result: ResultFail, Message: err.Error()}
+	}
+	var pending []string
+	for _, sns := range namespaces.Items {
+		if sns.Status.Namespace == "" {
+			pending = append(pending, sns.Name)
+		}
+	}
+	if len(pending) > 0 {
+		return Check{
+			Name:    name,
+			Result:  ResultWarn,
+			Reason:  "NamespacePending",
+			Message: fmt.Sprintf("No namespace assigned by the service provider yet for: %s", strings.Join(pending, ", ")),
+			Hint:    "Objects in these namespaces are not synced until the service provider assigns a namespace. Contact the service provider if this persists.",
+		}
+	}
+	return pass(name, "%d namespaces assigned.", len(namespaces.Items))
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	bindfake "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned/fake"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+func newBinding() *kubebindv1alpha1.APIServiceBinding {
+	return &kubebindv1alpha1.APIServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "mangodbs"},
+		Spec: kubebindv1alpha1.APIServiceBindingSpec{
+			Export: "mangodbs",
+			KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+				LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: "kubeconfig-abc", Key: "kubeconfig"},
+				Namespace:         "kube-bind",
+			},
+		},
+	}
+}
+
+func requireCheck(t *testing.T, got Check, result Result, reason string) {
+	t.Helper()
+	require.Equal(t, result, got.Result, "message: %s", got.Message)
+	require.Equal(t, reason, got.Reason)
+	if result == ResultFail && reason != "" {
+		require.Equal(t, hint(reason), got.Hint)
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	binding := newBinding()
+	binding.Status.Conditions = conditionsapi.Conditions{
+		{Type: conditionsapi.ReadyCondition, Status: corev1.ConditionFalse},
+		{Type: kubebindv1alpha1.APIServiceBindingConditionSecretValid, Status: corev1.ConditionTrue},
+		{Type: kubebindv1alpha1.APIServiceBindingConditionSchemaInSync, Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityError, Reason: "ForeignCustomResourceDefinition", Message: "owned by other"},
+		{Type: kubebindv1alpha1.APIServiceBindingConditionConnected, Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityWarning, Reason: "Migrating", Message: "migrating"},
+	}
+
+	checks := checkConditions(binding)
+	require.Len(t, checks, 3)
+	requireCheck(t, checks[0], ResultPass, "")
+	requireCheck(t, checks[1], ResultFail, "ForeignCustomResourceDefinition")
+	require.Equal(t, "owned by other", checks[1].Message)
+	requireCheck(t, checks[2], ResultWarn, "Migrating")
+	require.Equal(t, hint("Migrating"), checks[2].Hint)
+
+	checks = checkConditions(newBinding())
+	require.Len(t, checks, 1)
+	requireCheck(t, checks[0], ResultWarn, "")
+}
+
+func TestCheckHeartbeat(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     kubebindv1alpha1.ClusterBindingStatus
+		wantResult Result
+		wantReason string
+	}{
+		{
+			name: "healthy condition",
+			status: kubebindv1alpha1.ClusterBindingStatus{
+				LastHeartbeatTime: metav1.Now(),
+				Conditions:        conditionsapi.Conditions{{Type: kubebindv1alpha1.ClusterBindingConditionHealthy, Status: corev1.ConditionTrue}},
+			},
+			wantResult: ResultPass,
+		},
+		{
+			name: "unhealthy condition",
+			status: kubebindv1alpha1.ClusterBindingStatus{
+				Conditions: conditionsapi.Conditions{{Type: kubebindv1alpha1.ClusterBindingConditionHealthy, Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityError, Reason: "HeartbeatTimeout"}},
+			},
+			wantResult: ResultFail,
+			wantReason: "HeartbeatTimeout",
+		},
+		{
+			name: "drifting condition",
+			status: kubebindv1alpha1.ClusterBindingStatus{
+				Conditions: conditionsapi.Conditions{{Type: kubebindv1alpha1.ClusterBindingConditionHealthy, Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityWarning, Reason: "HeartbeatTimeDrift"}},
+			},
+			wantResult: ResultWarn,
+			wantReason: "HeartbeatTimeDrift",
+		},
+		{
+			name:       "no heartbeat yet",
+			status:     kubebindv1alpha1.ClusterBindingStatus{},
+			wantResult: ResultWarn,
+			wantReason: "FirstHeartbeatPending",
+		},
+		{
+			name: "recent heartbeat",
+			status: kubebindv1alpha1.ClusterBindingStatus{
+				LastHeartbeatTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+				HeartbeatInterval: metav1.Duration{Duration: time.Minute},
+			},
+			wantResult: ResultPass,
+		},
+		{
+			name: "missed heartbeats",
+			status: kubebindv1alpha1.ClusterBindingStatus{
+				LastHeartbeatTime: metav1.NewTime(time.Now().Add(-3 * time.Minute)),
+				HeartbeatInterval: metav1.Duration{Duration: time.Minute},
+			},
+			wantResult: ResultFail,
+			wantReason: "HeartbeatTimeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireCheck(t, checkHeartbeat(&kubebindv1alpha1.ClusterBinding{Status: tt.status}), tt.wantResult, tt.wantReason)
+		})
+	}
+}
+
+func TestCheckExport(t *testing.T) {
+	tests := []struct {
+		name        string
+		conditions  conditionsapi.Conditions
+		wantResult  Result
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name: "ready",
+			conditions: conditionsapi.Conditions{
+				{Type: conditionsapi.ReadyCondition, Status: corev1.ConditionTrue},
+				{Type: kubebindv1alpha1.APIServiceExportConditionConnected, Status: corev1.ConditionTrue},
+			},
+			wantResult:  ResultPass,
+			wantMessage: "kube-bind-abc/mangodbs is ready.",
+		},
+		{
+			name: "errors before warnings",
+			conditions: conditionsapi.Conditions{
+				{Type: conditionsapi.ReadyCondition, Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityError, Reason: "Ready"},
+				{Type: "Warned", Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityWarning, Reason: "Slow", Message: "slow"},
+				{Type: "Failed", Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityError, Reason: "NoServiceBinding", Message: "no binding"},
+			},
+			wantResult:  ResultFail,
+			wantReason:  "NoServiceBinding",
+			wantMessage: "kube-bind-abc/mangodbs: Failed: no binding; Warned: slow",
+		},
+		{
+			name: "warnings only",
+			conditions: conditionsapi.Conditions{
+				{Type: "Warned", Status: corev1.ConditionFalse, Severity: conditionsapi.ConditionSeverityWarning, Reason: "Slow", Message: "slow"},
+			},
+			wantResult:  ResultWarn,
+			wantMessage: "kube-bind-abc/mangodbs: Warned: slow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := &kubebindv1alpha1.APIServiceExport{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-abc", Name: "mangodbs"},
+				Status:     kubebindv1alpha1.APIServiceExportStatus{Conditions: tt.conditions},
+			}
+			check := checkExport(export)
+			requireCheck(t, check, tt.wantResult, tt.wantReason)
+			require.Equal(t, tt.wantMessage, check.Message)
+		})
+	}
+}
+
+func TestCheckExportResource(t *testing.T) {
+	gr := kubebindv1alpha1.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+	newResource := func(scope apiextensionsv1.ResourceScope, schema string) *kubebindv1alpha1.APIServiceExportResource {
+		return &kubebindv1alpha1.APIServiceExportResource{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-abc", Name: "mangodbs.mangodb.com"},
+			Spec: kubebindv1alpha1.APIServiceExportResourceSpec{
+				Group: "mangodb.com",
+				Scope: scope,
+				Versions: []kubebindv1alpha1.APIServiceExportResourceVersion{{
+					Name:   "v1",
+					Schema: kubebindv1alpha1.APIServiceExportResourceSchema{OpenAPIV3Schema: runtime.RawExtension{Raw: []byte(schema)}},
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		wantResult Result
+		wantReason string
+	}{
+		{
+			name:       "valid",
+			objects:    []runtime.Object{newResource(apiextensionsv1.NamespaceScoped, `{"type":"object"}`)},
+			wantResult: ResultPass,
+		},
+		{
+			name:       "not found",
+			wantResult: ResultFail,
+			wantReason: "ServiceExportResourceNotFound",
+		},
+		{
+			name:       "wrong scope",
+			objects:    []runtime.Object{newResource(apiextensionsv1.ClusterScoped, `{"type":"object"}`)},
+			wantResult: ResultFail,
+			wantReason: "ServiceExportResourceWrongScope",
+		},
+		{
+			name:       "invalid schema",
+			objects:    []runtime.Object{newResource(apiextensionsv1.NamespaceScoped, `{"type":`)},
+			wantResult: ResultFail,
+			wantReason: "ServiceExportResourceInvalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := &kubebindv1alpha1.APIServiceExport{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-abc", Name: "mangodbs"},
+				Spec:       kubebindv1alpha1.APIServiceExportSpec{Scope: kubebindv1alpha1.NamespacedScope},
+			}
+			check := checkExportResource(context.Background(), bindfake.NewSimpleClientset(tt.objects...), "kube-bind-abc", export, gr)
+			require.Equal(t, "APIServiceExportResource mangodbs.mangodb.com", check.Name)
+			requireCheck(t, check, tt.wantResult, tt.wantReason)
+		})
+	}
+}
+
+func TestCheckCRD(t *testing.T) {
+	gr := kubebindv1alpha1.GroupResource{Group: "mangodb.com", Resource: "mangodbs"}
+	newCRD := func(owners ...string) *apiextensionsv1.CustomResourceDefinition {
+		crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "mangodbs.mangodb.com"}}
+		for _, owner := range owners {
+			crd.OwnerReferences = append(crd.OwnerReferences, metav1.OwnerReference{
+				APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+				Kind:       "APIServiceBinding",
+				Name:       owner,
+			})
+		}
+		return crd
+	}
+
+	tests := []struct {
+		name        string
+		objects     []runtime.Object
+		wantResult  Result
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "owned",
+			objects:     []runtime.Object{newCRD("other", "mangodbs")},
+			wantResult:  ResultPass,
+			wantMessage: "Owned by APIServiceBinding mangodbs.",
+		},
+		{
+			name:        "not found",
+			wantResult:  ResultFail,
+			wantReason:  "CustomResourceDefinitionNotFound",
+			wantMessage: "CustomResourceDefinition mangodbs.mangodb.com not found in the consumer cluster.",
+		},
+		{
+			name:        "owned by other binding",
+			objects:     []runtime.Object{newCRD("other")},
+			wantResult:  ResultFail,
+			wantReason:  "ForeignCustomResourceDefinition",
+			wantMessage: "CustomResourceDefinition mangodbs.mangodb.com is owned by APIServiceBinding other.",
+		},
+		{
+			name:        "not owned by kube-bind",
+			objects:     []runtime.Object{newCRD()},
+			wantResult:  ResultFail,
+			wantReason:  "ForeignCustomResourceDefinition",
+			wantMessage: "CustomResourceDefinition mangodbs.mangodb.com is not owned by kube-bind.io.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &summary.Clients{Apiextensions: apiextensionsfake.NewSimpleClientset(tt.objects...)}
+			check := checkCRD(context.Background(), clients, newBinding(), gr)
+			requireCheck(t, check, tt.wantResult, tt.wantReason)
+			require.Equal(t, tt.wantMessage, check.Message)
+		})
+	}
+}
+
+func TestCheckKubeconfigSecret(t *testing.T) {
+	kubeconfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: provider
+  cluster:
+    server: https://provider.example.com
+contexts:
+- name: provider
+  context:
+    cluster: provider
+    namespace: kube-bind-abc
+current-context: provider
+`)
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		wantClient bool
+		wantResult Result
+		wantReason string
+	}{
+		{
+			name: "valid",
+			objects: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "kubeconfig-abc"},
+				Data:       map[string][]byte{"kubeconfig": kubeconfig},
+			}},
+			wantClient: true,
+			wantResult: ResultPass,
+		},
+		{
+			name:       "not found",
+			wantResult: ResultFail,
+			wantReason: "KubeconfigSecretNotFound",
+		},
+		{
+			name: "missing key",
+			objects: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "kubeconfig-abc"},
+				Data:       map[string][]byte{"other": kubeconfig},
+			}},
+			wantResult: ResultFail,
+			wantReason: "KubeconfigSecretInvalid",
+		},
+		{
+			name: "invalid kubeconfig",
+			objects: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "kubeconfig-abc"},
+				Data:       map[string][]byte{"kubeconfig": []byte("{")},
+			}},
+			wantResult: ResultFail,
+			wantReason: "KubeconfigSecretInvalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &summary.Clients{Kube: kubefake.NewSimpleClientset(tt.objects...)}
+			client, ns, check := checkKubeconfigSecret(context.Background(), clients, newBinding())
+			requireCheck(t, check, tt.wantResult, tt.wantReason)
+			require.Equal(t, tt.wantClient, client != nil)
+			if tt.wantClient {
+				require.Equal(t, "kube-bind-abc", ns)
+				require.Equal(t, "Secret kube-bind/kubeconfig-abc points to namespace kube-bind-abc at https://provider.example.com.", check.Message)
+			}
+		})
+	}
+}
+
+func TestCheckNamespaces(t *testing.T) {
+	assigned := &kubebindv1alpha1.APIServiceNamespace{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-abc", Name: "default"},
+		Status:     kubebindv1alpha1.APIServiceNamespaceStatus{Namespace: "kube-bind-abc-default"},
+	}
+	pending := &kubebindv1alpha1.APIServiceNamespace{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind-abc", Name: "team-a"},
+	}
+
+	check := checkNamespaces(context.Background(), bindfake.NewSimpleClientset(assigned), "kube-bind-abc")
+	requireCheck(t, check, ResultPass, "")
+	require.Equal(t, "1 namespaces assigned.", check.Message)
+
+	check = checkNamespaces(context.Background(), bindfake.NewSimpleClientset(assigned, pending), "kube-bind-abc")
+	requireCheck(t, check, ResultWarn, "NamespacePending")
+	require.Contains(t, check.Message, "team-a")
+	require.NotContains(t, check.Message, "default")
+}
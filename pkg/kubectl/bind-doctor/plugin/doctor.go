@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// DoctorOptions are the options for the kubectl-bind-doctor command.
+type DoctorOptions struct {
+	Options *base.Options
+
+	// Output is the output format, one of json or yaml. Empty means human readable.
+	Output string
+
+	// name is the name of the APIServiceBinding. Empty means all.
+	name string
+}
+
+// NewDoctorOptions returns new DoctorOptions.
+func NewDoctorOptions(streams genericclioptions.IOStreams) *DoctorOptions {
+	return &DoctorOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (d *DoctorOptions) BindFlags(cmd *cobra.Command) {
+	d.Options.BindFlags(cmd)
+
+	cmd.Flags().StringVarP(&d.Output, "output", "o", d.Output, "Output format. One of: json|yaml")
+}
+
+// Complete ensures all fields are initialized.
+func (d *DoctorOptions) Complete(args []string) error {
+	if err := d.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		d.name = args[0]
+	}
+	return nil
+}
+
+// Validate validates the DoctorOptions are complete and usable.
+func (d *DoctorOptions) Validate() error {
+	if d.Output == summary.OutputWide {
+		return fmt.Errorf("unsupported output format %q, must be one of json or yaml", d.Output)
+	}
+	if err := summary.ValidateOutput(d.Output); err != nil {
+		return err
+	}
+	return d.Options.Validate()
+}
+
+// Run diagnoses the APIServiceBindings and prints the checks. It fails if any check failed.
+func (d *DoctorOptions) Run(ctx context.Context) error {
+	config, err := d.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clients, err := summary.NewClients(config)
+	if err != nil {
+		return err
+	}
+
+	var bindings []kubebindv1alpha1.APIServiceBinding
+	if d.name != "" {
+		binding, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, d.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		bindings = append(bindings, *binding)
+	} else {
+		list, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		bindings = list.Items
+	}
+
+	konnectorCheck := checkKonnector(ctx, clients)
+	reports := make([]Report, 0, len(bindings))
+	for i := range bindings {
+		checks := append([]Check{konnectorCheck}, diagnose(ctx, clients, &bindings[i])...)
+		reports = append(reports, Report{Binding: bindings[i].Name, Checks: checks})
+	}
+
+	if printed, err := summary.PrintObject(d.Options.Out, d.Output, reports); printed {
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(reports) == 0 {
+			printCheck(d.Options.Out, konnectorCheck)
+			fmt.Fprintln(d.Options.Out, "No APIServiceBindings found.") // nolint: errcheck
+		}
+		for i, r := range reports {
+			if i > 0 {
+				fmt.Fprintln(d.Options.Out) // nolint: errcheck
+			}
+			fmt.Fprintf(d.Options.Out, "APIServiceBinding %s:\n", r.Binding) // nolint: errcheck
+			for _, c := range r.Checks {
+				printCheck(d.Options.Out, c)
+			}
+		}
+	}
+
+	failed := 0
+	for _, r := range reports {
+		for _, c := range r.Checks {
+			if c.Result == ResultFail {
+				failed++
+			}
+		}
+	}
+	if len(reports) == 0 && konnectorCheck.Result == ResultFail {
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+func printCheck(out io.Writer, c Check) {
+	var result string
+	switch c.Result {
+	case ResultPass:
+		result = color.GreenString("[PASS]")
+	case ResultWarn:
+		result = color.YellowString("[WARN]")
+	case ResultFail:
+		result = color.RedString("[FAIL]")
+	default:
+		result = "[SKIP]"
+	}
+
+	msg := c.Message
+	if c.Reason != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, c.Reason)
+	}
+	fmt.Fprintf(out, "  %s %s: %s\n", result, c.Name, msg) // nolint: errcheck
+	if c.Hint != "" && c.Result != ResultPass {
+		fmt.Fprintf(out, "         Hint: %s\n", c.Hint) // nolint: errcheck
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+// hints are remediation hints for the condition reasons set by the konnector and the
+// example backend.
+var hints = map[string]string{
+	// consumer side, APIServiceBinding
+	"KubeconfigSecretNotFound":             "Rerun \"kubectl bind\" against the service provider to recreate the kubeconfig secret.",
+	"KubeconfigSecretInvalid":              "The kubeconfig secret is corrupt. Rerun \"kubectl bind\" against the service provider to replace it.",
+	"APIServiceExportNotFound":             "The service provider removed the export. Rerun \"kubectl bind\" for repair, or unbind with \"kubectl bind unbind\".",
+	"ServiceExportResourceNotFound":        "The service provider does not serve the resource anymore. Contact the service provider.",
+	"ServiceExportResourceWrongScope":      "The service provider exports a cluster-scoped resource in a namespaced export. Contact the service provider.",
+	"ServiceExportResourceInvalid":         "The service provider exports an invalid schema. Contact the service provider.",
+	"CustomResourceDefinitionNotFound":     "The konnector has not created the CustomResourceDefinition yet, or it has been deleted. Check that the konnector runs: kubectl bind konnector status",
+	"CustomResourceDefinitionCreateFailed": "Check that the konnector has permission to create CustomResourceDefinitions: kubectl logs -n kube-bind deploy/konnector",
+	"CustomResourceDefinitionUpdateFailed": "Check the konnector logs for the rejected update: kubectl logs -n kube-bind deploy/konnector",
+	"ForeignCustomResourceDefinition":      "Another APIServiceBinding or a local installation owns the CustomResourceDefinition. Unbind or uninstall it, or bind under a different name.",
+	"InformerSyncTimeout":                  "The konnector cannot list from the service provider. Check the konnector logs: kubectl logs -n kube-bind deploy/konnector",
+	"ClusterBindingUpdateFailed":           "The konnector cannot update the ClusterBinding at the service provider. Check that the kubeconfig in the secret is still authorized.",
+	"Migrating":                            "Objects are being recreated at the new service provider. Wait for the migration to finish.",
+
+	// service provider side, ClusterBinding and APIServiceExport
+	"FirstHeartbeatPending":    "The konnector has not connected yet. Check that it runs: kubectl bind konnector status",
+	"HeartbeatIntervalMissing": "The konnector has not reported its heartbeat interval yet. Check that it runs: kubectl bind konnector status",
+	"HeartbeatTimeout":         "The konnector stopped sending heartbeats. Check that it runs, and its logs: kubectl logs -n kube-bind deploy/konnector",
+	"HeartbeatTimeDrift":       "Synchronize the clocks of the consumer and the service provider clusters, e.g. with NTP.",
+	"ProviderSecretNotFound":   "Rerun \"kubectl bind\" against the service provider to recreate the kubeconfig secret.",
+	"ProviderSecretInvalid":    "The kubeconfig secret is corrupt. Rerun \"kubectl bind\" against the service provider to replace it.",
+	"NoServiceBinding":         "No APIServiceBinding refers to the export. Rerun \"kubectl bind\" for repair.",
+	"MultipleServiceBindings":  "Delete all but one APIServiceBinding for the export.",
+}
+
+// hint returns the remediation hint for the given reason, or a generic one.
+func hint(reason string) string {
+	if h, found := hints[reason]; found {
+		return h
+	}
+	return "Check the konnector logs: kubectl logs -n kube-bind deploy/konnector"
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind-konnector/plugin"
+)
+
+var (
+	konnectorExampleUses = `
+	# show the installed konnector version and whether it is compatible.
+	%[1]s konnector status
+
+	# upgrade the konnector to the version of kubectl bind.
+	%[1]s konnector upgrade
+
+	# pin the konnector to a version, or to a custom image.
+	%[1]s konnector upgrade --konnector-version v0.1.0
+	%[1]s konnector upgrade --konnector-image registry.example.com/kube-bind/konnector:v0.1.0 --konnector-version v0.1.0
+
+	# uninstall the konnector after all bindings have been unbound.
+	%[1]s konnector uninstall
+	`
+)
+
+// options is what the subcommands have in common.
+type options interface {
+	BindFlags(cmd *cobra.Command)
+	Complete(args []string) error
+	Validate() error
+	Run(ctx context.Context) error
+}
+
+func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:          "konnector",
+		Short:        "Manage the konnector in the current cluster",
+		Example:      fmt.Sprintf(konnectorExampleUses, "kubectl bind"),
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(newSubcommand(streams, "status", "Show the installed konnector version and readiness", plugin.NewStatusOptions(streams)))
+	cmd.AddCommand(newSubcommand(streams, "upgrade", "Upgrade the konnector to the version of kubectl bind, or to the given version", plugin.NewUpgradeOptions(streams)))
+	cmd.AddCommand(newSubcommand(streams, "uninstall", "Uninstall the konnector", plugin.NewUninstallOptions(streams)))
+
+	return cmd, nil
+}
+
+func newSubcommand(streams genericclioptions.IOStreams, use, short string, opts options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          use,
+		Short:        short,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yellow := color.New(color.BgRed, color.FgBlack).SprintFunc()
+			fmt.Fprintf(streams.ErrOut, yellow("DISCLAIMER: This is a prototype. It will change in incompatible ways at any time.")+"\n\n") // nolint: errcheck
+
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	opts.BindFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+)
+
+// StatusOptions are the options for the kubectl-bind-konnector-status command.
+type StatusOptions struct {
+	Options *base.Options
+}
+
+// NewStatusOptions returns new StatusOptions.
+func NewStatusOptions(streams genericclioptions.IOStreams) *StatusOptions {
+	return &StatusOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (s *StatusOptions) BindFlags(cmd *cobra.Command) {
+	s.Options.BindFlags(cmd)
+}
+
+// Complete ensures all fields are initialized.
+func (s *StatusOptions) Complete(args []string) error {
+	return s.Options.Complete()
+}
+
+// Validate validates the StatusOptions are complete and usable.
+func (s *StatusOptions) Validate() error {
+	return s.Options.Validate()
+}
+
+// Run prints the installed konnector version and its readiness.
+func (s *StatusOptions) Run(ctx context.Context) error {
+	config, err := s.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	installation, err := kubectlkonnector.Installed(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+	if installation == nil {
+		fmt.Fprintf(s.Options.Out, "The konnector is not installed.\n") // nolint: errcheck
+		return nil
+	}
+
+	w := tabwriter.NewWriter(s.Options.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Namespace:\t%s\n", konnector.Namespace)                              // nolint: errcheck
+	fmt.Fprintf(w, "Version:\t%s\n", orUnknown(installation.Version))                    // nolint: errcheck
+	fmt.Fprintf(w, "Image:\t%s\n", installation.Image)                                   // nolint: errcheck
+	fmt.Fprintf(w, "Ready:\t%d/%d\n", installation.ReadyReplicas, installation.Replicas) // nolint: errcheck
+	fmt.Fprintf(w, "kubectl bind:\t%s\n", kubectlkonnector.CLIVersion())                 // nolint: errcheck
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if warning := kubectlkonnector.CompatibilityWarning(installation.Version); warning != "" {
+		fmt.Fprintf(s.Options.ErrOut, "Warning: %s\n", warning) // nolint: errcheck
+	}
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "<unknown>"
+	}
+	return s
+}
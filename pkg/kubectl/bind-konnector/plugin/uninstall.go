@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+)
+
+// UninstallOptions are the options for the kubectl-bind-konnector-uninstall command.
+type UninstallOptions struct {
+	Options *base.Options
+
+	// Force uninstalls even if APIServiceBindings exist. Their deletion will block
+	// until the konnector is installed again.
+	Force bool
+}
+
+// NewUninstallOptions returns new UninstallOptions.
+func NewUninstallOptions(streams genericclioptions.IOStreams) *UninstallOptions {
+	return &UninstallOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (u *UninstallOptions) BindFlags(cmd *cobra.Command) {
+	u.Options.BindFlags(cmd)
+
+	cmd.Flags().BoolVar(&u.Force, "force", u.Force, "Uninstall even if APIServiceBindings exist. They are not synced anymore, and their deletion blocks until the konnector is installed again")
+}
+
+// Complete ensures all fields are initialized.
+func (u *UninstallOptions) Complete(args []string) error {
+	return u.Options.Complete()
+}
+
+// Validate validates the UninstallOptions are complete and usable.
+func (u *UninstallOptions) Validate() error {
+	return u.Options.Validate()
+}
+
+// Run deletes the konnector.
+func (u *UninstallOptions) Run(ctx context.Context) error {
+	config, err := u.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	installation, err := kubectlkonnector.Installed(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+	if installation == nil {
+		fmt.Fprintf(u.Options.Out, "The konnector is not installed.\n") // nolint: errcheck
+		return nil
+	}
+
+	bindings, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	} else if err == nil && len(bindings.Items) > 0 && !u.Force {
+		return fmt.Errorf("there are %d APIServiceBindings. Unbind them with \"kubectl bind unbind\" first, or use --force", len(bindings.Items))
+	}
+
+	if err := kubectlkonnector.Uninstall(ctx, config); err != nil {
+		return err
+	}
+	fmt.Fprintf(u.Options.Out, "Uninstalled the konnector.\n") // nolint: errcheck
+
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+)
+
+// UpgradeOptions are the options for the kubectl-bind-konnector-upgrade command.
+type UpgradeOptions struct {
+	Options   *base.Options
+	Konnector kubectlkonnector.Options
+}
+
+// NewUpgradeOptions returns new UpgradeOptions.
+func NewUpgradeOptions(streams genericclioptions.IOStreams) *UpgradeOptions {
+	return &UpgradeOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (u *UpgradeOptions) BindFlags(cmd *cobra.Command) {
+	u.Options.BindFlags(cmd)
+	u.Konnector.BindFlags(cmd)
+}
+
+// Complete ensures all fields are initialized.
+func (u *UpgradeOptions) Complete(args []string) error {
+	return u.Options.Complete()
+}
+
+// Validate validates the UpgradeOptions are complete and usable.
+func (u *UpgradeOptions) Validate() error {
+	return u.Options.Validate()
+}
+
+// Run applies the konnector manifests of the chosen version.
+func (u *UpgradeOptions) Run(ctx context.Context) error {
+	config, err := u.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	installation, err := kubectlkonnector.Installed(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+	if installation == nil {
+		return errors.New("the konnector is not installed. Use \"kubectl bind\" to install it")
+	}
+
+	u.Konnector.KeepInstalled(installation)
+	image, version := u.Konnector.Resolve()
+	if installation.Version == version && installation.Image == image && installation.Webhook == u.Konnector.Webhook {
+		fmt.Fprintf(u.Options.Out, "The konnector %s is up to date.\n", version) // nolint: errcheck
+		return nil
+	}
+
+	if err := konnector.Bootstrap(ctx, discoveryClient, dynamicClient, u.Konnector.Batteries(), u.Konnector.BootstrapOption()); err != nil {
+		return err
+	}
+	fmt.Fprintf(u.Options.Out, "Upgraded the konnector from %s to %s (%s).\n", orUnknown(installation.Version), version, image) // nolint: errcheck
+
+	if installation.Webhook && !u.Konnector.Webhook {
+		// the konnector without webhook does not maintain the configuration anymore
+		err := kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, webhook.ConfigurationName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		fmt.Fprintf(u.Options.Out, "Disabled the konnector webhook.\n") // nolint: errcheck
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind-list/plugin"
+)
+
+var (
+	listExampleUses = `
+	# list the APIServiceBindings with provider, bound resources, heartbeat and synced objects.
+	%[1]s list
+
+	# list the APIServiceBindings with the kubeconfig secret and the reason why they are not ready.
+	%[1]s list -o wide
+
+	# list the APIServiceBindings as YAML.
+	%[1]s list -o yaml
+	`
+)
+
+func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
+	opts := plugin.NewListOptions(streams)
+	cmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List the bound API services",
+		Example:      fmt.Sprintf(listExampleUses, "kubectl bind"),
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yellow := color.New(color.BgRed, color.FgBlack).SprintFunc()
+			fmt.Fprintf(streams.ErrOut, yellow("DISCLAIMER: This is a prototype. It will change in incompatible ways at any time.")+"\n\n") // nolint: errcheck
+
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	opts.BindFlags(cmd)
+
+	return cmd, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// ListOptions are the options for the kubectl-bind-list command.
+type ListOptions struct {
+	Options *base.Options
+
+	// Output is the output format, one of json, yaml or wide. Empty means table.
+	Output string
+}
+
+// NewListOptions returns new ListOptions.
+func NewListOptions(streams genericclioptions.IOStreams) *ListOptions {
+	return &ListOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (l *ListOptions) BindFlags(cmd *cobra.Command) {
+	l.Options.BindFlags(cmd)
+
+	cmd.Flags().StringVarP(&l.Output, "output", "o", l.Output, "Output format. One of: json|yaml|wide")
+}
+
+// Complete ensures all fields are initialized.
+func (l *ListOptions) Complete(args []string) error {
+	return l.Options.Complete()
+}
+
+// Validate validates the ListOptions are complete and usable.
+func (l *ListOptions) Validate() error {
+	if err := summary.ValidateOutput(l.Output); err != nil {
+		return err
+	}
+	return l.Options.Validate()
+}
+
+// Run prints the summaries of all APIServiceBindings.
+func (l *ListOptions) Run(ctx context.Context) error {
+	config, err := l.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clients, err := summary.NewClients(config)
+	if err != nil {
+		return err
+	}
+
+	bindings, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	summaries, err := summary.Summarize(ctx, clients, bindings.Items)
+	if err != nil {
+		return err
+	}
+
+	if printed, err := summary.PrintObject(l.Options.Out, l.Output, summaries); printed {
+		return err
+	}
+	if len(summaries) == 0 {
+		_, err := l.Options.ErrOut.Write([]byte("No APIServiceBindings found.\n"))
+		return err
+	}
+	return summary.PrintTable(l.Options.Out, summaries, l.Output == summary.OutputWide)
+}
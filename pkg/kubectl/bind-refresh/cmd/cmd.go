@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind-refresh/plugin"
+)
+
+var (
+	refreshExampleUses = `
+	# rotate the kubeconfig of an APIServiceBinding with the cached session, or a browser login if it has expired.
+	%[1]s refresh mangodbs
+
+	# rotate the kubeconfig in CI, failing if a browser login is needed.
+	%[1]s refresh mangodbs --no-browser
+
+	# revoke the cached session of the service provider of an APIServiceBinding.
+	%[1]s refresh mangodbs --revoke
+	`
+)
+
+func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
+	opts := plugin.NewRefreshOptions(streams)
+	cmd := &cobra.Command{
+		Use:          "refresh <apiservicebinding-name>",
+		Short:        "Refresh the service provider credentials of a bound API service",
+		Example:      fmt.Sprintf(refreshExampleUses, "kubectl bind"),
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yellow := color.New(color.BgRed, color.FgBlack).SprintFunc()
+			fmt.Fprintf(streams.ErrOut, yellow("DISCLAIMER: This is a prototype. It will change in incompatible ways at any time.")+"\n\n") // nolint: errcheck
+
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			if err := opts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			return opts.Run(cmd.Context())
+		},
+	}
+	opts.BindFlags(cmd)
+
+	return cmd, nil
+}
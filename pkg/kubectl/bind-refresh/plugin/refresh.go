@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	bindplugin "github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin/resources"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/session"
+)
+
+// RefreshOptions are the options for the kubectl-bind-refresh command.
+type RefreshOptions struct {
+	Options *base.Options
+
+	// NoBrowser fails instead of falling back to a browser login if the session has expired.
+	NoBrowser bool
+	// Headless authenticates on any device if falling back to a browser login.
+	Headless bool
+	// Revoke revokes the cached session at the service provider instead of refreshing.
+	Revoke bool
+
+	// name is the name of the APIServiceBinding.
+	name string
+}
+
+// NewRefreshOptions returns new RefreshOptions.
+func NewRefreshOptions(streams genericclioptions.IOStreams) *RefreshOptions {
+	return &RefreshOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (r *RefreshOptions) BindFlags(cmd *cobra.Command) {
+	r.Options.BindFlags(cmd)
+
+	cmd.Flags().BoolVar(&r.NoBrowser, "no-browser", r.NoBrowser, "Fail instead of falling back to a browser login if the cached session has expired")
+	cmd.Flags().BoolVar(&r.Headless, "headless", r.Headless, "If falling back to a browser login, authenticate on any device, without a callback to localhost")
+	cmd.Flags().BoolVar(&r.Revoke, "revoke", r.Revoke, "Revoke the cached session at the service provider and remove it, instead of refreshing the credentials")
+}
+
+// Complete ensures all fields are initialized.
+func (r *RefreshOptions) Complete(args []string) error {
+	if err := r.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		r.name = args[0]
+	}
+	return nil
+}
+
+// Validate validates the RefreshOptions are complete and usable.
+func (r *RefreshOptions) Validate() error {
+	if r.name == "" {
+		return errors.New("name is required")
+	}
+	if r.NoBrowser && r.Headless {
+		return errors.New("--no-browser and --headless are mutually exclusive")
+	}
+	if r.Revoke && (r.NoBrowser || r.Headless) {
+		return errors.New("--revoke cannot be combined with --no-browser or --headless")
+	}
+	return r.Options.Validate()
+}
+
+// Run rotates the kubeconfig secret of the APIServiceBinding in place, with the cached session
+// of the service provider, or with a browser login if the session has expired.
+func (r *RefreshOptions) Run(ctx context.Context) error {
+	config, err := r.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	binding, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, r.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	ref := binding.Spec.KubeconfigSecretRef
+	secret, err := kubeClient.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	providerURL := secret.Annotations[resources.ProviderURLAnnotationKey]
+	if providerURL == "" {
+		return fmt.Errorf("kubeconfig secret %s/%s has no %s annotation. Rerun \"kubectl bind\" with the url of the service provider", ref.Namespace, ref.Name, resources.ProviderURLAnnotationKey)
+	}
+
+	// the kubeconfig is shared by all bindings using the secret
+	bindings, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	exports := exportsUsingSecret(bindings.Items, ref.Namespace, ref.Name)
+
+	store := session.NewStore("")
+	s, err := store.Get(providerURL)
+	if err != nil {
+		return err
+	}
+	if r.Revoke {
+		return r.revoke(ctx, store, s, providerURL)
+	}
+	if s == nil {
+		fmt.Fprintf(r.Options.ErrOut, "No cached session for %s.\n", providerURL) // nolint: errcheck
+		return r.login(ctx, providerURL, ref.Namespace, false)
+	}
+	if s.ID != secret.Annotations[resources.ClusterIDAnnotationKey] {
+		fmt.Fprintf(r.Options.ErrOut, "The cached session for %s is of identity %s, not of %s.\n", providerURL, s.ID, secret.Annotations[resources.ClusterIDAnnotationKey]) // nolint: errcheck
+		return r.login(ctx, providerURL, ref.Namespace, true)
+	}
+
+	response, err := session.Refresh(ctx, s, exports)
+	if errors.Is(err, session.ErrExpired) {
+		fmt.Fprintf(r.Options.ErrOut, "The cached session for %s has expired.\n", providerURL) // nolint: errcheck
+		if err := store.Delete(providerURL); err != nil {
+			return err
+		}
+		return r.login(ctx, providerURL, ref.Namespace, true)
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := resources.EnsureServiceBindingAuthData(ctx, string(response.Kubeconfig), response.ID, providerURL, ref.Namespace, ref.Name, kubeClient); err != nil {
+		return err
+	}
+	// the service provider replaces the session token on every refresh
+	if response.SessionToken != "" {
+		s.Token = response.SessionToken
+		s.CreatedAt = time.Now()
+		if err := store.Save(s); err != nil {
+			fmt.Fprintf(r.Options.ErrOut, "Warning: failed to cache session: %v\n", err) // nolint: errcheck
+		}
+	} else if err := store.Delete(providerURL); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Options.Out, "Refreshed the credentials of %d APIServiceBindings using secret %s/%s.\n", len(exports), ref.Namespace, ref.Name) // nolint: errcheck
+
+	return nil
+}
+
+// revoke revokes the cached session at the service provider and removes it locally.
+func (r *RefreshOptions) revoke(ctx context.Context, store *session.Store, s *session.Session, providerURL string) error {
+	if s == nil {
+		fmt.Fprintf(r.Options.Out, "No cached session for %s.\n", providerURL) // nolint: errcheck
+		return nil
+	}
+	if err := session.Revoke(ctx, s); err != nil {
+		return err
+	}
+	if err := store.Delete(providerURL); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Options.Out, "Revoked the cached session for %s.\n", providerURL) // nolint: errcheck
+	return nil
+}
+
+// login falls back to the browser login of kubectl bind, which updates the credentials of
+// existing bindings. The session is cached again if there was a cached session before.
+func (r *RefreshOptions) login(ctx context.Context, providerURL, secretNamespace string, cacheSession bool) error {
+	if r.NoBrowser {
+		return errors.New("a browser login is required, but --no-browser is set")
+	}
+	fmt.Fprintf(r.Options.ErrOut, "Falling back to a browser login. Select the same resources to update their credentials.\n") // nolint: errcheck
+
+	opts := bindplugin.NewBindOptions(r.Options.IOStreams)
+	opts.Options = r.Options
+	opts.SkipKonnector = true
+	opts.Headless = r.Headless
+	opts.CacheSession = cacheSession
+	opts.SecretNamespace = secretNamespace
+	if err := opts.Complete([]string{providerURL}); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return opts.Run(ctx, nil)
+}
+
+func exportsUsingSecret(bindings []kubebindv1alpha1.APIServiceBinding, namespace, name string) []string {
+	var exports []string
+	for _, b := range bindings {
+		if b.Spec.KubeconfigSecretRef.Namespace == namespace && b.Spec.KubeconfigSecretRef.Name == name {
+			exports = append(exports, b.Spec.Export)
+		}
+	}
+	sort.Strings(exports)
+	return exports
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// StatusOptions are the options for the kubectl-bind-status command.
+type StatusOptions struct {
+	Options *base.Options
+
+	// Output is the output format, one of json, yaml or wide. Empty means human readable.
+	Output string
+
+	// name is the name of the APIServiceBinding.
+	name string
+}
+
+// NewStatusOptions returns new StatusOptions.
+func NewStatusOptions(streams genericclioptions.IOStreams) *StatusOptions {
+	return &StatusOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (s *StatusOptions) BindFlags(cmd *cobra.Command) {
+	s.Options.BindFlags(cmd)
+
+	cmd.Flags().StringVarP(&s.Output, "output", "o", s.Output, "Output format. One of: json|yaml|wide")
+}
+
+// Complete ensures all fields are initialized.
+func (s *StatusOptions) Complete(args []string) error {
+	if err := s.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		s.name = args[0]
+	}
+	return nil
+}
+
+// Validate validates the StatusOptions are complete and usable.
+func (s *StatusOptions) Validate() error {
+	if s.name == "" {
+		return errors.New("name is required")
+	}
+	if err := summary.ValidateOutput(s.Output); err != nil {
+		return err
+	}
+	return s.Options.Validate()
+}
+
+// Run prints the summary of the APIServiceBinding.
+func (s *StatusOptions) Run(ctx context.Context) error {
+	config, err := s.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clients, err := summary.NewClients(config)
+	if err != nil {
+		return err
+	}
+
+	binding, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, s.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	summaries, err := summary.Summarize(ctx, clients, []kubebindv1alpha1.APIServiceBinding{*binding})
+	if err != nil {
+		return err
+	}
+
+	if printed, err := summary.PrintObject(s.Options.Out, s.Output, summaries[0]); printed {
+		return err
+	}
+	return summary.Describe(s.Options.Out, &summaries[0], s.Output == summary.OutputWide)
+}
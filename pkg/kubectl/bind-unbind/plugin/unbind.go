@@ -0,0 +1,345 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin/resources"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+// UnbindOptions are the options for the kubectl-bind-unbind command.
+type UnbindOptions struct {
+	Options *base.Options
+
+	// DryRun prints what would be deleted, without deleting anything.
+	DryRun bool
+	// KeepCRDs keeps the CustomResourceDefinitions and the objects in the consumer cluster.
+	KeepCRDs bool
+	// DeleteCRDs deletes the CustomResourceDefinitions and the objects in the consumer cluster,
+	// and at the service provider. Without KeepCRDs or DeleteCRDs, the CRD retention policy of
+	// the APIServiceBinding applies.
+	DeleteCRDs bool
+	// RemoveKonnector removes the konnector when no APIServiceBinding is left.
+	RemoveKonnector bool
+	// Timeout is how long to wait for the konnector to clean up the binding.
+	Timeout time.Duration
+
+	// name is the name of the APIServiceBinding.
+	name string
+}
+
+// NewUnbindOptions returns new UnbindOptions.
+func NewUnbindOptions(streams genericclioptions.IOStreams) *UnbindOptions {
+	return &UnbindOptions{
+		Options: base.NewOptions(streams),
+		Timeout: 5 * time.Minute,
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (u *UnbindOptions) BindFlags(cmd *cobra.Command) {
+	u.Options.BindFlags(cmd)
+
+	cmd.Flags().BoolVar(&u.DryRun, "dry-run", u.DryRun, "Only print what would be deleted")
+	cmd.Flags().BoolVar(&u.KeepCRDs, "keep-crds", u.KeepCRDs, "Keep the CustomResourceDefinitions and the objects in this cluster. They are not synced anymore")
+	cmd.Flags().BoolVar(&u.DeleteCRDs, "delete-crds", u.DeleteCRDs, "Delete the CustomResourceDefinitions and the objects in this cluster and at the service provider, even if the APIServiceBinding retains them")
+	cmd.Flags().BoolVar(&u.RemoveKonnector, "remove-konnector", u.RemoveKonnector, "Remove the konnector if this was the last APIServiceBinding")
+	cmd.Flags().DurationVar(&u.Timeout, "timeout", u.Timeout, "How long to wait for the konnector to clean up the APIServiceBinding")
+}
+
+// Complete ensures all fields are initialized.
+func (u *UnbindOptions) Complete(args []string) error {
+	if err := u.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		u.name = args[0]
+	}
+	return nil
+}
+
+// Validate validates the UnbindOptions are complete and usable.
+func (u *UnbindOptions) Validate() error {
+	if u.name == "" {
+		return errors.New("name is required")
+	}
+	if u.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if u.KeepCRDs && u.DeleteCRDs {
+		return errors.New("--keep-crds and --delete-crds are mutually exclusive")
+	}
+	return u.Options.Validate()
+}
+
+// plan is what unbinding will delete or keep.
+type plan struct {
+	binding *kubebindv1alpha1.APIServiceBinding
+
+	// crdRetention is what happens to the CustomResourceDefinitions of the binding.
+	crdRetention kubebindv1alpha1.CRDRetentionPolicy
+
+	// resources are the CustomResourceDefinitions of the binding.
+	resources []summary.Resource
+	// sharedWith maps CustomResourceDefinitions to other bindings keeping them.
+	sharedWith map[string][]string
+
+	// deleteSecret is true if no other binding references the kubeconfig secret.
+	deleteSecret bool
+	// removeKonnector is true if no other binding is left.
+	removeKonnector bool
+}
+
+// Run deletes the APIServiceBinding, and cleans up after the konnector is done with it.
+func (u *UnbindOptions) Run(ctx context.Context) error {
+	config, err := u.Options.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	clients, err := summary.NewClients(config)
+	if err != nil {
+		return err
+	}
+
+	p, err := u.plan(ctx, bindClient, clients)
+	if err != nil {
+		return err
+	}
+	u.printPlan(p)
+	if u.DryRun {
+		return nil
+	}
+
+	if p.crdRetention == kubebindv1alpha1.CRDRetentionPolicyRetain && !u.KeepCRDs && p.deletableResources() > 0 && u.interactive() {
+		confirmed, err := u.confirm("Delete the CustomResourceDefinitions instead, with all objects in this cluster and at the service provider?")
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			p.crdRetention = kubebindv1alpha1.CRDRetentionPolicyDelete
+		}
+	}
+
+	if policy := p.crdRetention; p.binding.Spec.CRDRetention != policy {
+		patch := fmt.Sprintf(`{"spec":{"crdRetention":%q}}`, policy)
+		if _, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Patch(ctx, u.name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to set CRD retention policy of APIServiceBinding %q: %w", u.name, err)
+		}
+	}
+
+	if err := bindClient.KubeBindV1alpha1().APIServiceBindings().Delete(ctx, u.name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	fmt.Fprintf(u.Options.ErrOut, "Waiting for the konnector to clean up APIServiceBinding %q.\n", u.name) // nolint: errcheck
+	if err := wait.PollImmediateWithContext(ctx, time.Second, u.Timeout, func(ctx context.Context) (bool, error) {
+		_, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, u.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return fmt.Errorf("APIServiceBinding %q is still there, is the konnector running? %w", u.name, err)
+	}
+	fmt.Fprintf(u.Options.ErrOut, "APIServiceBinding %q deleted.\n", u.name) // nolint: errcheck
+
+	// the konnector might have bound more in the meantime. Check again.
+	bindings, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	ref := p.binding.Spec.KubeconfigSecretRef
+	if p.deleteSecret && !referencesSecret(bindings.Items, ref.Namespace, ref.Name) {
+		err := clients.Kube.CoreV1().Secrets(ref.Namespace).Delete(ctx, ref.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		fmt.Fprintf(u.Options.ErrOut, "Secret %s/%s deleted.\n", ref.Namespace, ref.Name) // nolint: errcheck
+	}
+	if p.removeKonnector && len(bindings.Items) == 0 {
+		if err := kubectlkonnector.Uninstall(ctx, config); err != nil {
+			return err
+		}
+		fmt.Fprintf(u.Options.ErrOut, "Konnector removed.\n") // nolint: errcheck
+	}
+
+	return nil
+}
+
+func (u *UnbindOptions) plan(ctx context.Context, bindClient bindclient.Interface, clients *summary.Clients) (*plan, error) {
+	bindings, err := bindClient.KubeBindV1alpha1().APIServiceBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var binding *kubebindv1alpha1.APIServiceBinding
+	var others []kubebindv1alpha1.APIServiceBinding
+	for i := range bindings.Items {
+		if bindings.Items[i].Name == u.name {
+			binding = &bindings.Items[i]
+		} else {
+			others = append(others, bindings.Items[i])
+		}
+	}
+	if binding == nil {
+		return nil, apierrors.NewNotFound(kubebindv1alpha1.SchemeGroupVersion.WithResource("apiservicebindings").GroupResource(), u.name)
+	}
+
+	summaries, err := summary.Summarize(ctx, clients, []kubebindv1alpha1.APIServiceBinding{*binding})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan{
+		binding:      binding,
+		crdRetention: binding.Spec.CRDRetention,
+		resources:    summaries[0].Resources,
+		sharedWith:   map[string][]string{},
+	}
+	switch {
+	case u.KeepCRDs:
+		p.crdRetention = kubebindv1alpha1.CRDRetentionPolicyRetain
+	case u.DeleteCRDs:
+		p.crdRetention = kubebindv1alpha1.CRDRetentionPolicyDelete
+	case p.crdRetention == "":
+		p.crdRetention = kubebindv1alpha1.CRDRetentionPolicyRetain
+	}
+	for _, r := range p.resources {
+		crd, err := clients.Apiextensions.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, r.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range crd.OwnerReferences {
+			parts := strings.SplitN(ref.APIVersion, "/", 2)
+			if parts[0] == kubebindv1alpha1.SchemeGroupVersion.Group && ref.Kind == "APIServiceBinding" && ref.Name != u.name {
+				p.sharedWith[r.Name] = append(p.sharedWith[r.Name], ref.Name)
+			}
+		}
+		sort.Strings(p.sharedWith[r.Name])
+	}
+
+	// only delete secrets created by kubectl bind
+	ref := binding.Spec.KubeconfigSecretRef
+	if !referencesSecret(others, ref.Namespace, ref.Name) {
+		secret, err := clients.Kube.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		} else if err == nil {
+			_, p.deleteSecret = secret.Annotations[resources.ClusterIDAnnotationKey]
+		}
+	}
+	p.removeKonnector = u.RemoveKonnector && len(others) == 0
+
+	return p, nil
+}
+
+func (u *UnbindOptions) printPlan(p *plan) {
+	out := u.Options.Out
+	verb := "will be"
+	if u.DryRun {
+		verb = "would be"
+	}
+
+	fmt.Fprintf(out, "APIServiceBinding %q %s deleted.\n", p.binding.Name, verb) // nolint: errcheck
+	for _, r := range p.resources {
+		switch {
+		case len(p.sharedWith[r.Name]) > 0:
+			fmt.Fprintf(out, "CustomResourceDefinition %q with %d objects %s kept, it is still bound by %s.\n", r.Name, r.Objects, verb, strings.Join(p.sharedWith[r.Name], ", ")) // nolint: errcheck
+		case p.crdRetention == kubebindv1alpha1.CRDRetentionPolicyRetain:
+			fmt.Fprintf(out, "CustomResourceDefinition %q with %d objects %s kept, but not synced anymore.\n", r.Name, r.Objects, verb) // nolint: errcheck
+		default:
+			fmt.Fprintf(out, "CustomResourceDefinition %q %s deleted, with %d objects and their copies at the service provider.\n", r.Name, verb, r.Objects) // nolint: errcheck
+		}
+	}
+
+	ref := p.binding.Spec.KubeconfigSecretRef
+	if p.deleteSecret {
+		fmt.Fprintf(out, "Secret %s/%s %s deleted.\n", ref.Namespace, ref.Name, verb) // nolint: errcheck
+	} else {
+		fmt.Fprintf(out, "Secret %s/%s %s kept.\n", ref.Namespace, ref.Name, verb) // nolint: errcheck
+	}
+	if p.removeKonnector {
+		fmt.Fprintf(out, "Konnector in namespace %q %s removed.\n", konnector.Namespace, verb) // nolint: errcheck
+	}
+}
+
+// deletableResources returns the number of CustomResourceDefinitions not bound by other bindings.
+func (p *plan) deletableResources() int {
+	n := 0
+	for _, r := range p.resources {
+		if len(p.sharedWith[r.Name]) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// interactive returns true if the user can be asked for confirmation.
+func (u *UnbindOptions) interactive() bool {
+	f, ok := u.Options.In.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// confirm asks the user the given yes/no question, defaulting to no.
+func (u *UnbindOptions) confirm(question string) (bool, error) {
+	fmt.Fprintf(u.Options.Out, "%s [y/N]: ", question) // nolint: errcheck
+	answer, err := bufio.NewReader(u.Options.In).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+func referencesSecret(bindings []kubebindv1alpha1.APIServiceBinding, namespace, name string) bool {
+	for _, b := range bindings {
+		if b.Spec.KubeconfigSecretRef.Namespace == namespace && b.Spec.KubeconfigSecretRef.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	bindfake "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned/fake"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin/resources"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/summary"
+)
+
+func newBinding(name, secret string, retention kubebindv1alpha1.CRDRetentionPolicy) *kubebindv1alpha1.APIServiceBinding {
+	return &kubebindv1alpha1.APIServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: kubebindv1alpha1.APIServiceBindingSpec{
+			Export:       name,
+			CRDRetention: retention,
+			KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+				LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: secret, Key: "kubeconfig"},
+				Namespace:         "kube-bind",
+			},
+		},
+	}
+}
+
+func newCRD(name string, bindings ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	for _, b := range bindings {
+		crd.OwnerReferences = append(crd.OwnerReferences, metav1.OwnerReference{
+			APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "APIServiceBinding",
+			Name:       b,
+		})
+	}
+	return crd
+}
+
+func newSecret(name string, createdByBind bool) *corev1.Secret {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: name}}
+	if createdByBind {
+		secret.Annotations = map[string]string{resources.ClusterIDAnnotationKey: "cluster"}
+	}
+	return secret
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings []runtime.Object
+		crds     []runtime.Object
+		secrets  []runtime.Object
+		options  UnbindOptions
+
+		wantRetention       kubebindv1alpha1.CRDRetentionPolicy
+		wantResources       []string
+		wantSharedWith      map[string][]string
+		wantDeleteSecret    bool
+		wantRemoveKonnector bool
+		wantOutput          []string
+	}{
+		{
+			name:                "last binding",
+			bindings:            []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", "")},
+			crds:                []runtime.Object{newCRD("mangodbs.mangodb.com", "mangodbs"), newCRD("others.example.com", "other")},
+			secrets:             []runtime.Object{newSecret("kubeconfig-abc", true)},
+			options:             UnbindOptions{RemoveKonnector: true},
+			wantRetention:       kubebindv1alpha1.CRDRetentionPolicyRetain,
+			wantResources:       []string{"mangodbs.mangodb.com"},
+			wantSharedWith:      map[string][]string{},
+			wantDeleteSecret:    true,
+			wantRemoveKonnector: true,
+			wantOutput: []string{
+				`APIServiceBinding "mangodbs" would be deleted.`,
+				`CustomResourceDefinition "mangodbs.mangodb.com" with 0 objects would be kept, but not synced anymore.`,
+				`Secret kube-bind/kubeconfig-abc would be deleted.`,
+				`Konnector in namespace "kube-bind" would be removed.`,
+			},
+		},
+		{
+			name:             "delete policy of the binding",
+			bindings:         []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", kubebindv1alpha1.CRDRetentionPolicyDelete)},
+			crds:             []runtime.Object{newCRD("mangodbs.mangodb.com", "mangodbs")},
+			secrets:          []runtime.Object{newSecret("kubeconfig-abc", true)},
+			wantRetention:    kubebindv1alpha1.CRDRetentionPolicyDelete,
+			wantResources:    []string{"mangodbs.mangodb.com"},
+			wantSharedWith:   map[string][]string{},
+			wantDeleteSecret: true,
+			wantOutput: []string{
+				`APIServiceBinding "mangodbs" would be deleted.`,
+				`CustomResourceDefinition "mangodbs.mangodb.com" would be deleted, with 0 objects and their copies at the service provider.`,
+				`Secret kube-bind/kubeconfig-abc would be deleted.`,
+			},
+		},
+		{
+			name:             "keep-crds overrides the policy",
+			bindings:         []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", kubebindv1alpha1.CRDRetentionPolicyDelete)},
+			crds:             []runtime.Object{newCRD("mangodbs.mangodb.com", "mangodbs")},
+			secrets:          []runtime.Object{newSecret("kubeconfig-abc", true)},
+			options:          UnbindOptions{KeepCRDs: true},
+			wantRetention:    kubebindv1alpha1.CRDRetentionPolicyRetain,
+			wantResources:    []string{"mangodbs.mangodb.com"},
+			wantSharedWith:   map[string][]string{},
+			wantDeleteSecret: true,
+		},
+		{
+			name:           "delete-crds overrides the policy",
+			bindings:       []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", kubebindv1alpha1.CRDRetentionPolicyRetain)},
+			crds:           []runtime.Object{newCRD("mangodbs.mangodb.com", "mangodbs")},
+			options:        UnbindOptions{DeleteCRDs: true},
+			wantRetention:  kubebindv1alpha1.CRDRetentionPolicyDelete,
+			wantResources:  []string{"mangodbs.mangodb.com"},
+			wantSharedWith: map[string][]string{},
+		},
+		{
+			name: "shared CRD and secret",
+			bindings: []runtime.Object{
+				newBinding("mangodbs", "kubeconfig-abc", kubebindv1alpha1.CRDRetentionPolicyDelete),
+				newBinding("other", "kubeconfig-abc", ""),
+			},
+			crds:           []runtime.Object{newCRD("mangodbs.mangodb.com", "other", "mangodbs")},
+			secrets:        []runtime.Object{newSecret("kubeconfig-abc", true)},
+			options:        UnbindOptions{RemoveKonnector: true},
+			wantRetention:  kubebindv1alpha1.CRDRetentionPolicyDelete,
+			wantResources:  []string{"mangodbs.mangodb.com"},
+			wantSharedWith: map[string][]string{"mangodbs.mangodb.com": {"other"}},
+			wantOutput: []string{
+				`APIServiceBinding "mangodbs" would be deleted.`,
+				`CustomResourceDefinition "mangodbs.mangodb.com" with 0 objects would be kept, it is still bound by other.`,
+				`Secret kube-bind/kubeconfig-abc would be kept.`,
+			},
+		},
+		{
+			name:           "secret not created by kubectl bind",
+			bindings:       []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", "")},
+			secrets:        []runtime.Object{newSecret("kubeconfig-abc", false)},
+			wantRetention:  kubebindv1alpha1.CRDRetentionPolicyRetain,
+			wantSharedWith: map[string][]string{},
+		},
+		{
+			name:           "missing secret",
+			bindings:       []runtime.Object{newBinding("mangodbs", "kubeconfig-abc", "")},
+			wantRetention:  kubebindv1alpha1.CRDRetentionPolicyRetain,
+			wantSharedWith: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			u := tt.options
+			u.Options = base.NewOptions(genericclioptions.IOStreams{Out: &out})
+			u.DryRun = true
+			u.name = "mangodbs"
+			clients := &summary.Clients{
+				Kube:          kubefake.NewSimpleClientset(tt.secrets...),
+				Apiextensions: apiextensionsfake.NewSimpleClientset(tt.crds...),
+				Dynamic:       dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+			}
+
+			p, err := u.plan(context.Background(), bindfake.NewSimpleClientset(tt.bindings...), clients)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRetention, p.crdRetention)
+			var names []string
+			for _, r := range p.resources {
+				names = append(names, r.Name)
+			}
+			require.Equal(t, tt.wantResources, names)
+			require.Equal(t, tt.wantSharedWith, p.sharedWith)
+			require.Equal(t, tt.wantDeleteSecret, p.deleteSecret)
+			require.Equal(t, tt.wantRemoveKonnector, p.removeKonnector)
+
+			if tt.wantOutput != nil {
+				u.printPlan(p)
+				require.Equal(t, tt.wantOutput, strings.Split(strings.TrimSpace(out.String()), "\n"))
+			}
+		})
+	}
+}
+
+func TestPlanNotFound(t *testing.T) {
+	u := UnbindOptions{name: "mangodbs"}
+	clients := &summary.Clients{
+		Kube:          kubefake.NewSimpleClientset(),
+		Apiextensions: apiextensionsfake.NewSimpleClientset(),
+		Dynamic:       dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+	}
+	_, err := u.plan(context.Background(), bindfake.NewSimpleClientset(newBinding("other", "kubeconfig-abc", "")), clients)
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func TestReferencesSecret(t *testing.T) {
+	bindings := []kubebindv1alpha1.APIServiceBinding{
+		*newBinding("a", "kubeconfig-abc", ""),
+		*newBinding("b", "kubeconfig-def", ""),
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		secret    string
+		want      bool
+	}{
+		{name: "referenced", namespace: "kube-bind", secret: "kubeconfig-def", want: true},
+		{name: "other name", namespace: "kube-bind", secret: "kubeconfig-xyz"},
+		{name: "other namespace", namespace: "default", secret: "kubeconfig-abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, referencesSecret(bindings, tt.namespace, tt.secret))
+		})
+	}
+	require.False(t, referencesSecret(nil, "kube-bind", "kubeconfig-abc"))
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: " Yes \n", want: true},
+		{input: "n\n"},
+		{input: "\n"},
+		{input: ""},
+		{input: "yes please\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+			u := UnbindOptions{Options: base.NewOptions(genericclioptions.IOStreams{In: strings.NewReader(tt.input), Out: &out})}
+			got, err := u.confirm("Delete?")
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, "Delete? [y/N]: ", out.String())
+		})
+	}
+}
@@ -32,6 +32,24 @@ var (
 	bindExampleUses = `
 	# binds to the given remote API service
 	%[1]s bind apiservice https://mangodb.com/exports
+
+	# authenticate in a browser on any device, e.g. when connected via SSH
+	%[1]s bind https://mangodb.com/exports --headless
+
+	# cache the session of the service provider, to refresh the credentials later with "kubectl bind refresh" without a browser
+	%[1]s bind https://mangodb.com/exports --cache-session
+
+	# name the APIServiceBindings after the service provider, with the kubeconfig in a pre-existing namespace
+	%[1]s bind https://mangodb.com/exports --secret-namespace team-a --name-template "{{.Resource}}.{{.Group}}-{{.Provider}}"
+
+	# authenticate and print the manifests for the konnector, the kubeconfig secret and the binding, without changing the cluster
+	%[1]s bind https://mangodb.com/exports --dry-run=client -o yaml
+
+	# authenticate and write the manifests to a GitOps repository, with a SealedSecret placeholder for the kubeconfig
+	%[1]s bind https://mangodb.com/exports --output-dir ./clusters/prod/kube-bind --secret-format=SealedSecret
+
+	# install the konnector at a pinned version
+	%[1]s bind https://mangodb.com/exports --konnector-version v0.1.0
 	`
 )
 
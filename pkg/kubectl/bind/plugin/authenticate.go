@@ -22,7 +22,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/mdp/qrterminal/v3"
 
@@ -52,23 +51,32 @@ func fetchAuthenticationRoute(url string) (*kubebindv1alpha1.APIServiceProvider,
 	return provider, nil
 }
 
-func authenticate(provider *kubebindv1alpha1.APIServiceProvider, authEndpoint, sessionID string, urlCh chan<- string) error {
+func authenticate(provider *kubebindv1alpha1.APIServiceProvider, authEndpoint, sessionID string, cacheSession bool, out io.Writer, urlCh chan<- string) error {
 	u, err := url.Parse(provider.Spec.AuthenticatedClientURL)
 	if err != nil {
 		return fmt.Errorf("failed to parse auth url: %v", err)
 	}
 
 	values := u.Query()
-	values.Add("u", authEndpoint)
+	if authEndpoint != "" {
+		values.Add("u", authEndpoint)
+	}
 	values.Add("s", sessionID)
+	if cacheSession {
+		values.Add("cache_session", "true")
+	}
 	u.RawQuery = values.Encode()
 
-	fmt.Printf("\nTo authenticate, visit %s in your browser or scan the QRCode below:\n\n", u.String())
+	if authEndpoint == "" {
+		fmt.Fprintf(out, "\nTo authenticate, visit %s in a browser on any device or scan the QRCode below:\n\n", u.String()) // nolint: errcheck
+	} else {
+		fmt.Fprintf(out, "\nTo authenticate, visit %s in your browser or scan the QRCode below:\n\n", u.String()) // nolint: errcheck
+	}
 
 	// TODO(sttts): callback backend, not 127.0.0.1
 	config := qrterminal.Config{
 		Level:     qrterminal.L,
-		Writer:    os.Stdout,
+		Writer:    out,
 		BlackChar: qrterminal.WHITE,
 		WhiteChar: qrterminal.BLACK,
 		QuietZone: 2,
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestAuthenticateURL(t *testing.T) {
+	provider := &kubebindv1alpha1.APIServiceProvider{
+		Spec: kubebindv1alpha1.APIServiceProviderSpec{AuthenticatedClientURL: "http://mangodb.example.com/authorize"},
+	}
+
+	tests := []struct {
+		name         string
+		authEndpoint string
+		cacheSession bool
+		want         url.Values
+	}{
+		{
+			name:         "browser",
+			authEndpoint: "http://127.0.0.1:1234/callback",
+			want:         url.Values{"u": {"http://127.0.0.1:1234/callback"}, "s": {"session"}},
+		},
+		{
+			name: "headless",
+			want: url.Values{"s": {"session"}},
+		},
+		{
+			name:         "cache session",
+			authEndpoint: "http://127.0.0.1:1234/callback",
+			cacheSession: true,
+			want:         url.Values{"u": {"http://127.0.0.1:1234/callback"}, "s": {"session"}, "cache_session": {"true"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urlCh := make(chan string, 1)
+			require.NoError(t, authenticate(provider, tt.authEndpoint, "session", tt.cacheSession, io.Discard, urlCh))
+
+			u, err := url.Parse(<-urlCh)
+			require.NoError(t, err)
+			require.Equal(t, "/authorize", u.Path)
+			require.Equal(t, tt.want, u.Query())
+		})
+	}
+}
@@ -18,10 +18,13 @@ package plugin
 
 import (
 	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -31,7 +34,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
-	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
@@ -46,6 +49,8 @@ import (
 	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
 	"github.com/kube-bind/kube-bind/pkg/kubectl/base"
 	"github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin/resources"
+	kubectlkonnector "github.com/kube-bind/kube-bind/pkg/kubectl/konnector"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/session"
 )
 
 // BindOptions contains the options for creating an APIBinding.
@@ -58,12 +63,41 @@ type BindOptions struct {
 
 	// skipKonnector skips the deployment of the konnector.
 	SkipKonnector bool
+	// Konnector pins the image and version of the deployed konnector.
+	Konnector kubectlkonnector.Options
+
+	// Headless authenticates without a callback on localhost, by polling the service provider
+	// while the user authenticates on any device.
+	Headless bool
+	// CacheSession stores the session token of the service provider, for "kubectl bind refresh".
+	CacheSession bool
+
+	// DryRun is "none" or "client". With "client", the manifests are printed instead
+	// of being applied to the cluster.
+	DryRun string
+	// Output is the format of the printed manifests, "yaml" or "json".
+	Output string
+	// OutputDir is a directory to write the manifests to, instead of applying them.
+	OutputDir string
+	// SecretFormat is the kind of the printed kubeconfig secret, "Secret" or "SealedSecret".
+	SecretFormat string
+
+	// SecretNamespace is the namespace of the kubeconfig secret.
+	SecretNamespace string
+	// NameTemplate is the text/template of the APIServiceBinding names.
+	NameTemplate string
+
+	nameTemplate *template.Template
 }
 
 // NewBindOptions returns new BindOptions.
 func NewBindOptions(streams genericclioptions.IOStreams) *BindOptions {
 	return &BindOptions{
-		Options: base.NewOptions(streams),
+		Options:         base.NewOptions(streams),
+		DryRun:          DryRunNone,
+		SecretFormat:    SecretFormatSecret,
+		SecretNamespace: DefaultSecretNamespace,
+		NameTemplate:    DefaultNameTemplate,
 	}
 }
 
@@ -72,6 +106,15 @@ func (b *BindOptions) AddCmdFlags(cmd *cobra.Command) {
 	b.Options.BindFlags(cmd)
 
 	cmd.Flags().BoolVar(&b.SkipKonnector, "skip-konnector", false, "Skip the deployment of the konnector")
+	b.Konnector.BindFlags(cmd)
+	cmd.Flags().BoolVar(&b.Headless, "headless", b.Headless, "Authenticate in a browser on any device, without a callback to localhost, e.g. over SSH or in CI")
+	cmd.Flags().BoolVar(&b.CacheSession, "cache-session", b.CacheSession, "Cache the session token of the service provider in ~/.kube/kube-bind/sessions, to refresh the credentials with \"kubectl bind refresh\" without a browser login")
+	cmd.Flags().StringVar(&b.DryRun, "dry-run", b.DryRun, "Must be \"none\" or \"client\". With \"client\", only print the manifests for the konnector, the kubeconfig secret and the APIServiceBinding, without changing the cluster")
+	cmd.Flags().StringVarP(&b.Output, "output", "o", b.Output, "Output format of the manifests with --dry-run=client or --output-dir. One of: yaml|json")
+	cmd.Flags().StringVar(&b.OutputDir, "output-dir", b.OutputDir, "Write the manifests to this directory instead of applying them, e.g. for GitOps")
+	cmd.Flags().StringVar(&b.SecretFormat, "secret-format", b.SecretFormat, "Kind of the kubeconfig secret manifest. One of: Secret|SealedSecret. SealedSecret requires --output-dir and writes a placeholder to be sealed with kubeseal")
+	cmd.Flags().StringVar(&b.SecretNamespace, "secret-namespace", b.SecretNamespace, "Namespace of the kubeconfig secret. It is created if it does not exist")
+	cmd.Flags().StringVar(&b.NameTemplate, "name-template", b.NameTemplate, "Go template of the APIServiceBinding names, with the fields .Resource, .Group, .Export, .Provider and .Host, e.g. \"{{.Resource}}.{{.Group}}-{{.Provider}}\". The template only names the APIServiceBinding objects: the CRDs are always named <resource>.<group>, hence a resource can be bound from one service provider per cluster only")
 }
 
 // Complete ensures all fields are initialized.
@@ -96,6 +139,30 @@ func (b *BindOptions) Validate() error {
 		return fmt.Errorf("invalid url %q: %w", b.URL, err)
 	}
 
+	if b.DryRun != DryRunNone && b.DryRun != DryRunClient {
+		return fmt.Errorf("invalid --dry-run %q, must be %q or %q", b.DryRun, DryRunNone, DryRunClient)
+	}
+	if b.Output != "" && b.Output != OutputYAML && b.Output != OutputJSON {
+		return fmt.Errorf("invalid --output %q, must be %q or %q", b.Output, OutputYAML, OutputJSON)
+	}
+	if b.Output != "" && !b.printsManifests() {
+		return errors.New("--output requires --dry-run=client or --output-dir")
+	}
+	if b.SecretFormat != SecretFormatSecret && b.SecretFormat != SecretFormatSealedSecret {
+		return fmt.Errorf("invalid --secret-format %q, must be %q or %q", b.SecretFormat, SecretFormatSecret, SecretFormatSealedSecret)
+	}
+	if b.SecretFormat == SecretFormatSealedSecret && b.OutputDir == "" {
+		return errors.New("--secret-format=SealedSecret requires --output-dir")
+	}
+	if errs := validation.IsDNS1123Label(b.SecretNamespace); len(errs) > 0 {
+		return fmt.Errorf("invalid --secret-namespace %q: %s", b.SecretNamespace, strings.Join(errs, ", "))
+	}
+	tmpl, err := parseNameTemplate(b.NameTemplate)
+	if err != nil {
+		return err
+	}
+	b.nameTemplate = tmpl
+
 	return b.Options.Validate()
 }
 
@@ -103,45 +170,57 @@ func (b *BindOptions) Validate() error {
 func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 	logger := klog.FromContext(ctx).WithValues("command", "bind", "url", b.URL)
 
-	config, err := b.ClientConfig.ClientConfig()
-	if err != nil {
-		return err
+	// keep stdout clean for the manifests
+	out := b.IOStreams.Out
+	if b.printsManifests() && b.OutputDir == "" {
+		out = b.IOStreams.ErrOut
 	}
-	kubeClient, err := kubeclient.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-	apiextensionsClient, err := apiextensionsclient.NewForConfig(config)
+
+	exportURL, err := url.Parse(b.URL)
 	if err != nil {
-		return err
+		return err // should never happen because we test this in Validate()
 	}
-	bindClient, err := bindclient.NewForConfig(config)
+
+	provider, err := fetchAuthenticationRoute(exportURL.String())
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to fetch authentication url %q: %v", exportURL, err)
 	}
 
 	var response *backendresources.AuthResponse
-	auth, err := authenticator.NewDefaultAuthenticator(10*time.Minute, func(ctx context.Context, resp *backendresources.AuthResponse) error {
+	action := func(ctx context.Context, resp *backendresources.AuthResponse) error {
 		response = resp
 		return nil
-	})
-	if err != nil {
-		return err
 	}
-
-	exportURL, err := url.Parse(b.URL)
-	if err != nil {
-		return err // should never happen because we test this in Validate()
+	var auth authenticator.Authenticator
+	var sessionID string
+	if b.Headless {
+		if provider.Spec.AuthenticationCollectURL == "" {
+			return fmt.Errorf("service provider %q does not support headless authentication", provider.Spec.ProviderPrettyName)
+		}
+		// the session ID grants the kubeconfig, hence it must not be guessable
+		bs := make([]byte, 32)
+		if _, err := cryptorand.Read(bs); err != nil {
+			return err
+		}
+		sessionID = hex.EncodeToString(bs)
+		var code string
+		if code, err = authenticator.NewConfirmationCode(); err != nil {
+			return err
+		}
+		if err := authenticator.StartHeadlessSession(ctx, provider.Spec.AuthenticationCollectURL, sessionID, code); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\nWhen asked in the browser, enter the confirmation code %s\n", code) // nolint: errcheck
+		auth, err = authenticator.NewHeadlessAuthenticator(provider.Spec.AuthenticationCollectURL, sessionID, 10*time.Minute, action)
+	} else {
+		sessionID = rand.String(rand.IntnRange(20, 30))
+		auth, err = authenticator.NewDefaultAuthenticator(10*time.Minute, action)
 	}
-
-	provider, err := fetchAuthenticationRoute(exportURL.String())
 	if err != nil {
-		return fmt.Errorf("failed to fetch authentication url %q: %v", exportURL, err)
+		return err
 	}
 
-	sessionID := rand.String(rand.IntnRange(20, 30))
-
-	if err := authenticate(provider, auth.Endpoint(ctx), sessionID, urlCh); err != nil {
+	if err := authenticate(provider, auth.Endpoint(ctx), sessionID, b.CacheSession, out, urlCh); err != nil {
 		return err
 	}
 
@@ -151,7 +230,28 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		return fmt.Errorf("authentication timeout")
 	}
 
-	fmt.Fprintf(b.IOStreams.Out, "Successfully authenticated to %s\n", exportURL.String()) // nolint: errcheck
+	fmt.Fprintf(out, "Successfully authenticated to %s\n", exportURL.String()) // nolint: errcheck
+
+	if b.printsManifests() {
+		return b.writeManifests(provider, response)
+	}
+
+	config, err := b.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	apiextensionsClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	bindClient, err := bindclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
 
 	// bootstrap the konnector
 	dynamicClient, err := dynamic.NewForConfig(config)
@@ -163,10 +263,22 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		return err
 	}
 	if !b.SkipKonnector {
-		logger.V(1).Info("Deploying konnector")
-		if err := konnector.Bootstrap(ctx, discoveryClient, dynamicClient, sets.NewString()); err != nil {
+		installation, err := kubectlkonnector.Installed(ctx, kubeClient)
+		if err != nil {
 			return err
 		}
+		if installation != nil && !b.Konnector.IsSet() {
+			// keep the installed version. Upgrades are explicit with "kubectl bind konnector upgrade".
+			if warning := kubectlkonnector.CompatibilityWarning(installation.Version); warning != "" {
+				fmt.Fprintf(b.IOStreams.ErrOut, "Warning: %s\n", warning) // nolint: errcheck
+			}
+		} else {
+			logger.V(1).Info("Deploying konnector")
+			b.Konnector.KeepInstalled(installation)
+			if err := konnector.Bootstrap(ctx, discoveryClient, dynamicClient, b.Konnector.Batteries(), b.Konnector.BootstrapOption()); err != nil {
+				return err
+			}
+		}
 	}
 	first := true
 	if err := wait.PollImmediateInfiniteWithContext(ctx, 1*time.Second, func(ctx context.Context) (bool, error) {
@@ -185,19 +297,23 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		return err
 	}
 
-	// create the namespace
-	if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "kube-bind",
-		},
-	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+	// create the namespace, unless it exists, e.g. managed by policy
+	if _, err := kubeClient.CoreV1().Namespaces().Get(ctx, b.SecretNamespace, metav1.GetOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return err
-	} else if err == nil {
-		fmt.Fprintf(b.IOStreams.Out, "Created kube-binding namespace.\n") // nolint: errcheck
+	} else if apierrors.IsNotFound(err) {
+		if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: b.SecretNamespace,
+			},
+		}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		} else if err == nil {
+			fmt.Fprintf(b.IOStreams.Out, "Created %s namespace.\n", b.SecretNamespace) // nolint: errcheck
+		}
 	}
 
 	// look for secret of the given identity
-	secrets, err := kubeClient.CoreV1().Secrets("kube-bind").List(ctx, metav1.ListOptions{})
+	secrets, err := kubeClient.CoreV1().Secrets(b.SecretNamespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
@@ -209,55 +325,95 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		}
 	}
 
-	// check for existing CRD
-	crd, err := apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, response.Resource+"."+response.Group, metav1.GetOptions{})
-	if err != nil && !apierrors.IsNotFound(err) {
-		return err
-	}
-	if secretName == "" {
-		if err == nil {
-			return fmt.Errorf("CRD %s.%s already exists and is not from this service provider", response.Resource, response.Group)
+	// check for existing CRDs, before touching anything
+	var exports []backendresources.AuthResponseExport
+	for _, export := range response.AllExports() {
+		if _, err := b.bindingName(provider, export); err != nil {
+			return err
+		}
+		crd, err := apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, export.Resource+"."+export.Group, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		} else if apierrors.IsNotFound(err) {
+			exports = append(exports, export)
+			continue
+		}
+		if secretName == "" {
+			return fmt.Errorf("CRD %s.%s already exists and is not from this service provider", export.Resource, export.Group)
 		}
 
-		fmt.Fprintf(b.IOStreams.Out, "Creating secret for identity %s\n", response.ID) // nolint: errcheck
-		secretName, err = resources.EnsureServiceBindingAuthData(ctx, string(response.Kubeconfig), response.ID, "kube-bind", "", kubeClient)
+		fmt.Fprintf(b.IOStreams.Out, "Found existing CRD %s. Checking owner.\n", crd.Name) // nolint: errcheck
+		owned, err := ownedByBindingWithSecret(ctx, bindClient, crd.OwnerReferences, b.SecretNamespace, secretName)
 		if err != nil {
 			return err
+		} else if !owned {
+			return fmt.Errorf("found existing CustomResourceDefinition %s not from this service provider", crd.Name)
 		}
-	} else if err == nil {
-		fmt.Fprintf(b.IOStreams.Out, "Found existing CRD %s. Checking owner.\n", crd.Name) // noline: errcheck
-
-		// check if the CRD is owner-refed by the APIServiceBinding
-		for _, ref := range crd.OwnerReferences {
-			parts := strings.SplitN(ref.APIVersion, "/", 2)
-			if parts[0] != kubebindv1alpha1.SchemeGroupVersion.Group || ref.Kind != "APIServiceBinding" {
-				continue
-			}
+		fmt.Fprintf(b.IOStreams.Out, "CRD %s is already bound, updating credentials only\n", crd.Name) // nolint: errcheck
+	}
 
-			existing, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, ref.Name, metav1.GetOptions{})
-			if err != nil && !apierrors.IsNotFound(err) {
-				return err
-			} else if apierrors.IsNotFound(err) {
-				continue
-			}
+	if secretName == "" {
+		fmt.Fprintf(b.IOStreams.Out, "Creating secret for identity %s\n", response.ID) // nolint: errcheck
+	} else {
+		fmt.Fprintf(b.IOStreams.Out, "Updating credentials\n") // nolint: errcheck
+	}
+	secretName, err = resources.EnsureServiceBindingAuthData(ctx, string(response.Kubeconfig), response.ID, b.URL, b.SecretNamespace, secretName, kubeClient)
+	if err != nil {
+		return err
+	}
 
-			if existing.Spec.KubeconfigSecretRef.Namespace == "kube-bind" && existing.Spec.KubeconfigSecretRef.Name == secretName {
-				fmt.Fprintf(b.IOStreams.Out, "Updating credentials for existing APIServiceBinding %s\n", existing.Name) // nolint: errcheck
-				_, err = resources.EnsureServiceBindingAuthData(ctx, string(response.Kubeconfig), response.ID, "kube-bind", secretName, kubeClient)
-				return err
-			}
+	if b.CacheSession && response.SessionToken != "" && provider.Spec.AuthenticationRefreshURL != "" {
+		if err := session.NewStore("").Save(&session.Session{
+			ProviderURL: b.URL,
+			RefreshURL:  provider.Spec.AuthenticationRefreshURL,
+			ID:          response.ID,
+			Token:       response.SessionToken,
+			CreatedAt:   time.Now(),
+		}); err != nil {
+			// not fatal, refresh falls back to a browser login
+			fmt.Fprintf(b.IOStreams.ErrOut, "Warning: failed to cache session: %v\n", err) // nolint: errcheck
 		}
-		return fmt.Errorf("found existing CustomResourceDefinition %s not from this service provider", response.ID)
-	} else {
-		fmt.Fprintf(b.IOStreams.Out, "Updating credentials\n") // noilnt: errcheck
-		secretName, err = resources.EnsureServiceBindingAuthData(ctx, string(response.Kubeconfig), response.ID, "kube-bind", secretName, kubeClient)
+	}
+
+	// create new APIServiceBindings.
+	for _, export := range exports {
+		name, err := b.bindingName(provider, export)
 		if err != nil {
 			return err
 		}
+		if err := b.createServiceBinding(ctx, bindClient, name, export, secretName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ownedByBindingWithSecret returns true if one of the owners is an APIServiceBinding using the given
+// kubeconfig secret.
+func ownedByBindingWithSecret(ctx context.Context, bindClient bindclient.Interface, owners []metav1.OwnerReference, secretNamespace, secretName string) (bool, error) {
+	for _, ref := range owners {
+		parts := strings.SplitN(ref.APIVersion, "/", 2)
+		if parts[0] != kubebindv1alpha1.SchemeGroupVersion.Group || ref.Kind != "APIServiceBinding" {
+			continue
+		}
+
+		existing, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		} else if apierrors.IsNotFound(err) {
+			continue
+		}
+
+		if existing.Spec.KubeconfigSecretRef.Namespace == secretNamespace && existing.Spec.KubeconfigSecretRef.Name == secretName {
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	// create new APIServiceBinding.
-	first = true
+func (b *BindOptions) createServiceBinding(ctx context.Context, bindClient bindclient.Interface, name string, export backendresources.AuthResponseExport, secretName string) error {
+	first := true
 	if err := wait.PollInfinite(1*time.Second, func() (bool, error) {
 		if !first {
 			first = false
@@ -265,8 +421,7 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		}
 		_, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Create(ctx, &kubebindv1alpha1.APIServiceBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      response.Resource + "." + response.Group,
-				Namespace: "kube-bind",
+				Name: name,
 			},
 			Spec: kubebindv1alpha1.APIServiceBindingSpec{
 				KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
@@ -274,22 +429,22 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 						Name: secretName,
 						Key:  "kubeconfig",
 					},
-					Namespace: "kube-bind",
+					Namespace: b.SecretNamespace,
 				},
-				Export: response.Export,
+				Export: export.Export,
 			},
 		}, metav1.CreateOptions{})
 		if err != nil && !apierrors.IsAlreadyExists(err) {
 			return false, err
 		} else if apierrors.IsAlreadyExists(err) {
-			existing, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, response.Resource+"."+response.Group, metav1.GetOptions{})
+			existing, err := bindClient.KubeBindV1alpha1().APIServiceBindings().Get(ctx, name, metav1.GetOptions{})
 			if err != nil {
 				return false, nil
 			}
-			if existing.Spec.KubeconfigSecretRef.Namespace == "kube-bind" && existing.Spec.KubeconfigSecretRef.Name == secretName {
+			if existing.Spec.KubeconfigSecretRef.Namespace == b.SecretNamespace && existing.Spec.KubeconfigSecretRef.Name == secretName {
 				return true, nil
 			}
-			return false, fmt.Errorf("APIServiceBinding %s.%s already exists, but from different provider", response.Resource, response.Group)
+			return false, fmt.Errorf("APIServiceBinding %s already exists, but from different provider", name)
 		}
 
 		return true, nil
@@ -297,7 +452,7 @@ func (b *BindOptions) Run(ctx context.Context, urlCh chan<- string) error {
 		fmt.Fprintln(b.IOStreams.Out, "") // nolint: errcheck
 		return err
 	}
-	fmt.Fprintf(b.IOStreams.Out, "\nCreated APIServiceBinding %s.%s\n", response.Resource, response.Group) // nolint: errcheck
+	fmt.Fprintf(b.IOStreams.Out, "Created APIServiceBinding %s\n", name) // nolint: errcheck
 
 	return nil
 }
@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	backendresources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/kubectl/bind/plugin/resources"
+)
+
+const (
+	// DryRunNone applies everything to the cluster.
+	DryRunNone = "none"
+	// DryRunClient prints the manifests instead of applying them.
+	DryRunClient = "client"
+
+	// OutputYAML prints the manifests as YAML documents.
+	OutputYAML = "yaml"
+	// OutputJSON prints the manifests as JSON list.
+	OutputJSON = "json"
+
+	// SecretFormatSecret emits the kubeconfig as plain Secret.
+	SecretFormatSecret = "Secret"
+	// SecretFormatSealedSecret emits a SealedSecret with a placeholder instead of the
+	// kubeconfig, to be sealed with kubeseal.
+	SecretFormatSealedSecret = "SealedSecret"
+
+	// unsealedKubeconfigFile is the file in the output directory the kubeconfig is written
+	// to with the SealedSecret format. It must not be committed.
+	unsealedKubeconfigFile = "kubeconfig.unsealed"
+)
+
+// manifest is a group of objects, written to one file in the output directory.
+type manifest struct {
+	name    string
+	objects []*unstructured.Unstructured
+}
+
+// printsManifests returns true if the manifests are printed instead of applied.
+func (b *BindOptions) printsManifests() bool {
+	return b.DryRun == DryRunClient || b.OutputDir != ""
+}
+
+// writeManifests writes the manifests for the given authentication response to the output
+// directory, or to stdout.
+func (b *BindOptions) writeManifests(provider *kubebindv1alpha1.APIServiceProvider, response *backendresources.AuthResponse) error {
+	manifests, err := b.manifests(provider, response)
+	if err != nil {
+		return err
+	}
+
+	if b.OutputDir == "" {
+		var objs []*unstructured.Unstructured
+		for _, m := range manifests {
+			objs = append(objs, m.objects...)
+		}
+		return b.encode(b.IOStreams.Out, objs)
+	}
+
+	if err := os.MkdirAll(b.OutputDir, 0755); err != nil {
+		return err
+	}
+	ext := ".yaml"
+	if b.Output == OutputJSON {
+		ext = ".json"
+	}
+	for _, m := range manifests {
+		var buf bytes.Buffer
+		if err := b.encode(&buf, m.objects); err != nil {
+			return err
+		}
+		filename := filepath.Join(b.OutputDir, m.name+ext)
+		if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil { // nolint: gosec
+			return err
+		}
+		fmt.Fprintf(b.IOStreams.Out, "Wrote %s\n", filename) // nolint: errcheck
+	}
+
+	if b.SecretFormat == SecretFormatSealedSecret {
+		filename := filepath.Join(b.OutputDir, unsealedKubeconfigFile)
+		if err := os.WriteFile(filename, response.Kubeconfig, 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(b.IOStreams.Out, "Wrote %s. Seal it with kubeseal into the SealedSecret, and do not commit it.\n", filename) // nolint: errcheck
+	}
+
+	return nil
+}
+
+// manifests returns the konnector, the kubeconfig secret and the APIServiceBinding as
+// manifests to be applied in this order.
+func (b *BindOptions) manifests(provider *kubebindv1alpha1.APIServiceProvider, response *backendresources.AuthResponse) ([]manifest, error) {
+	var manifests []manifest
+
+	namespaceFound := false
+	if !b.SkipKonnector {
+		objs, err := konnector.Manifests(b.Konnector.Batteries(), b.Konnector.BootstrapOption())
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest{name: "00-konnector", objects: objs})
+		for _, obj := range objs {
+			if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" && obj.GetName() == b.SecretNamespace {
+				namespaceFound = true
+			}
+		}
+	}
+	if !namespaceFound {
+		ns, err := toUnstructured(&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: b.SecretNamespace},
+		})
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest{name: "05-namespace", objects: []*unstructured.Unstructured{ns}})
+	}
+
+	// the secret name must be stable to get the same manifests every time
+	hash := sha256.Sum256([]byte(response.ID))
+	secretName := "kubeconfig-" + hex.EncodeToString(hash[:])[:8]
+	secretMeta := metav1.ObjectMeta{
+		Name:      secretName,
+		Namespace: b.SecretNamespace,
+		Annotations: map[string]string{
+			resources.ClusterIDAnnotationKey:   response.ID,
+			resources.ProviderURLAnnotationKey: b.URL,
+		},
+	}
+	var secret *unstructured.Unstructured
+	if b.SecretFormat == SecretFormatSealedSecret {
+		secret = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "bitnami.com/v1alpha1",
+			"kind":       "SealedSecret",
+			"metadata": map[string]interface{}{
+				"name":      secretName,
+				"namespace": b.SecretNamespace,
+			},
+			"spec": map[string]interface{}{
+				"encryptedData": map[string]interface{}{
+					"kubeconfig": fmt.Sprintf("<kubeseal --raw --namespace %s --name %s --from-file=%s>", b.SecretNamespace, secretName, unsealedKubeconfigFile),
+				},
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      secretName,
+						"namespace": b.SecretNamespace,
+						"annotations": map[string]interface{}{
+							resources.ClusterIDAnnotationKey:   response.ID,
+							resources.ProviderURLAnnotationKey: b.URL,
+						},
+					},
+				},
+			},
+		}}
+	} else {
+		var err error
+		secret, err = toUnstructured(&corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: secretMeta,
+			Data: map[string][]byte{
+				"kubeconfig": response.Kubeconfig,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	manifests = append(manifests, manifest{name: "10-kubeconfig", objects: []*unstructured.Unstructured{secret}})
+
+	var bindings []*unstructured.Unstructured
+	for _, export := range response.AllExports() {
+		name, err := b.bindingName(provider, export)
+		if err != nil {
+			return nil, err
+		}
+		binding, err := toUnstructured(&kubebindv1alpha1.APIServiceBinding{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+				Kind:       "APIServiceBinding",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: kubebindv1alpha1.APIServiceBindingSpec{
+				KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+					LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{
+						Name: secretName,
+						Key:  "kubeconfig",
+					},
+					Namespace: b.SecretNamespace,
+				},
+				Export: export.Export,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	manifests = append(manifests, manifest{name: "20-apiservicebindings", objects: bindings})
+
+	return manifests, nil
+}
+
+// encode writes the objects as YAML documents, or as JSON list.
+func (b *BindOptions) encode(w io.Writer, objs []*unstructured.Unstructured) error {
+	if b.Output == OutputJSON {
+		items := make([]interface{}, 0, len(objs))
+		for _, obj := range objs {
+			items = append(items, obj.Object)
+		}
+		bs, err := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bs))
+		return err
+	}
+
+	for _, obj := range objs {
+		bs, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", bs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: raw}
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+	return u, nil
+}
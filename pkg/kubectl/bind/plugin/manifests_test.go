@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/yaml"
+
+	backendresources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func newManifestsOptions(t *testing.T, out *bytes.Buffer) *BindOptions {
+	t.Helper()
+
+	b := NewBindOptions(genericclioptions.IOStreams{Out: out})
+	b.URL = "https://mangodb.example.com/exports"
+	b.SkipKonnector = true
+	b.DryRun = DryRunClient
+	tmpl, err := parseNameTemplate(b.NameTemplate)
+	require.NoError(t, err)
+	b.nameTemplate = tmpl
+	return b
+}
+
+func TestWriteManifests(t *testing.T) {
+	provider := &kubebindv1alpha1.APIServiceProvider{}
+	response := &backendresources.AuthResponse{
+		ID:         "cluster-id",
+		Kubeconfig: []byte("kubeconfig"),
+		Exports: []backendresources.AuthResponseExport{
+			{Resource: "mangodbs", Group: "mangodb.com", Export: "mangodbs"},
+			{Resource: "backups", Group: "mangodb.com", Export: "backups"},
+		},
+	}
+	wantKinds := []string{"Namespace", "Secret", "APIServiceBinding", "APIServiceBinding"}
+	wantNames := []string{"kube-bind", "kubeconfig-", "mangodbs.mangodb.com", "backups.mangodb.com"}
+
+	requireObjects := func(t *testing.T, objs []map[string]interface{}) {
+		t.Helper()
+		require.Len(t, objs, len(wantKinds))
+		for i, obj := range objs {
+			require.Equal(t, wantKinds[i], obj["kind"])
+			name := obj["metadata"].(map[string]interface{})["name"].(string)
+			require.True(t, strings.HasPrefix(name, wantNames[i]), "%s does not start with %s", name, wantNames[i])
+		}
+		secretName := objs[1]["metadata"].(map[string]interface{})["name"]
+		ref := objs[2]["spec"].(map[string]interface{})["kubeconfigSecretRef"].(map[string]interface{})
+		require.Equal(t, secretName, ref["name"])
+		require.Equal(t, "kube-bind", ref["namespace"])
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		var out bytes.Buffer
+		b := newManifestsOptions(t, &out)
+		require.NoError(t, b.writeManifests(provider, response))
+
+		docs := strings.Split(out.String(), "---\n")
+		require.Empty(t, docs[0])
+		var objs []map[string]interface{}
+		for _, doc := range docs[1:] {
+			var obj map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+			objs = append(objs, obj)
+		}
+		requireObjects(t, objs)
+		require.NotContains(t, out.String(), "creationTimestamp")
+		require.NotContains(t, out.String(), "status")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		b := newManifestsOptions(t, &out)
+		b.Output = OutputJSON
+		require.NoError(t, b.writeManifests(provider, response))
+
+		var list struct {
+			APIVersion string                   `json:"apiVersion"`
+			Kind       string                   `json:"kind"`
+			Items      []map[string]interface{} `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &list))
+		require.Equal(t, "v1", list.APIVersion)
+		require.Equal(t, "List", list.Kind)
+		requireObjects(t, list.Items)
+	})
+
+	t.Run("stable secret name", func(t *testing.T) {
+		var first, second bytes.Buffer
+		require.NoError(t, newManifestsOptions(t, &first).writeManifests(provider, response))
+		require.NoError(t, newManifestsOptions(t, &second).writeManifests(provider, response))
+		require.Equal(t, first.String(), second.String())
+	})
+
+	t.Run("output dir with sealed secret", func(t *testing.T) {
+		var out bytes.Buffer
+		b := newManifestsOptions(t, &out)
+		b.DryRun = DryRunNone
+		b.OutputDir = filepath.Join(t.TempDir(), "manifests")
+		b.SecretFormat = SecretFormatSealedSecret
+		require.NoError(t, b.writeManifests(provider, response))
+
+		entries, err := os.ReadDir(b.OutputDir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		require.Equal(t, []string{"05-namespace.yaml", "10-kubeconfig.yaml", "20-apiservicebindings.yaml", unsealedKubeconfigFile}, names)
+
+		info, err := os.Stat(filepath.Join(b.OutputDir, unsealedKubeconfigFile))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		kubeconfig, err := os.ReadFile(filepath.Join(b.OutputDir, unsealedKubeconfigFile))
+		require.NoError(t, err)
+		require.Equal(t, "kubeconfig", string(kubeconfig))
+
+		secret, err := os.ReadFile(filepath.Join(b.OutputDir, "10-kubeconfig.yaml"))
+		require.NoError(t, err)
+		require.Contains(t, string(secret), "kind: SealedSecret")
+		require.Contains(t, string(secret), "--from-file="+unsealedKubeconfigFile)
+		require.NotContains(t, string(secret), "a3ViZWNvbmZpZw==", "kubeconfig must not be in the manifests")
+	})
+}
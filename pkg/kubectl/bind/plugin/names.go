@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	backendresources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+const (
+	// DefaultSecretNamespace is the default namespace of the kubeconfig secrets.
+	DefaultSecretNamespace = "kube-bind"
+
+	// DefaultNameTemplate is the default template of APIServiceBinding names.
+	DefaultNameTemplate = "{{.Resource}}.{{.Group}}"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// bindingNameData is the input of the binding name template.
+type bindingNameData struct {
+	Resource string
+	Group    string
+	Export   string
+	// Provider is the pretty name of the service provider, lower-cased and with invalid
+	// characters replaced by dashes.
+	Provider string
+	// Host is the host name of the service provider url.
+	Host string
+}
+
+// bindingName returns the name of the APIServiceBinding for the given export.
+func (b *BindOptions) bindingName(provider *kubebindv1alpha1.APIServiceProvider, export backendresources.AuthResponseExport) (string, error) {
+	data := bindingNameData{
+		Resource: export.Resource,
+		Group:    export.Group,
+		Export:   export.Export,
+		Provider: strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(provider.Spec.ProviderPrettyName), "-"), "-."),
+	}
+	if u, err := url.Parse(b.URL); err == nil {
+		data.Host = u.Hostname()
+	}
+
+	var buf bytes.Buffer
+	if err := b.nameTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute --name-template: %w", err)
+	}
+	name := buf.String()
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid APIServiceBinding name %q from --name-template: %s", name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+func parseNameTemplate(s string) (*template.Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --name-template %q: %w", s, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	backendresources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+)
+
+func TestBindingName(t *testing.T) {
+	provider := &kubebindv1alpha1.APIServiceProvider{
+		Spec: kubebindv1alpha1.APIServiceProviderSpec{ProviderPrettyName: "MangoDB Inc."},
+	}
+	export := backendresources.AuthResponseExport{Resource: "mangodbs", Group: "mangodb.com", Export: "mangodbs.mangodb.com"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "default",
+			template: DefaultNameTemplate,
+			want:     "mangodbs.mangodb.com",
+		},
+		{
+			name:     "provider",
+			template: "{{.Resource}}.{{.Group}}-{{.Provider}}",
+			want:     "mangodbs.mangodb.com-mangodb-inc",
+		},
+		{
+			name:     "host",
+			template: "{{.Export}}.{{.Host}}",
+			want:     "mangodbs.mangodb.com.mangodb.example.com",
+		},
+		{
+			name:     "invalid name",
+			template: "{{.Resource}}_{{.Group}}",
+			wantErr:  `invalid APIServiceBinding name "mangodbs_mangodb.com" from --name-template`,
+		},
+		{
+			name:     "empty name",
+			template: "",
+			wantErr:  `invalid APIServiceBinding name "" from --name-template`,
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Kind}}",
+			wantErr:  "failed to execute --name-template",
+		},
+		{
+			name:     "unparsable",
+			template: "{{.Resource",
+			wantErr:  `invalid --name-template "{{.Resource"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseNameTemplate(tt.template)
+			if err == nil {
+				b := &BindOptions{URL: "https://mangodb.example.com/exports", nameTemplate: tmpl}
+				var name string
+				name, err = b.bindingName(provider, export)
+				if tt.wantErr == "" {
+					require.NoError(t, err)
+					require.Equal(t, tt.want, name)
+					return
+				}
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -29,19 +29,23 @@ import (
 
 const (
 	ClusterIDAnnotationKey = "kube-bind.io/cluster-id"
+	// ProviderURLAnnotationKey is the url of the service provider the kubeconfig is from, used to
+	// refresh the kubeconfig.
+	ProviderURLAnnotationKey = "kube-bind.io/provider-url"
 )
 
 // EnsureServiceBindingAuthData create a secret which contains the service binding authenticated data such as
 // the binding session id and the kubeconfig of the service provider cluster. If it is pre-existing, the kubeconfig
 // is updated.
-func EnsureServiceBindingAuthData(ctx context.Context, kubeconfig, clusterID, ns, name string, client kubeclient.Interface) (string, error) {
+func EnsureServiceBindingAuthData(ctx context.Context, kubeconfig, clusterID, providerURL, ns, name string, client kubeclient.Interface) (string, error) {
 	if name == "" {
 		secret := &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace:    ns,
 				GenerateName: "kubeconfig-",
 				Annotations: map[string]string{
-					ClusterIDAnnotationKey: clusterID,
+					ClusterIDAnnotationKey:   clusterID,
+					ProviderURLAnnotationKey: providerURL,
 				},
 			},
 			Data: map[string][]byte{
@@ -70,6 +74,7 @@ func EnsureServiceBindingAuthData(ctx context.Context, kubeconfig, clusterID, ns
 			return errors.NewAlreadyExists(corev1.Resource("secret"), secret.Name)
 		}
 		secret.Data["kubeconfig"] = []byte(kubeconfig)
+		secret.Annotations[ProviderURLAnnotationKey] = providerURL
 		if _, err := client.CoreV1().Secrets(ns).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
 			return err
 		}
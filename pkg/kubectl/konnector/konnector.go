@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package konnector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/version"
+
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+	"github.com/kube-bind/kube-bind/pkg/bootstrap"
+	"github.com/kube-bind/kube-bind/pkg/konnector/webhook"
+)
+
+// imageRepository is the repository of the released konnector images.
+var imageRepository = strings.SplitN(konnector.DefaultImage, ":", 2)[0]
+
+// Options are the flags choosing the konnector image and version.
+type Options struct {
+	// Image is the konnector image. Defaults to the image of Version.
+	Image string
+	// Version is the konnector version. Defaults to the version of the CLI.
+	Version string
+	// Webhook enables the validating admission webhook of the konnector.
+	Webhook bool
+
+	// webhookChanged returns true if --konnector-webhook has been set explicitly.
+	webhookChanged func() bool
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (o *Options) BindFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Image, "konnector-image", o.Image, fmt.Sprintf("The konnector image. Defaults to %s:<konnector-version>", imageRepository))
+	cmd.Flags().StringVar(&o.Version, "konnector-version", o.Version, "The konnector version. Defaults to the version of kubectl bind")
+	cmd.Flags().BoolVar(&o.Webhook, "konnector-webhook", o.Webhook, "Enable the validating admission webhook of the konnector. Requires cert-manager. Defaults to the installed konnector")
+	o.webhookChanged = func() bool {
+		return cmd.Flags().Changed("konnector-webhook")
+	}
+}
+
+// IsSet returns true if the image, the version or the webhook have been chosen explicitly.
+func (o *Options) IsSet() bool {
+	return o.Image != "" || o.Version != "" || o.Webhook
+}
+
+// KeepInstalled keeps the webhook of the installed konnector, unless --konnector-webhook
+// has been set explicitly.
+func (o *Options) KeepInstalled(installation *Installation) {
+	if installation == nil || (o.webhookChanged != nil && o.webhookChanged()) {
+		return
+	}
+	o.Webhook = o.Webhook || installation.Webhook
+}
+
+// Batteries returns the optional parts of the konnector to install.
+func (o *Options) Batteries() sets.String {
+	batteries := sets.NewString()
+	if o.Webhook {
+		batteries.Insert(konnector.WebhookBattery)
+	}
+	return batteries
+}
+
+// Resolve returns the image and the version to install.
+func (o *Options) Resolve() (image, version string) {
+	version = o.Version
+	if version == "" {
+		version = CLIVersion()
+	}
+	image = o.Image
+	if image == "" {
+		if version == konnector.DefaultVersion {
+			image = konnector.DefaultImage
+		} else {
+			image = imageRepository + ":" + version
+		}
+	}
+	return image, version
+}
+
+// BootstrapOption returns the option to bootstrap the chosen konnector.
+func (o *Options) BootstrapOption() bootstrap.Option {
+	return konnector.ImageOption(o.Resolve())
+}
+
+// CLIVersion returns the version of this CLI if it is a release, and the default
+// konnector version otherwise.
+func CLIVersion() string {
+	v, err := utilversion.ParseSemantic(version.Get().GitVersion)
+	if err != nil || v.PreRelease() != "" || v.BuildMetadata() != "" || (v.Major() == 0 && v.Minor() == 0 && v.Patch() == 0) {
+		return konnector.DefaultVersion
+	}
+	return "v" + v.String()
+}
+
+// Installation is the state of an installed konnector.
+type Installation struct {
+	Version       string
+	Image         string
+	Replicas      int32
+	ReadyReplicas int32
+	// Webhook is true if the validating admission webhook is enabled.
+	Webhook bool
+}
+
+// Installed returns the installed konnector, or nil if there is none.
+func Installed(ctx context.Context, kubeClient kubeclient.Interface) (*Installation, error) {
+	deployment, err := kubeClient.AppsV1().Deployments(konnector.Namespace).Get(ctx, konnector.DeploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	installation := &Installation{
+		Version:       deployment.Annotations[konnector.VersionAnnotationKey],
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+	}
+	if deployment.Spec.Replicas != nil {
+		installation.Replicas = *deployment.Spec.Replicas
+	}
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		if c.Name != "konnector" {
+			continue
+		}
+		installation.Image = c.Image
+		for _, arg := range c.Args {
+			if strings.HasPrefix(arg, "--webhook-bind-address=") {
+				installation.Webhook = true
+			}
+		}
+	}
+	return installation, nil
+}
+
+// CompatibilityWarning returns a warning if the installed konnector version might not
+// be compatible with this CLI, or an empty string. Versions with the same major and
+// minor version are compatible.
+func CompatibilityWarning(installed string) string {
+	return compatibilityWarning(installed, CLIVersion())
+}
+
+func compatibilityWarning(installed, cli string) string {
+	switch {
+	case installed == "":
+		return "The installed konnector has no version, probably installed by an older kubectl bind. Run \"kubectl bind konnector upgrade\"."
+	case cli == konnector.DefaultVersion:
+		return "" // development build, anything goes
+	case installed == konnector.DefaultVersion:
+		return fmt.Sprintf("The installed konnector version %q is not pinned. Run \"kubectl bind konnector upgrade\" to install %s.", installed, cli)
+	}
+
+	installedVersion, err := utilversion.ParseSemantic(installed)
+	if err != nil {
+		return fmt.Sprintf("Cannot determine whether the installed konnector version %q is compatible with kubectl bind %s.", installed, cli)
+	}
+	cliVersion := utilversion.MustParseSemantic(cli)
+	if installedVersion.Major() != cliVersion.Major() || installedVersion.Minor() != cliVersion.Minor() {
+		return fmt.Sprintf("The installed konnector %s is not compatible with kubectl bind %s. Run \"kubectl bind konnector upgrade\".", installed, cli)
+	}
+	return ""
+}
+
+// Uninstall deletes the konnector, and the webhook configuration it maintains. The
+// namespace is kept.
+func Uninstall(ctx context.Context, config *rest.Config) error {
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if err := konnector.Uninstall(ctx, discoveryClient, dynamicClient); err != nil {
+		return err
+	}
+	err = kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, webhook.ConfigurationName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package konnector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	"github.com/kube-bind/kube-bind/deploy/konnector"
+)
+
+func TestCompatibilityWarning(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed string
+		cli       string
+		wantWarn  bool
+	}{
+		{name: "no version", installed: "", cli: "v0.2.0", wantWarn: true},
+		{name: "no version, development build", installed: "", cli: konnector.DefaultVersion, wantWarn: true},
+		{name: "development build", installed: "v0.1.0", cli: konnector.DefaultVersion},
+		{name: "unpinned konnector", installed: konnector.DefaultVersion, cli: "v0.2.0", wantWarn: true},
+		{name: "same version", installed: "v0.2.0", cli: "v0.2.0"},
+		{name: "other patch version", installed: "v0.2.3", cli: "v0.2.0"},
+		{name: "other minor version", installed: "v0.1.0", cli: "v0.2.0", wantWarn: true},
+		{name: "other major version", installed: "v1.2.0", cli: "v0.2.0", wantWarn: true},
+		{name: "invalid version", installed: "foo", cli: "v0.2.0", wantWarn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compatibilityWarning(tt.installed, tt.cli)
+			require.Equal(t, tt.wantWarn, got != "", "warning: %q", got)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		wantImage   string
+		wantVersion string
+	}{
+		{
+			name:        "pinned version",
+			opts:        Options{Version: "v0.1.0"},
+			wantImage:   imageRepository + ":v0.1.0",
+			wantVersion: "v0.1.0",
+		},
+		{
+			name:        "default version",
+			opts:        Options{Version: konnector.DefaultVersion},
+			wantImage:   konnector.DefaultImage,
+			wantVersion: konnector.DefaultVersion,
+		},
+		{
+			name:        "custom image",
+			opts:        Options{Image: "registry.example.com/konnector:v0.1.0-custom", Version: "v0.1.0"},
+			wantImage:   "registry.example.com/konnector:v0.1.0-custom",
+			wantVersion: "v0.1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, version := tt.opts.Resolve()
+			require.Equal(t, tt.wantImage, image)
+			require.Equal(t, tt.wantVersion, version)
+		})
+	}
+}
+
+func TestInstalled(t *testing.T) {
+	deployment := func(args ...string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   konnector.Namespace,
+				Name:        konnector.DeploymentName,
+				Annotations: map[string]string{konnector.VersionAnnotationKey: "v0.1.0"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: pointer.Int32(2),
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "konnector", Image: imageRepository + ":v0.1.0", Args: args},
+				}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		want       *Installation
+	}{
+		{
+			name: "not installed",
+		},
+		{
+			name:       "without webhook",
+			deployment: deployment(),
+			want:       &Installation{Version: "v0.1.0", Image: imageRepository + ":v0.1.0", Replicas: 2},
+		},
+		{
+			name:       "with webhook",
+			deployment: deployment("--webhook-bind-address=:9443", "--webhook-service=kube-bind/konnector-webhook:443"),
+			want:       &Installation{Version: "v0.1.0", Image: imageRepository + ":v0.1.0", Replicas: 2, Webhook: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kubefake.NewSimpleClientset()
+			if tt.deployment != nil {
+				require.NoError(t, client.Tracker().Add(tt.deployment))
+			}
+			installation, err := Installed(context.Background(), client)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, installation)
+		})
+	}
+}
+
+func TestKeepInstalled(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		installation *Installation
+		want         bool
+	}{
+		{name: "not installed"},
+		{name: "not installed, enabled", args: []string{"--konnector-webhook"}, want: true},
+		{name: "installed without webhook", installation: &Installation{}},
+		{name: "installed without webhook, enabled", args: []string{"--konnector-webhook"}, installation: &Installation{}, want: true},
+		{name: "keeps the installed webhook", installation: &Installation{Webhook: true}, want: true},
+		{name: "keeps the installed webhook on upgrade", args: []string{"--konnector-version=v0.2.0"}, installation: &Installation{Webhook: true}, want: true},
+		{name: "disables the installed webhook explicitly", args: []string{"--konnector-webhook=false"}, installation: &Installation{Webhook: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			cmd := &cobra.Command{}
+			opts.BindFlags(cmd)
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			opts.KeepInstalled(tt.installation)
+			require.Equal(t, tt.want, opts.Webhook)
+			require.Equal(t, tt.want, opts.Batteries().Has(konnector.WebhookBattery))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	backendresources "github.com/kube-bind/kube-bind/contrib/example-backend/kubernetes/resources"
+)
+
+// ErrExpired is returned by Refresh if the service provider does not accept the session token anymore.
+var ErrExpired = errors.New("session expired")
+
+// refreshTimeout bounds the refresh request to the service provider.
+const refreshTimeout = 30 * time.Second
+
+// Refresh requests a new kubeconfig for the given exports, with the token of the session.
+// It returns ErrExpired if a browser login is needed.
+func Refresh(ctx context.Context, session *Session, exports []string) (*backendresources.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	values := url.Values{}
+	for _, export := range exports {
+		values.Add("export", export)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, session.RefreshURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, ErrExpired
+	default:
+		return nil, fmt.Errorf("failed to refresh session at %s: %s: %s", session.RefreshURL, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	response := &backendresources.AuthResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, fmt.Errorf("failed to decode refresh response: %w", err)
+	}
+	return response, nil
+}
+
+// Revoke revokes the token of the session at the service provider, such that it cannot be
+// used anymore.
+func Revoke(ctx context.Context, session *Session) error {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, session.RefreshURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) // nolint: errcheck
+		return fmt.Errorf("failed to revoke session at %s: %s: %s", session.RefreshURL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
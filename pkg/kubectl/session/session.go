@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// Session is the cached session of a service provider. The token grants new kubeconfigs
+// for the identity without a browser login, hence it is only stored readable by the user.
+type Session struct {
+	// ProviderURL is the url of the service provider, as passed to kubectl bind.
+	ProviderURL string `json:"providerURL"`
+	// RefreshURL is the authenticationRefreshURL of the service provider.
+	RefreshURL string `json:"refreshURL"`
+	// ID is the identity of the session, as in the cluster id annotation of the kubeconfig secret.
+	ID string `json:"id"`
+	// Token is the opaque session token.
+	Token string `json:"token"`
+	// CreatedAt is when the token was issued.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store stores sessions in a directory, one file per service provider.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store in the given directory, or in ~/.kube/kube-bind/sessions if empty.
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = filepath.Join(homedir.HomeDir(), ".kube", "kube-bind", "sessions")
+	}
+	return &Store{dir: dir}
+}
+
+// Get returns the session of the given service provider url, or nil if there is none.
+func (s *Store) Get(providerURL string) (*Session, error) {
+	bs, err := os.ReadFile(s.path(providerURL))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal(bs, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Save writes the session readable only by the user, replacing any previous session of
+// the service provider.
+func (s *Store) Save(session *Session) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	bs, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	// write atomically, such that concurrent reads never see a partial file
+	f, err := os.CreateTemp(s.dir, ".session-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // nolint: errcheck
+	if err := f.Chmod(0600); err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	if _, err := f.Write(bs); err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(session.ProviderURL))
+}
+
+// Delete removes the session of the given service provider url, if any.
+func (s *Store) Delete(providerURL string) error {
+	if err := os.Remove(s.path(providerURL)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) path(providerURL string) string {
+	hash := sha256.Sum256([]byte(providerURL))
+	return filepath.Join(s.dir, hex.EncodeToString(hash[:])[:16]+".json")
+}
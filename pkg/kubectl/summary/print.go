@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputWide prints additional columns or details.
+	OutputWide = "wide"
+	// OutputJSON prints JSON.
+	OutputJSON = "json"
+	// OutputYAML prints YAML.
+	OutputYAML = "yaml"
+)
+
+// ValidateOutput returns an error if output is not a supported output format.
+func ValidateOutput(output string) error {
+	switch output {
+	case "", OutputWide, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of json, yaml or wide", output)
+}
+
+// PrintObject prints v as JSON or YAML. It returns false for other output formats.
+func PrintObject(out io.Writer, output string, v interface{}) (bool, error) {
+	switch output {
+	case OutputJSON:
+		bs, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return true, err
+		}
+		_, err = fmt.Fprintln(out, string(bs))
+		return true, err
+	case OutputYAML:
+		bs, err := yaml.Marshal(v)
+		if err != nil {
+			return true, err
+		}
+		_, err = out.Write(bs)
+		return true, err
+	}
+	return false, nil
+}
+
+// PrintTable prints the bindings as table, with additional columns if wide is true.
+func PrintTable(out io.Writer, bindings []Binding, wide bool) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+
+	header := "NAME\tPROVIDER\tEXPORT\tRESOURCES\tSYNCED\tHEARTBEAT\tREADY"
+	if wide {
+		header += "\tSECRET\tMESSAGE"
+	}
+	fmt.Fprintln(w, header) // nolint: errcheck
+
+	for _, b := range bindings {
+		names := make([]string, 0, len(b.Resources))
+		objects, synced := 0, 0
+		for _, r := range b.Resources {
+			names = append(names, r.Name)
+			objects += r.Objects
+			synced += r.Synced
+		}
+		resources := strings.Join(names, ",")
+		if resources == "" {
+			resources = "<none>"
+		}
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%d/%d\t%s\t%s",
+			b.Name, orNone(b.Provider), b.Export, resources, synced, objects, b.heartbeatAge(), b.Ready,
+		)
+		if wide {
+			row += fmt.Sprintf("\t%s\t%s", b.KubeconfigSecret, b.Message)
+		}
+		fmt.Fprintln(w, row) // nolint: errcheck
+	}
+
+	return w.Flush()
+}
+
+// Describe prints the details of the binding. With wide, the messages of all conditions are printed.
+func Describe(out io.Writer, b *Binding, wide bool) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", b.Name)                          // nolint: errcheck
+	fmt.Fprintf(w, "Provider:\t%s\n", orNone(b.Provider))          // nolint: errcheck
+	fmt.Fprintf(w, "Export:\t%s\n", b.Export)                      // nolint: errcheck
+	fmt.Fprintf(w, "Kubeconfig Secret:\t%s\n", b.KubeconfigSecret) // nolint: errcheck
+	fmt.Fprintf(w, "Heartbeat:\t%s\n", b.heartbeatAge())           // nolint: errcheck
+	if b.Ready == "True" {
+		fmt.Fprintf(w, "Ready:\t%s\n", b.Ready) // nolint: errcheck
+	} else {
+		fmt.Fprintf(w, "Ready:\t%s (%s) %s\n", b.Ready, b.Reason, b.Message) // nolint: errcheck
+	}
+
+	fmt.Fprintln(w, "Resources:") // nolint: errcheck
+	if len(b.Resources) == 0 {
+		fmt.Fprintln(w, "  <none>") // nolint: errcheck
+	} else {
+		fmt.Fprintln(w, "  NAME\tOBJECTS\tSYNCED") // nolint: errcheck
+		for _, r := range b.Resources {
+			fmt.Fprintf(w, "  %s\t%d\t%d\n", r.Name, r.Objects, r.Synced) // nolint: errcheck
+		}
+	}
+
+	fmt.Fprintln(w, "Conditions:") // nolint: errcheck
+	if len(b.Conditions) == 0 {
+		fmt.Fprintln(w, "  <none>") // nolint: errcheck
+	} else {
+		if wide {
+			fmt.Fprintln(w, "  TYPE\tSTATUS\tSEVERITY\tAGE\tREASON\tMESSAGE") // nolint: errcheck
+		} else {
+			fmt.Fprintln(w, "  TYPE\tSTATUS\tAGE\tREASON") // nolint: errcheck
+		}
+		for _, c := range b.Conditions {
+			age := "<unknown>"
+			if !c.LastTransitionTime.IsZero() {
+				age = duration.HumanDuration(time.Since(c.LastTransitionTime.Time))
+			}
+			if wide {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", c.Type, c.Status, c.Severity, age, c.Reason, c.Message) // nolint: errcheck
+			} else {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", c.Type, c.Status, age, c.Reason) // nolint: errcheck
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+func (b *Binding) heartbeatAge() string {
+	if b.LastHeartbeatTime == nil {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(b.LastHeartbeatTime.Time))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
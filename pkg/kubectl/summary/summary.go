@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/util/conditions"
+	bindclient "github.com/kube-bind/kube-bind/pkg/client/clientset/versioned"
+)
+
+// providerTimeout bounds the requests to the service provider, which might be unreachable.
+const providerTimeout = 5 * time.Second
+
+// Binding is the summarized state of an APIServiceBinding.
+type Binding struct {
+	Name             string `json:"name"`
+	Provider         string `json:"provider,omitempty"`
+	Export           string `json:"export"`
+	KubeconfigSecret string `json:"kubeconfigSecret"`
+
+	// LastHeartbeatTime is the last heartbeat of the konnector seen by the service provider,
+	// or nil if the service provider cannot be reached.
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+
+	// Ready is the status of the summarized conditions, with reason and message if not true.
+	Ready   corev1.ConditionStatus `json:"ready"`
+	Reason  string                 `json:"reason,omitempty"`
+	Message string                 `json:"message,omitempty"`
+
+	Resources  []Resource               `json:"resources,omitempty"`
+	Conditions conditionsapi.Conditions `json:"conditions,omitempty"`
+}
+
+// Resource is the summarized state of a CustomResourceDefinition of a binding.
+type Resource struct {
+	Name    string `json:"name"`
+	Objects int    `json:"objects"`
+	Synced  int    `json:"synced"`
+}
+
+// Clients are the consumer cluster clients needed to summarize bindings.
+type Clients struct {
+	Kube          kubeclient.Interface
+	Apiextensions apiextensionsclient.Interface
+	Dynamic       dynamic.Interface
+}
+
+// NewClients creates Clients for the given config.
+func NewClients(config *rest.Config) (*Clients, error) {
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	apiextensionsClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Clients{Kube: kubeClient, Apiextensions: apiextensionsClient, Dynamic: dynamicClient}, nil
+}
+
+// Summarize returns the summaries of the given bindings, in the order of the bindings.
+func Summarize(ctx context.Context, clients *Clients, bindings []kubebindv1alpha1.APIServiceBinding) ([]Binding, error) {
+	crds, err := clients.Apiextensions.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]Binding, 0, len(bindings))
+	for i := range bindings {
+		binding := &bindings[i]
+		s := Binding{
+			Name:             binding.Name,
+			Provider:         binding.Status.ProviderPrettyName,
+			Export:           binding.Spec.Export,
+			KubeconfigSecret: binding.Spec.KubeconfigSecretRef.Namespace + "/" + binding.Spec.KubeconfigSecretRef.Name,
+			Conditions:       binding.Status.Conditions,
+		}
+
+		// summarize the conditions like the konnector does
+		copied := binding.DeepCopy()
+		conditions.SetSummary(copied)
+		s.Ready = corev1.ConditionUnknown
+		if ready := conditions.Get(copied, conditionsapi.ReadyCondition); ready != nil {
+			s.Ready = ready.Status
+			if ready.Status != corev1.ConditionTrue {
+				s.Reason = ready.Reason
+				s.Message = ready.Message
+			}
+		}
+
+		s.LastHeartbeatTime = lastHeartbeatTime(ctx, clients, binding)
+
+		for i := range crds.Items {
+			crd := &crds.Items[i]
+			if !ownedBy(crd, binding) {
+				continue
+			}
+			r, err := summarizeResource(ctx, clients, crd)
+			if err != nil {
+				return nil, err
+			}
+			s.Resources = append(s.Resources, r)
+		}
+		sort.Slice(s.Resources, func(i, j int) bool {
+			return s.Resources[i].Name < s.Resources[j].Name
+		})
+
+		summaries = append(summaries, s)
+	}
+
+	return summaries, nil
+}
+
+func ownedBy(crd *apiextensionsv1.CustomResourceDefinition, binding *kubebindv1alpha1.APIServiceBinding) bool {
+	for _, ref := range crd.OwnerReferences {
+		parts := strings.SplitN(ref.APIVersion, "/", 2)
+		if parts[0] == kubebindv1alpha1.SchemeGroupVersion.Group && ref.Kind == "APIServiceBinding" && ref.Name == binding.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeResource counts the objects of the given CRD, and those whose current
+// generation has been synced to the service provider.
+func summarizeResource(ctx context.Context, clients *Clients, crd *apiextensionsv1.CustomResourceDefinition) (Resource, error) {
+	r := Resource{Name: crd.Name}
+
+	var version string
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			version = v.Name
+			break
+		}
+	}
+	if version == "" {
+		return r, nil
+	}
+
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: version, Resource: crd.Spec.Names.Plural}
+	objs, err := clients.Dynamic.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return r, fmt.Errorf("failed to list %s: %w", crd.Name, err)
+	}
+	for _, obj := range objs.Items {
+		r.Objects++
+		annotations := obj.GetAnnotations()
+		switch {
+		case obj.GetLabels()[kubebindv1alpha1.OriginLabelKey] == kubebindv1alpha1.OriginProvider:
+			r.Synced++ // synced from the service provider
+		case annotations[kubebindv1alpha1.SyncErrorAnnotationKey] == "" &&
+			annotations[kubebindv1alpha1.SyncedGenerationAnnotationKey] == strconv.FormatInt(obj.GetGeneration(), 10):
+			r.Synced++
+		}
+	}
+
+	return r, nil
+}
+
+// ProviderConfig returns the rest config for the service provider from the kubeconfig
+// secret of the binding, and the namespace of the consumer cluster at the service provider.
+func ProviderConfig(ctx context.Context, kubeClient kubeclient.Interface, binding *kubebindv1alpha1.APIServiceBinding) (*rest.Config, string, error) {
+	ref := binding.Spec.KubeconfigSecretRef
+	secret, err := kubeClient.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	kubeconfig, found := secret.Data[ref.Key]
+	if !found {
+		return nil, "", fmt.Errorf("kubeconfig secret %s/%s is missing %q key", ref.Namespace, ref.Name, ref.Key)
+	}
+	cfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("kubeconfig secret %s/%s is invalid: %w", ref.Namespace, ref.Name, err)
+	}
+	kubeContext, found := cfg.Contexts[cfg.CurrentContext]
+	if !found || kubeContext.Namespace == "" {
+		return nil, "", fmt.Errorf("kubeconfig secret %s/%s is invalid: current context %q not found or without namespace", ref.Namespace, ref.Name, cfg.CurrentContext)
+	}
+	providerConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("kubeconfig secret %s/%s is invalid: %w", ref.Namespace, ref.Name, err)
+	}
+	providerConfig.Timeout = providerTimeout
+	return providerConfig, kubeContext.Namespace, nil
+}
+
+// lastHeartbeatTime returns the last heartbeat in the ClusterBinding of the service
+// provider, or nil if it cannot be read.
+func lastHeartbeatTime(ctx context.Context, clients *Clients, binding *kubebindv1alpha1.APIServiceBinding) *metav1.Time {
+	providerConfig, ns, err := ProviderConfig(ctx, clients.Kube, binding)
+	if err != nil {
+		return nil
+	}
+	providerClient, err := bindclient.NewForConfig(providerConfig)
+	if err != nil {
+		return nil
+	}
+	clusterBinding, err := providerClient.KubeBindV1alpha1().ClusterBindings(ns).Get(ctx, "cluster", metav1.GetOptions{})
+	if err != nil || clusterBinding.Status.LastHeartbeatTime.IsZero() {
+		return nil
+	}
+	return &clusterBinding.Status.LastHeartbeatTime
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The Kube Bind Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	kubebindv1alpha1 "github.com/kube-bind/kube-bind/pkg/apis/kubebind/v1alpha1"
+	conditionsapi "github.com/kube-bind/kube-bind/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+)
+
+func newCRD(name, group, plural, binding string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: plural, Kind: "MangoDB", ListKind: "MangoDBList"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: false},
+				{Name: "v1", Served: true, Storage: true},
+			},
+		},
+	}
+	if binding != "" {
+		crd.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: kubebindv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "APIServiceBinding",
+			Name:       binding,
+		}}
+	}
+	return crd
+}
+
+func newObject(name string, generation int64, labels, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("mangodb.com/v1")
+	obj.SetKind("MangoDB")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetGeneration(generation)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestSummarize(t *testing.T) {
+	bindings := []kubebindv1alpha1.APIServiceBinding{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "mangodbs"},
+			Spec: kubebindv1alpha1.APIServiceBindingSpec{
+				Export: "mangodbs",
+				KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+					LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: "kubeconfig-abc", Key: "kubeconfig"},
+					Namespace:         "kube-bind",
+				},
+			},
+			Status: kubebindv1alpha1.APIServiceBindingStatus{
+				ProviderPrettyName: "MangoDB Inc.",
+				Conditions: conditionsapi.Conditions{
+					{Type: kubebindv1alpha1.APIServiceBindingConditionSecretValid, Status: corev1.ConditionTrue},
+					{
+						Type:     kubebindv1alpha1.APIServiceBindingConditionSchemaInSync,
+						Status:   corev1.ConditionFalse,
+						Severity: conditionsapi.ConditionSeverityError,
+						Reason:   "ForeignCustomResourceDefinition",
+						Message:  "CustomResourceDefinition mangodbs.mangodb.com is not owned by kube-bind.io.",
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty"},
+			Spec: kubebindv1alpha1.APIServiceBindingSpec{
+				Export: "empty",
+				KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+					LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: "kubeconfig-abc", Key: "kubeconfig"},
+					Namespace:         "kube-bind",
+				},
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	gvr := schema.GroupVersionResource{Group: "mangodb.com", Version: "v1", Resource: "mangodbs"}
+	clients := &Clients{
+		Kube: kubefake.NewSimpleClientset(),
+		Apiextensions: apiextensionsfake.NewSimpleClientset(
+			newCRD("mangodbs.mangodb.com", "mangodb.com", "mangodbs", "mangodbs"),
+			newCRD("backups.mangodb.com", "mangodb.com", "backups", "mangodbs"),
+			newCRD("others.example.com", "example.com", "others", "other"),
+		),
+		Dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+			map[schema.GroupVersionResource]string{
+				gvr: "MangoDBList",
+				{Group: "mangodb.com", Version: "v1", Resource: "backups"}: "BackupList",
+			},
+			newObject("synced", 2, nil, map[string]string{kubebindv1alpha1.SyncedGenerationAnnotationKey: "2"}),
+			newObject("outdated", 3, nil, map[string]string{kubebindv1alpha1.SyncedGenerationAnnotationKey: "2"}),
+			newObject("failed", 2, nil, map[string]string{
+				kubebindv1alpha1.SyncedGenerationAnnotationKey: "2",
+				kubebindv1alpha1.SyncErrorAnnotationKey:        "denied",
+			}),
+			newObject("provider", 1, map[string]string{kubebindv1alpha1.OriginLabelKey: kubebindv1alpha1.OriginProvider}, nil),
+		),
+	}
+
+	summaries, err := Summarize(context.Background(), clients, bindings)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+
+	s := summaries[0]
+	require.Equal(t, "mangodbs", s.Name)
+	require.Equal(t, "MangoDB Inc.", s.Provider)
+	require.Equal(t, "kube-bind/kubeconfig-abc", s.KubeconfigSecret)
+	require.Nil(t, s.LastHeartbeatTime, "secret does not exist")
+	require.Equal(t, corev1.ConditionFalse, s.Ready)
+	require.Equal(t, "ForeignCustomResourceDefinition", s.Reason)
+	require.Equal(t, []Resource{
+		{Name: "backups.mangodb.com"},
+		{Name: "mangodbs.mangodb.com", Objects: 4, Synced: 2},
+	}, s.Resources)
+
+	require.Equal(t, "empty", summaries[1].Name)
+	require.Empty(t, summaries[1].Resources)
+
+	var out bytes.Buffer
+	require.NoError(t, PrintTable(&out, summaries, true))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"NAME", "PROVIDER", "EXPORT", "RESOURCES", "SYNCED", "HEARTBEAT", "READY", "SECRET", "MESSAGE"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"mangodbs", "MangoDB", "Inc.", "mangodbs", "backups.mangodb.com,mangodbs.mangodb.com", "2/4", "<unknown>", "False", "kube-bind/kubeconfig-abc"}, strings.Fields(lines[1])[:9])
+	require.Equal(t, []string{"empty", "<none>", "empty", "<none>", "0/0", "<unknown>"}, strings.Fields(lines[2])[:6])
+}
+
+func TestProviderConfig(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: provider
+  cluster:
+    server: https://provider.example.com
+contexts:
+- name: provider
+  context:
+    cluster: provider
+    namespace: %s
+current-context: provider
+`
+	tests := []struct {
+		name    string
+		data    map[string][]byte
+		wantNS  string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			data:   map[string][]byte{"kubeconfig": []byte(strings.Replace(kubeconfig, "%s", "kube-bind-abc", 1))},
+			wantNS: "kube-bind-abc",
+		},
+		{
+			name:    "missing key",
+			data:    map[string][]byte{"other": []byte("")},
+			wantErr: `kubeconfig secret kube-bind/kubeconfig-abc is missing "kubeconfig" key`,
+		},
+		{
+			name:    "no namespace",
+			data:    map[string][]byte{"kubeconfig": []byte(strings.Replace(kubeconfig, "%s", `""`, 1))},
+			wantErr: `current context "provider" not found or without namespace`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-bind", Name: "kubeconfig-abc"},
+				Data:       tt.data,
+			})
+			binding := &kubebindv1alpha1.APIServiceBinding{
+				Spec: kubebindv1alpha1.APIServiceBindingSpec{
+					KubeconfigSecretRef: kubebindv1alpha1.ClusterSecretKeyRef{
+						LocalSecretKeyRef: kubebindv1alpha1.LocalSecretKeyRef{Name: "kubeconfig-abc", Key: "kubeconfig"},
+						Namespace:         "kube-bind",
+					},
+				},
+			}
+
+			config, ns, err := ProviderConfig(context.Background(), kubeClient, binding)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantNS, ns)
+			require.Equal(t, "https://provider.example.com", config.Host)
+			require.Equal(t, providerTimeout, config.Timeout)
+		})
+	}
+}